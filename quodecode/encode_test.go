@@ -0,0 +1,76 @@
+package quodecode_test
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/quomproject/quolang/quodecode"
+	"github.com/quomproject/quolang/quoty"
+)
+
+func ExampleEncodeIntoBody() {
+	type Leg struct {
+		Side   string                   `quo:"side,label"`
+		Amount quoty.StellarAssetAmount `quo:"amount"`
+		Spread *big.Rat                 `quo:"spread"`
+	}
+	type Market struct {
+		Base    quoty.StellarAsset `cty:"base"`
+		Counter quoty.StellarAsset `cty:"counter"`
+	}
+	type Strategy struct {
+		Name   string  `quo:"name"`
+		Market *Market `quo:"market"`
+		Legs   []Leg   `quo:"leg,block"`
+	}
+
+	strategy := Strategy{
+		Name: "mm",
+		Market: &Market{
+			Base: quoty.StellarAsset{Code: "XLM"},
+			Counter: quoty.StellarAsset{
+				Code:   "USD",
+				Issuer: "GABC",
+			},
+		},
+		Legs: []Leg{
+			{
+				Side:   "buy",
+				Amount: quoty.StellarAssetAmount(1005000000),
+				Spread: big.NewRat(25, 10000),
+			},
+			{
+				Side:   "sell",
+				Amount: quoty.StellarAssetAmount(5),
+				Spread: big.NewRat(1, 3),
+			},
+		},
+	}
+
+	f := hclwrite.NewEmptyFile()
+	quodecode.EncodeIntoBody(&strategy, f.Body())
+	fmt.Printf("%s", hclwrite.Format(f.Bytes()))
+
+	// Output:
+	// name = "mm"
+	// market = {
+	//   base = {
+	//     code   = "XLM"
+	//     issuer = null
+	//   }
+	//   counter = {
+	//     code   = "USD"
+	//     issuer = "GABC"
+	//   }
+	// }
+	//
+	// leg "buy" {
+	//   amount = 100.5
+	//   spread = 0.0025
+	// }
+	// leg "sell" {
+	//   amount = 0.0000005
+	//   spread = (1 / 3)
+	// }
+}
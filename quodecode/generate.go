@@ -0,0 +1,211 @@
+package quodecode
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// TokensForAttribute returns a sequence of tokens representing an attribute
+// definition for the given name and value, terminated by a newline.
+//
+// The value is rendered using TokensForValue, and so the same constraints
+// apply.
+func TokensForAttribute(name string, val cty.Value) hclwrite.Tokens {
+	toks := hclwrite.Tokens{
+		{
+			Type:  hclsyntax.TokenIdent,
+			Bytes: []byte(name),
+		},
+		{
+			Type:  hclsyntax.TokenEqual,
+			Bytes: []byte{'='},
+		},
+	}
+	toks = appendTokensForValue(val, toks)
+	toks = append(toks, &hclwrite.Token{
+		Type:  hclsyntax.TokenNewline,
+		Bytes: []byte{'\n'},
+	})
+	return toks
+}
+
+// TokensForValue returns a sequence of tokens that represents the given
+// constant value in the Quo native syntax.
+//
+// This is similar to hclwrite.TokensForValue, except that it also supports
+// the Quo types. Numbers that have a finite decimal representation are
+// written as decimal literals, while all others are written as a division of
+// two integers so that they can be evaluated back to exactly the same value.
+// Stellar asset amounts are written as numbers, which will convert back to
+// amounts when decoded.
+//
+// Only known values can be converted to tokens. Passing an unknown value
+// will cause this function to panic.
+func TokensForValue(val cty.Value) hclwrite.Tokens {
+	return appendTokensForValue(val, nil)
+}
+
+func appendTokensForValue(val cty.Value, toks hclwrite.Tokens) hclwrite.Tokens {
+	switch {
+
+	case !val.IsKnown():
+		panic("cannot produce tokens for unknown value")
+
+	case val.IsNull():
+		toks = append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenIdent,
+			Bytes: []byte(`null`),
+		})
+
+	case val.Type() == quoty.Number:
+		toks = appendTokensForNumber(val.EncapsulatedValue().(*big.Rat), toks)
+
+	case val.Type() == quoty.StellarAssetAmountType:
+		numVal, err := convert.Convert(val, quoty.Number)
+		if err != nil {
+			// should never happen, because all amounts are valid numbers
+			panic(fmt.Sprintf("failed to convert amount to number: %s", err))
+		}
+		toks = appendTokensForNumber(numVal.EncapsulatedValue().(*big.Rat), toks)
+
+	case val.Type() == cty.Bool:
+		var src []byte
+		if val.True() {
+			src = []byte(`true`)
+		} else {
+			src = []byte(`false`)
+		}
+		toks = append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenIdent,
+			Bytes: src,
+		})
+
+	case val.Type() == cty.String, val.Type() == cty.Number:
+		// The hclwrite rules for these types are also correct for Quo.
+		toks = append(toks, hclwrite.TokensForValue(val)...)
+
+	case val.Type().IsListType() || val.Type().IsSetType() || val.Type().IsTupleType():
+		toks = append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenOBrack,
+			Bytes: []byte{'['},
+		})
+
+		i := 0
+		for it := val.ElementIterator(); it.Next(); {
+			if i > 0 {
+				toks = append(toks, &hclwrite.Token{
+					Type:  hclsyntax.TokenComma,
+					Bytes: []byte{','},
+				})
+			}
+			_, eVal := it.Element()
+			toks = appendTokensForValue(eVal, toks)
+			i++
+		}
+
+		toks = append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenCBrack,
+			Bytes: []byte{']'},
+		})
+
+	case val.Type().IsMapType() || val.Type().IsObjectType():
+		toks = append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenOBrace,
+			Bytes: []byte{'{'},
+		})
+		if val.LengthInt() > 0 {
+			toks = append(toks, &hclwrite.Token{
+				Type:  hclsyntax.TokenNewline,
+				Bytes: []byte{'\n'},
+			})
+		}
+
+		// We sort the keys so that the result is deterministic.
+		vals := val.AsValueMap()
+		keys := make([]string, 0, len(vals))
+		for k := range vals {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if quosyntax.ValidIdentifier(k) {
+				toks = append(toks, &hclwrite.Token{
+					Type:  hclsyntax.TokenIdent,
+					Bytes: []byte(k),
+				})
+			} else {
+				toks = append(toks, hclwrite.TokensForValue(cty.StringVal(k))...)
+			}
+			toks = append(toks, &hclwrite.Token{
+				Type:  hclsyntax.TokenEqual,
+				Bytes: []byte{'='},
+			})
+			toks = appendTokensForValue(vals[k], toks)
+			toks = append(toks, &hclwrite.Token{
+				Type:  hclsyntax.TokenNewline,
+				Bytes: []byte{'\n'},
+			})
+		}
+
+		toks = append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenCBrace,
+			Bytes: []byte{'}'},
+		})
+
+	default:
+		panic(fmt.Sprintf("cannot produce tokens for %#v", val))
+	}
+
+	return toks
+}
+
+func appendTokensForNumber(br *big.Rat, toks hclwrite.Tokens) hclwrite.Tokens {
+	if br.IsInt() {
+		return append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenNumberLit,
+			Bytes: []byte(br.Num().String()),
+		})
+	}
+
+	if places, exact := quoty.ExactDecimalPlaces(br); exact {
+		return append(toks, &hclwrite.Token{
+			Type:  hclsyntax.TokenNumberLit,
+			Bytes: []byte(br.FloatString(places)),
+		})
+	}
+
+	// If there is no finite decimal representation then we'll write the
+	// number as a division, which the Quo language will evaluate to exactly
+	// the same rational number.
+	return append(toks,
+		&hclwrite.Token{
+			Type:  hclsyntax.TokenOParen,
+			Bytes: []byte{'('},
+		},
+		&hclwrite.Token{
+			Type:  hclsyntax.TokenNumberLit,
+			Bytes: []byte(br.Num().String()),
+		},
+		&hclwrite.Token{
+			Type:  hclsyntax.TokenSlash,
+			Bytes: []byte{'/'},
+		},
+		&hclwrite.Token{
+			Type:  hclsyntax.TokenNumberLit,
+			Bytes: []byte(br.Denom().String()),
+		},
+		&hclwrite.Token{
+			Type:  hclsyntax.TokenCParen,
+			Bytes: []byte{')'},
+		},
+	)
+}
@@ -0,0 +1,26 @@
+package quodecode
+
+import (
+	"math/big"
+	"reflect"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var victimExpr hcl.Expression
+var victimBody hcl.Body
+
+var exprType = reflect.TypeOf(&victimExpr).Elem()
+var bodyType = reflect.TypeOf(&victimBody).Elem()
+var blockType = reflect.TypeOf((*hcl.Block)(nil))
+var attrType = reflect.TypeOf((*hcl.Attribute)(nil))
+var attrsType = reflect.TypeOf(hcl.Attributes(nil))
+
+var valueType = reflect.TypeOf(cty.Value{})
+var ratType = reflect.TypeOf(big.Rat{})
+var bigIntType = reflect.TypeOf(big.Int{})
+var bigFloatType = reflect.TypeOf(big.Float{})
+var amountType = reflect.TypeOf(quoty.StellarAssetAmount(0))
+var assetType = reflect.TypeOf(quoty.StellarAsset{})
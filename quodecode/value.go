@@ -0,0 +1,503 @@
+package quodecode
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ImpliedType returns the cty.Type that corresponds to the Go type of the
+// given value, taking into account the special Go types that represent
+// the Quo types from package quoty.
+//
+// This is similar to gocty.ImpliedType, but with the following additional
+// mappings:
+//
+//	big.Rat, big.Int, big.Float, and all Go integer and float kinds map to quoty.Number
+//	quoty.StellarAssetAmount maps to quoty.StellarAssetAmountType
+//	quoty.StellarAsset maps to quoty.StellarAssetType
+//
+// Structs are mapped to object types using "cty" field tags, as with gocty.
+func ImpliedType(gv interface{}) (cty.Type, error) {
+	return impliedType(reflect.TypeOf(gv), make(cty.Path, 0))
+}
+
+func impliedType(rt reflect.Type, path cty.Path) (cty.Type, error) {
+	switch rt {
+	case ratType, bigIntType, bigFloatType:
+		return quoty.Number, nil
+	case amountType:
+		return quoty.StellarAssetAmountType, nil
+	case assetType:
+		return quoty.StellarAssetType, nil
+	case valueType:
+		return cty.DynamicPseudoType, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Ptr:
+		return impliedType(rt.Elem(), path)
+
+	case reflect.Bool:
+		return cty.Bool, nil
+
+	case reflect.String:
+		return cty.String, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return quoty.Number, nil
+
+	case reflect.Slice:
+		path := append(path, cty.IndexStep{Key: cty.UnknownVal(cty.Number)})
+		ety, err := impliedType(rt.Elem(), path)
+		if err != nil {
+			return cty.NilType, err
+		}
+		return cty.List(ety), nil
+
+	case reflect.Map:
+		if rt.Key().Kind() != reflect.String {
+			return cty.NilType, path.NewErrorf("no cty.Type for %s (must have string keys)", rt)
+		}
+		path := append(path, cty.IndexStep{Key: cty.UnknownVal(cty.String)})
+		ety, err := impliedType(rt.Elem(), path)
+		if err != nil {
+			return cty.NilType, err
+		}
+		return cty.Map(ety), nil
+
+	case reflect.Struct:
+		fields := structTagIndices(rt)
+		if len(fields) == 0 {
+			return cty.NilType, path.NewErrorf("no cty.Type for %s (no cty field tags)", rt)
+		}
+		atys := make(map[string]cty.Type, len(fields))
+		for k, fi := range fields {
+			path := append(path, cty.GetAttrStep{Name: k})
+			aty, err := impliedType(rt.Field(fi).Type, path)
+			if err != nil {
+				return cty.NilType, err
+			}
+			atys[k] = aty
+		}
+		return cty.Object(atys), nil
+
+	default:
+		return cty.NilType, path.NewErrorf("no cty.Type for %s", rt)
+	}
+}
+
+// FromValue assigns the given cty.Value to the Go value that the given
+// pointer refers to, converting the value first to the type implied by the
+// target, as would be returned by ImpliedType.
+//
+// If the returned error is non-nil then the target may have been
+// partially-populated.
+func FromValue(val cty.Value, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("target value must be a pointer, not %s", rv.Type().String()))
+	}
+	path := make(cty.Path, 0)
+
+	ty, err := impliedType(rv.Type().Elem(), path)
+	if err != nil {
+		return err
+	}
+	val, err = convertValue(val, ty)
+	if err != nil {
+		return err
+	}
+	return fromValue(val, rv.Elem(), path)
+}
+
+func fromValue(val cty.Value, target reflect.Value, path cty.Path) error {
+	if target.Type() == valueType {
+		target.Set(reflect.ValueOf(val))
+		return nil
+	}
+
+	if target.Kind() == reflect.Ptr {
+		if val.IsNull() {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		ev := reflect.New(target.Type().Elem())
+		if err := fromValue(val, ev.Elem(), path); err != nil {
+			return err
+		}
+		target.Set(ev)
+		return nil
+	}
+
+	if !val.IsKnown() {
+		return path.NewErrorf("value must be known")
+	}
+	if val.IsNull() {
+		switch target.Kind() {
+		case reflect.Slice, reflect.Map:
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		default:
+			return path.NewErrorf("value must not be null")
+		}
+	}
+
+	switch target.Type() {
+	case ratType:
+		br := val.EncapsulatedValue().(*big.Rat)
+		target.Addr().Interface().(*big.Rat).Set(br)
+		return nil
+	case bigIntType:
+		br := val.EncapsulatedValue().(*big.Rat)
+		if !br.IsInt() {
+			return path.NewErrorf("value must be a whole number")
+		}
+		target.Addr().Interface().(*big.Int).Set(br.Num())
+		return nil
+	case bigFloatType:
+		br := val.EncapsulatedValue().(*big.Rat)
+		target.Addr().Interface().(*big.Float).SetRat(br)
+		return nil
+	case amountType:
+		amt := val.EncapsulatedValue().(*quoty.StellarAssetAmount)
+		target.SetInt(int64(*amt))
+		return nil
+	case assetType:
+		code := val.GetAttr("code")
+		issuer := val.GetAttr("issuer")
+		if code.IsNull() {
+			return path.GetAttr("code").NewErrorf("value must not be null")
+		}
+		if !code.IsKnown() {
+			return path.GetAttr("code").NewErrorf("value must be known")
+		}
+		if !issuer.IsKnown() {
+			return path.GetAttr("issuer").NewErrorf("value must be known")
+		}
+		asset := quoty.StellarAsset{
+			Code: code.AsString(),
+		}
+		if !issuer.IsNull() {
+			asset.Issuer = issuer.AsString()
+		}
+		target.Set(reflect.ValueOf(asset))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Bool:
+		target.SetBool(val.True())
+		return nil
+
+	case reflect.String:
+		target.SetString(val.AsString())
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		br := val.EncapsulatedValue().(*big.Rat)
+		bits := uint(target.Type().Bits())
+		min := int64(-1) << (bits - 1)
+		max := int64(math.MaxInt64) >> (64 - bits)
+		if !br.IsInt() || !br.Num().IsInt64() || target.OverflowInt(br.Num().Int64()) {
+			return path.NewErrorf("value must be a whole number, between %d and %d", min, max)
+		}
+		target.SetInt(br.Num().Int64())
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		br := val.EncapsulatedValue().(*big.Rat)
+		bits := uint(target.Type().Bits())
+		max := uint64(math.MaxUint64) >> (64 - bits)
+		if !br.IsInt() || !br.Num().IsUint64() || target.OverflowUint(br.Num().Uint64()) {
+			return path.NewErrorf("value must be a whole number, between 0 and %d", max)
+		}
+		target.SetUint(br.Num().Uint64())
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		br := val.EncapsulatedValue().(*big.Rat)
+		f, _ := br.Float64()
+		if target.OverflowFloat(f) {
+			return path.NewErrorf("value must be a number that can be represented as a %s", target.Type())
+		}
+		target.SetFloat(f)
+		return nil
+
+	case reflect.Slice:
+		l := val.LengthInt()
+		sl := reflect.MakeSlice(target.Type(), l, l)
+		i := 0
+		for it := val.ElementIterator(); it.Next(); i++ {
+			_, ev := it.Element()
+			path := append(path, cty.IndexStep{Key: cty.NumberIntVal(int64(i))})
+			if err := fromValue(ev, sl.Index(i), path); err != nil {
+				return err
+			}
+		}
+		target.Set(sl)
+		return nil
+
+	case reflect.Map:
+		mv := reflect.MakeMapWithSize(target.Type(), val.LengthInt())
+		ety := target.Type().Elem()
+		kty := target.Type().Key()
+		for it := val.ElementIterator(); it.Next(); {
+			k, ev := it.Element()
+			path := append(path, cty.IndexStep{Key: k})
+			nv := reflect.New(ety)
+			if err := fromValue(ev, nv.Elem(), path); err != nil {
+				return err
+			}
+			mv.SetMapIndex(reflect.ValueOf(k.AsString()).Convert(kty), nv.Elem())
+		}
+		target.Set(mv)
+		return nil
+
+	case reflect.Struct:
+		for k, fi := range structTagIndices(target.Type()) {
+			path := append(path, cty.GetAttrStep{Name: k})
+			if err := fromValue(val.GetAttr(k), target.Field(fi), path); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		// Should never happen, since we converted to the implied type
+		// before we got here.
+		return path.NewErrorf("can't decode into %s", target.Type())
+	}
+}
+
+// ToValue produces a cty.Value representation of the given Go value, using
+// the type that would be returned for it by ImpliedType.
+func ToValue(gv interface{}) (cty.Value, error) {
+	rv := reflect.ValueOf(gv)
+	path := make(cty.Path, 0)
+	ty, err := impliedType(rv.Type(), path)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return toValue(rv, ty, path)
+}
+
+func toValue(rv reflect.Value, ty cty.Type, path cty.Path) (cty.Value, error) {
+	if rv.Type() == valueType {
+		return rv.Interface().(cty.Value), nil
+	}
+
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return cty.NullVal(ty), nil
+		}
+		return toValue(rv.Elem(), ty, path)
+	}
+
+	switch rv.Type() {
+	case ratType, bigIntType, bigFloatType:
+		if !rv.CanAddr() {
+			// The math/big types are used via pointer methods, so we need
+			// an addressable copy of the value to work with.
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			rv = ptr.Elem()
+		}
+	}
+
+	switch rv.Type() {
+	case ratType:
+		br := rv.Addr().Interface().(*big.Rat)
+		return quoty.NumberVal(new(big.Rat).Set(br)), nil
+	case bigIntType:
+		bi := rv.Addr().Interface().(*big.Int)
+		return quoty.NumberVal(new(big.Rat).SetInt(bi)), nil
+	case bigFloatType:
+		bf := rv.Addr().Interface().(*big.Float)
+		if bf.IsInf() {
+			return cty.NilVal, path.NewErrorf("infinity is not allowed")
+		}
+		br, _ := bf.Rat(nil)
+		return quoty.NumberVal(br), nil
+	case amountType:
+		return quoty.StellarAssetAmountVal(quoty.StellarAssetAmount(rv.Int())), nil
+	case assetType:
+		return quoty.StellarAssetVal(rv.Interface().(quoty.StellarAsset)), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return cty.BoolVal(rv.Bool()), nil
+
+	case reflect.String:
+		return cty.StringVal(rv.String()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return quoty.NumberIntVal(rv.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bi := new(big.Int).SetUint64(rv.Uint())
+		return quoty.NumberVal(new(big.Rat).SetInt(bi)), nil
+
+	case reflect.Float32, reflect.Float64:
+		br := new(big.Rat).SetFloat64(rv.Float())
+		if br == nil {
+			return cty.NilVal, path.NewErrorf("infinity and NaN are not allowed")
+		}
+		return quoty.NumberVal(br), nil
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return cty.NullVal(ty), nil
+		}
+		ety := ty.ElementType()
+		if rv.Len() == 0 {
+			return cty.ListValEmpty(ety), nil
+		}
+		vals := make([]cty.Value, rv.Len())
+		for i := range vals {
+			path := append(path, cty.IndexStep{Key: cty.NumberIntVal(int64(i))})
+			ev, err := toValue(rv.Index(i), ety, path)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[i] = ev
+		}
+		return cty.ListVal(vals), nil
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return cty.NullVal(ty), nil
+		}
+		ety := ty.ElementType()
+		if rv.Len() == 0 {
+			return cty.MapValEmpty(ety), nil
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		vals := make(map[string]cty.Value, len(keys))
+		for _, kv := range keys {
+			k := kv.String()
+			path := append(path, cty.IndexStep{Key: cty.StringVal(k)})
+			ev, err := toValue(rv.MapIndex(kv), ety, path)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[k] = ev
+		}
+		return cty.MapVal(vals), nil
+
+	case reflect.Struct:
+		fields := structTagIndices(rv.Type())
+		vals := make(map[string]cty.Value, len(fields))
+		for k, fi := range fields {
+			path := append(path, cty.GetAttrStep{Name: k})
+			av, err := toValue(rv.Field(fi), ty.AttributeType(k), path)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[k] = av
+		}
+		return cty.ObjectVal(vals), nil
+
+	default:
+		return cty.NilVal, path.NewErrorf("can't convert Go %s to %s", rv.Type(), ty.FriendlyName())
+	}
+}
+
+// structTagIndices interrogates the fields of the given type (which must
+// be a struct type) and returns a map from the "cty" tag names found to
+// the indices of the fields they annotate.
+func structTagIndices(st reflect.Type) map[string]int {
+	ct := st.NumField()
+	ret := make(map[string]int, ct)
+
+	for i := 0; i < ct; i++ {
+		field := st.Field(i)
+		attrName := field.Tag.Get("cty")
+		if attrName != "" {
+			ret[attrName] = i
+		}
+	}
+
+	return ret
+}
+
+// convertValue is like convert.Convert, except that numbers are also
+// converted to strings wherever the given type calls for a string, so that
+// numbers can be decoded into string fields as with gohcl.
+//
+// This is done here rather than by convert.Convert, because quoty.Number has
+// no implicit conversion to string: otherwise numbers would be converted
+// to strings anywhere a string is expected, such as function arguments.
+func convertValue(val cty.Value, ty cty.Type) (cty.Value, error) {
+	return convert.Convert(numbersToStrings(val, ty), ty)
+}
+
+// numbersToStrings returns the given value with any numbers that correspond
+// to strings in the given type replaced by their decimal representations,
+// leaving the rest of the conversion to the given type to convert.Convert.
+func numbersToStrings(val cty.Value, ty cty.Type) cty.Value {
+	vty := val.Type()
+	if ty == cty.String && vty.Equals(quoty.Number) {
+		// quoty.ToString can't fail for a number.
+		ret, _ := quoty.ToString(val)
+		return ret
+	}
+	if !val.IsKnown() || val.IsNull() {
+		return val
+	}
+
+	switch {
+	case (ty.IsListType() || ty.IsSetType()) && (vty.IsListType() || vty.IsSetType() || vty.IsTupleType()):
+		if val.LengthInt() == 0 {
+			return val
+		}
+		elems := make([]cty.Value, 0, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			elems = append(elems, numbersToStrings(elem, ty.ElementType()))
+		}
+		return cty.TupleVal(elems)
+	case ty.IsTupleType() && (vty.IsListType() || vty.IsTupleType()):
+		etys := ty.TupleElementTypes()
+		elems := make([]cty.Value, 0, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			if i := len(elems); i < len(etys) {
+				elem = numbersToStrings(elem, etys[i])
+			}
+			elems = append(elems, elem)
+		}
+		return cty.TupleVal(elems)
+	case (ty.IsMapType() || ty.IsObjectType()) && (vty.IsMapType() || vty.IsObjectType()):
+		if val.LengthInt() == 0 {
+			return val
+		}
+		attrs := make(map[string]cty.Value, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			name := key.AsString()
+			switch {
+			case ty.IsMapType():
+				elem = numbersToStrings(elem, ty.ElementType())
+			case ty.HasAttribute(name):
+				elem = numbersToStrings(elem, ty.AttributeType(name))
+			}
+			attrs[name] = elem
+		}
+		return cty.ObjectVal(attrs)
+	default:
+		return val
+	}
+}
@@ -0,0 +1,13 @@
+// Package quodiag renders the diagnostics produced when parsing and
+// evaluating Quo configuration, for people and for other programs.
+//
+// NewTextWriter produces text for a terminal, including the lines of source
+// code that each diagnostic refers to and the values of the variables used
+// by the expression that caused it. WriteJSON produces the same information
+// in a JSON format that is intended to remain stable, as described by the
+// Report type.
+//
+// Both take the parsed files, keyed by filename, as returned by the Files
+// method of hclparse.Parser, so that the source code of each diagnostic's
+// range can be found.
+package quodiag
@@ -0,0 +1,154 @@
+package quodiag
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// FormatVersion is the version of the JSON format produced by WriteJSON.
+//
+// The minor version is incremented when fields are added, and the major
+// version when any existing field is removed or its meaning changes, so
+// consumers should accept any version with the major version they expect.
+const FormatVersion = "1.0"
+
+// Report is the JSON representation of a set of diagnostics, as produced by
+// WriteJSON.
+type Report struct {
+	FormatVersion string       `json:"format_version"`
+	ErrorCount    int          `json:"error_count"`
+	WarningCount  int          `json:"warning_count"`
+	Diagnostics   []Diagnostic `json:"diagnostics"`
+}
+
+// Diagnostic is the JSON representation of a single diagnostic.
+type Diagnostic struct {
+	// Severity is either "error" or "warning".
+	Severity string `json:"severity"`
+
+	Summary string `json:"summary"`
+	Detail  string `json:"detail,omitempty"`
+
+	// Range is the subject of the diagnostic, and Snippet is its source
+	// code. Both are omitted if the diagnostic has no subject, and Snippet
+	// is also omitted if the source code isn't available.
+	Range   *Range   `json:"range,omitempty"`
+	Snippet *Snippet `json:"snippet,omitempty"`
+
+	// Values are the values of the variables referenced by the expression
+	// that caused the diagnostic, sorted by traversal.
+	Values []Value `json:"values,omitempty"`
+}
+
+// Range is the JSON representation of hcl.Range.
+type Range struct {
+	Filename string `json:"filename"`
+	Start    Pos    `json:"start"`
+	End      Pos    `json:"end"`
+}
+
+// Pos is the JSON representation of hcl.Pos.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// Snippet is the source code of the lines containing the subject of a
+// diagnostic, and its context range if it has one.
+type Snippet struct {
+	// Context describes the construct containing the subject, such as the
+	// block it belongs to, if there is one.
+	Context string `json:"context,omitempty"`
+
+	// Code is the source code of the lines, separated by newlines, and
+	// StartLine is the line number of the first of them.
+	Code      string `json:"code"`
+	StartLine int    `json:"start_line"`
+
+	// HighlightStartOffset and HighlightEndOffset are the byte offsets in
+	// Code of the subject of the diagnostic.
+	HighlightStartOffset int `json:"highlight_start_offset"`
+	HighlightEndOffset   int `json:"highlight_end_offset"`
+}
+
+// Value is the value of a variable referenced by the expression that caused
+// a diagnostic.
+type Value struct {
+	// Traversal is the reference to the variable in native syntax, such as
+	// "strategy.a.spread".
+	Traversal string `json:"traversal"`
+
+	// Value is a concise description of the value. Numbers and strings are
+	// shown in full, while collections and objects are summarized.
+	Value string `json:"value"`
+}
+
+// NewReport returns the JSON representation of the given diagnostics, using
+// the source code from the given files.
+func NewReport(diags hcl.Diagnostics, files map[string]*hcl.File) *Report {
+	ret := &Report{
+		FormatVersion: FormatVersion,
+		Diagnostics:   make([]Diagnostic, 0, len(diags)),
+	}
+	for _, diag := range diags {
+		switch diag.Severity {
+		case hcl.DiagError:
+			ret.ErrorCount++
+		case hcl.DiagWarning:
+			ret.WarningCount++
+		}
+		ret.Diagnostics = append(ret.Diagnostics, newDiagnostic(diag, files))
+	}
+	return ret
+}
+
+// WriteJSON writes the JSON representation of the given diagnostics, as
+// described by Report, to the given writer, followed by a newline.
+func WriteJSON(wr io.Writer, diags hcl.Diagnostics, files map[string]*hcl.File) error {
+	return json.NewEncoder(wr).Encode(NewReport(diags, files))
+}
+
+func newDiagnostic(diag *hcl.Diagnostic, files map[string]*hcl.File) Diagnostic {
+	ret := Diagnostic{
+		Summary: diag.Summary,
+		Detail:  diag.Detail,
+	}
+	switch diag.Severity {
+	case hcl.DiagError:
+		ret.Severity = "error"
+	case hcl.DiagWarning:
+		ret.Severity = "warning"
+	}
+
+	if diag.Subject != nil {
+		ret.Range = newRange(*diag.Subject)
+	}
+	if snip := newSnippet(diag, files); snip != nil {
+		code, start, end := snip.code()
+		ret.Snippet = &Snippet{
+			Context:              snip.context,
+			Code:                 code,
+			StartLine:            snip.lines[0].number,
+			HighlightStartOffset: start,
+			HighlightEndOffset:   end,
+		}
+	}
+	for _, v := range variableValues(diag) {
+		ret.Values = append(ret.Values, Value{
+			Traversal: v.traversal,
+			Value:     v.value,
+		})
+	}
+	return ret
+}
+
+func newRange(rng hcl.Range) *Range {
+	return &Range{
+		Filename: rng.Filename,
+		Start:    Pos{Line: rng.Start.Line, Column: rng.Start.Column, Byte: rng.Start.Byte},
+		End:      Pos{Line: rng.End.Line, Column: rng.End.Column, Byte: rng.End.Byte},
+	}
+}
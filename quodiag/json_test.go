@@ -0,0 +1,44 @@
+package quodiag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	files, diags := testDiagnostics(t)
+
+	var buf strings.Builder
+	if err := WriteJSON(&buf, diags, files); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"format_version":"1.0","error_count":3,"warning_count":1,"diagnostics":[` +
+		`{"severity":"error","summary":"Invalid operand","detail":"Unsuitable value for right operand: a number is required.",` +
+		`"range":{"filename":"test.quo","start":{"line":2,"column":27,"byte":41},"end":{"line":2,"column":32,"byte":46}},` +
+		`"snippet":{"context":"strategy \"a\" \u003e attribute \"mid\"","code":"  mid = (bid + ask) / 2 + label","start_line":2,"highlight_start_offset":26,"highlight_end_offset":31},` +
+		`"values":[{"traversal":"label","value":"\"x\""}]},` +
+		`{"severity":"warning","summary":"Wide spread","detail":"The spread is more than a third of the mid price, which is unusually wide for this market.",` +
+		`"range":{"filename":"test.quo","start":{"line":4,"column":5,"byte":64},"end":{"line":5,"column":10,"byte":83}},` +
+		`"snippet":{"context":"strategy \"a\" \u003e attribute \"spread\"","code":"    ask - bid\n  ) / mid","start_line":4,"highlight_start_offset":4,"highlight_end_offset":23},` +
+		`"values":[{"traversal":"ask","value":"1.5"},{"traversal":"bid","value":"1"},{"traversal":"mid","value":"1.25"}]},` +
+		`{"severity":"error","summary":"No source",` +
+		`"range":{"filename":"other.quo","start":{"line":3,"column":1,"byte":20},"end":{"line":3,"column":5,"byte":24}}},` +
+		`{"severity":"error","summary":"No subject","detail":"This diagnostic doesn't refer to the source code."}` +
+		"]}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("wrong output\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestWriteJSONEmpty(t *testing.T) {
+	var buf strings.Builder
+	if err := WriteJSON(&buf, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"format_version":"1.0","error_count":0,"warning_count":0,"diagnostics":[]}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("wrong output\ngot:  %s\nwant: %s", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package quodiag
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// snippet is the source code surrounding the subject of a diagnostic.
+type snippet struct {
+	// context describes the construct containing the subject, such as
+	// the block it belongs to, or is empty if there is no such construct.
+	context string
+
+	lines []snippetLine
+}
+
+// snippetLine is a single line of a snippet, with the byte offsets within
+// the line of the part of it that is highlighted. The offsets are equal if
+// no part of the line is highlighted.
+type snippetLine struct {
+	number         int
+	text           []byte
+	highlightStart int
+	highlightEnd   int
+}
+
+// newSnippet returns the snippet for the given diagnostic, or nil if it has
+// no subject or its source code is not in the given files.
+func newSnippet(diag *hcl.Diagnostic, files map[string]*hcl.File) *snippet {
+	if diag.Subject == nil {
+		return nil
+	}
+	file := files[diag.Subject.Filename]
+	if file == nil || file.Bytes == nil {
+		return nil
+	}
+
+	snipRange := *diag.Subject
+	highlightRange := snipRange
+	if diag.Context != nil {
+		// Show enough of the source code to include both the subject and
+		// context ranges, which overlap in all reasonable situations.
+		snipRange = hcl.RangeOver(snipRange, *diag.Context)
+	}
+	// We can't illustrate an empty range, so we'll turn such ranges into
+	// single-character ranges, which might point off the end of a line but
+	// are good enough for the partitioning below.
+	if snipRange.Empty() {
+		snipRange.End.Byte++
+		snipRange.End.Column++
+	}
+	if highlightRange.Empty() {
+		highlightRange.End.Byte++
+		highlightRange.End.Column++
+	}
+
+	ret := &snippet{
+		context: contextString(file, diag.Subject.Start.Byte),
+	}
+	src := file.Bytes
+	sc := hcl.NewRangeScanner(src, diag.Subject.Filename, bufio.ScanLines)
+	for sc.Scan() {
+		lineRange := sc.Range()
+		if !lineRange.Overlaps(snipRange) {
+			continue
+		}
+		before, highlighted, _ := lineRange.PartitionAround(highlightRange)
+		line := snippetLine{
+			number: lineRange.Start.Line,
+			text:   append([]byte(nil), sc.Bytes()...),
+		}
+		if !highlighted.Empty() {
+			line.highlightStart = len(before.SliceBytes(src))
+			line.highlightEnd = line.highlightStart + len(highlighted.SliceBytes(src))
+		}
+		ret.lines = append(ret.lines, line)
+	}
+	if len(ret.lines) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// code returns the lines of the snippet joined together, along with the
+// byte offsets within the result of the highlighted part.
+func (s *snippet) code() (string, int, int) {
+	var buf bytes.Buffer
+	start, end := -1, -1
+	for i, line := range s.lines {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if line.highlightStart != line.highlightEnd {
+			if start < 0 {
+				start = buf.Len() + line.highlightStart
+			}
+			end = buf.Len() + line.highlightEnd
+		}
+		buf.Write(line.text)
+	}
+	if start < 0 {
+		start, end = 0, 0
+	}
+	return buf.String(), start, end
+}
+
+func contextString(file *hcl.File, offset int) string {
+	type contextStringer interface {
+		ContextString(offset int) string
+	}
+
+	if cser, ok := file.Nav.(contextStringer); ok {
+		return cser.ContextString(offset)
+	}
+	return ""
+}
+
+// variableValue is the value of one of the variables referenced by the
+// expression of a diagnostic.
+type variableValue struct {
+	traversal string
+	value     string
+}
+
+// variableValues returns the values of the variables referenced by the
+// expression of the given diagnostic, sorted by their traversals.
+func variableValues(diag *hcl.Diagnostic) []variableValue {
+	if diag.Expression == nil || diag.EvalContext == nil {
+		return nil
+	}
+
+	var ret []variableValue
+	seen := make(map[string]bool)
+	for _, traversal := range diag.Expression.Variables() {
+		val, diags := traversal.TraverseAbs(diag.EvalContext)
+		if diags.HasErrors() || !val.IsKnown() {
+			// Anything that produces errors probably has its own diagnostic
+			// already, and there's nothing to say about unknown values.
+			continue
+		}
+		traversalStr := traversalString(traversal)
+		if seen[traversalStr] {
+			continue
+		}
+		seen[traversalStr] = true
+		ret = append(ret, variableValue{
+			traversal: traversalStr,
+			value:     valueString(val),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].traversal < ret[j].traversal
+	})
+	return ret
+}
+
+// traversalString returns the given traversal in native syntax, except that
+// any complex index keys are replaced with a placeholder.
+func traversalString(traversal hcl.Traversal) string {
+	var buf bytes.Buffer
+	for _, step := range traversal {
+		switch tStep := step.(type) {
+		case hcl.TraverseRoot:
+			buf.WriteString(tStep.Name)
+		case hcl.TraverseAttr:
+			buf.WriteByte('.')
+			buf.WriteString(tStep.Name)
+		case hcl.TraverseIndex:
+			buf.WriteByte('[')
+			if tStep.Key.Type().IsPrimitiveType() {
+				buf.WriteString(valueString(tStep.Key))
+			} else {
+				buf.WriteString("...")
+			}
+			buf.WriteByte(']')
+		}
+	}
+	return buf.String()
+}
+
+// valueString returns a concise description of the given value, suitable
+// for diagnostics. Collections and structural values are summarized rather
+// than shown in full.
+func valueString(val cty.Value) string {
+	ty := val.Type()
+	switch {
+	case val.IsNull():
+		return "null"
+	case !val.IsKnown():
+		return "(not yet known)"
+	case ty == cty.Bool:
+		if val.True() {
+			return "true"
+		}
+		return "false"
+	case ty == cty.Number:
+		return val.AsBigFloat().Text('g', 10)
+	case ty == cty.String:
+		return fmt.Sprintf("%q", val.AsString())
+	case ty.IsCapsuleType():
+		// A quoty.Number is converted to its decimal representation.
+		if strVal, err := quoty.ToString(val); err == nil {
+			return strVal.AsString()
+		}
+		return ty.FriendlyName()
+	case ty.IsCollectionType() || ty.IsTupleType():
+		switch l := val.LengthInt(); l {
+		case 0:
+			return "empty " + ty.FriendlyName()
+		case 1:
+			return ty.FriendlyName() + " with 1 element"
+		default:
+			return fmt.Sprintf("%s with %d elements", ty.FriendlyName(), l)
+		}
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		switch l := len(atys); l {
+		case 0:
+			return "object with no attributes"
+		case 1:
+			var name string
+			for k := range atys {
+				name = k
+			}
+			return fmt.Sprintf("object with 1 attribute %q", name)
+		default:
+			return fmt.Sprintf("object with %d attributes", l)
+		}
+	default:
+		return ty.FriendlyName()
+	}
+}
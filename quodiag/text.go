@@ -0,0 +1,192 @@
+package quodiag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+type textWriter struct {
+	files map[string]*hcl.File
+	wr    io.Writer
+	width uint
+	color bool
+}
+
+// NewTextWriter creates a DiagnosticWriter that writes diagnostics to the
+// given writer as formatted text, including the source code from the given
+// files that each diagnostic refers to and the values of the variables used
+// by the expression that caused it.
+//
+// The given width may be zero to disable word-wrapping of the detail text.
+//
+// If color is set to true, the output will include VT100 escape sequences to
+// color-code the severity indicators and highlight the relevant part of the
+// source code. Otherwise, the relevant part is underlined on the following
+// line.
+func NewTextWriter(wr io.Writer, files map[string]*hcl.File, width uint, color bool) hcl.DiagnosticWriter {
+	return &textWriter{
+		files: files,
+		wr:    wr,
+		width: width,
+		color: color,
+	}
+}
+
+func (w *textWriter) WriteDiagnostic(diag *hcl.Diagnostic) error {
+	if diag == nil {
+		return errors.New("nil diagnostic")
+	}
+
+	var buf strings.Builder
+
+	var colorCode, highlightCode, resetCode string
+	if w.color {
+		switch diag.Severity {
+		case hcl.DiagError:
+			colorCode = "\x1b[31m"
+		case hcl.DiagWarning:
+			colorCode = "\x1b[33m"
+		}
+		resetCode = "\x1b[0m"
+		highlightCode = "\x1b[1;4m"
+	}
+
+	fmt.Fprintf(&buf, "%s%s%s: %s\n\n", colorCode, severityString(diag.Severity), resetCode, diag.Summary)
+
+	if diag.Subject != nil {
+		if snip := newSnippet(diag, w.files); snip != nil {
+			context := ""
+			if snip.context != "" {
+				context = ", in " + snip.context
+			}
+			fmt.Fprintf(&buf, "  on %s line %d%s:\n", diag.Subject.Filename, diag.Subject.Start.Line, context)
+
+			for _, line := range snip.lines {
+				if line.highlightStart == line.highlightEnd {
+					fmt.Fprintf(&buf, "%4d: %s\n", line.number, line.text)
+					continue
+				}
+				fmt.Fprintf(
+					&buf, "%4d: %s%s%s%s%s\n",
+					line.number,
+					line.text[:line.highlightStart],
+					highlightCode, line.text[line.highlightStart:line.highlightEnd], resetCode,
+					line.text[line.highlightEnd:],
+				)
+				if !w.color {
+					buf.WriteString("      ")
+					buf.WriteString(underline(line))
+					buf.WriteByte('\n')
+				}
+			}
+		} else {
+			fmt.Fprintf(&buf, "  on %s line %d:\n  (source code not available)\n", diag.Subject.Filename, diag.Subject.Start.Line)
+		}
+		buf.WriteByte('\n')
+	}
+
+	if vals := variableValues(diag); len(vals) != 0 {
+		for i, v := range vals {
+			if i == 0 {
+				buf.WriteString("with ")
+			} else {
+				buf.WriteString("     ")
+			}
+			if v.value == "null" {
+				fmt.Fprintf(&buf, "%s set to null", v.traversal)
+			} else {
+				fmt.Fprintf(&buf, "%s as %s", v.traversal, v.value)
+			}
+			if i == len(vals)-1 {
+				buf.WriteString(".\n\n")
+			} else {
+				buf.WriteString(",\n")
+			}
+		}
+	}
+
+	if diag.Detail != "" {
+		detail := diag.Detail
+		if w.width != 0 {
+			detail = wrapString(detail, int(w.width))
+		}
+		fmt.Fprintf(&buf, "%s\n\n", detail)
+	}
+
+	_, err := io.WriteString(w.wr, buf.String())
+	return err
+}
+
+func (w *textWriter) WriteDiagnostics(diags hcl.Diagnostics) error {
+	for _, diag := range diags {
+		err := w.WriteDiagnostic(diag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func severityString(severity hcl.DiagnosticSeverity) string {
+	switch severity {
+	case hcl.DiagError:
+		return "Error"
+	case hcl.DiagWarning:
+		return "Warning"
+	default:
+		// should never happen
+		return "???????"
+	}
+}
+
+// underline returns a line of carets beneath the highlighted part of the
+// given line, preserving any tabs before it so that the carets line up.
+func underline(line snippetLine) string {
+	var buf strings.Builder
+	for _, r := range string(line.text[:line.highlightStart]) {
+		if r == '\t' {
+			buf.WriteRune('\t')
+		} else {
+			buf.WriteByte(' ')
+		}
+	}
+	n := utf8.RuneCount(line.text[line.highlightStart:line.highlightEnd])
+	if n == 0 {
+		n = 1
+	}
+	buf.WriteString(strings.Repeat("^", n))
+	return buf.String()
+}
+
+// wrapString wraps the given text so that no line is longer than the given
+// width, except for words that are longer than the width on their own.
+// Existing line breaks are preserved.
+func wrapString(s string, width int) string {
+	var buf strings.Builder
+	for i, para := range strings.Split(s, "\n") {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		lineLen := 0
+		for j, word := range strings.Fields(para) {
+			wordLen := utf8.RuneCountInString(word)
+			switch {
+			case j == 0:
+			case lineLen+1+wordLen > width:
+				buf.WriteByte('\n')
+				lineLen = 0
+			default:
+				buf.WriteByte(' ')
+				lineLen++
+			}
+			buf.WriteString(word)
+			lineLen += wordLen
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,148 @@
+package quodiag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testSource = `strategy "a" {
+  mid = (bid + ask) / 2 + label
+  spread = (
+    ask - bid
+  ) / mid
+}
+`
+
+// testDiagnostics returns the parsed test source and some diagnostics that
+// refer to it.
+func testDiagnostics(t *testing.T) (map[string]*hcl.File, hcl.Diagnostics) {
+	t.Helper()
+
+	file, diags := quosyntax.ParseConfig([]byte(testSource), "test.quo", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(diags) != 0 {
+		t.Fatalf("unexpected parse diagnostics: %s", diags.Error())
+	}
+	content, diags := file.Body.Content(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "strategy", LabelNames: []string{"name"}},
+		},
+	})
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	attrs, diags := content.Blocks[0].Body.JustAttributes()
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"bid":   quoty.NumberIntVal(1),
+			"ask":   quoty.MustParseNumberVal("1.5"),
+			"mid":   quoty.MustParseNumberVal("1.25"),
+			"label": cty.StringVal("x"),
+			"book":  cty.ListValEmpty(quoty.Number),
+		},
+	}
+	_, diags = attrs["mid"].Expr.Value(ctx)
+
+	spread := attrs["spread"].Expr
+	diags = append(diags, &hcl.Diagnostic{
+		Severity:    hcl.DiagWarning,
+		Summary:     "Wide spread",
+		Detail:      "The spread is more than a third of the mid price, which is unusually wide for this market.",
+		Subject:     spread.Range().Ptr(),
+		Expression:  spread,
+		EvalContext: ctx,
+	})
+	diags = append(diags, &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "No source",
+		Subject: &hcl.Range{
+			Filename: "other.quo",
+			Start:    hcl.Pos{Line: 3, Column: 1, Byte: 20},
+			End:      hcl.Pos{Line: 3, Column: 5, Byte: 24},
+		},
+	})
+	diags = append(diags, &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "No subject",
+		Detail:   "This diagnostic doesn't refer to the source code.",
+	})
+
+	return map[string]*hcl.File{"test.quo": file}, diags
+}
+
+func TestTextWriter(t *testing.T) {
+	files, diags := testDiagnostics(t)
+
+	var buf strings.Builder
+	if err := NewTextWriter(&buf, files, 40, false).WriteDiagnostics(diags); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Error: Invalid operand
+
+  on test.quo line 2, in strategy "a" > attribute "mid":
+   2:   mid = (bid + ask) / 2 + label
+                                ^^^^^
+
+with label as "x".
+
+Unsuitable value for right operand: a
+number is required.
+
+Warning: Wide spread
+
+  on test.quo line 4, in strategy "a" > attribute "spread":
+   4:     ask - bid
+          ^^^^^^^^^
+   5:   ) / mid
+      ^^^^^^^^^
+
+with ask as 1.5,
+     bid as 1,
+     mid as 1.25.
+
+The spread is more than a third of the
+mid price, which is unusually wide for
+this market.
+
+Error: No source
+
+  on other.quo line 3:
+  (source code not available)
+
+Error: No subject
+
+This diagnostic doesn't refer to the
+source code.
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTextWriterColor(t *testing.T) {
+	files, diags := testDiagnostics(t)
+
+	var buf strings.Builder
+	if err := NewTextWriter(&buf, files, 0, true).WriteDiagnostic(diags[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\x1b[31mError\x1b[0m: Invalid operand\n\n" +
+		"  on test.quo line 2, in strategy \"a\" > attribute \"mid\":\n" +
+		"   2:   mid = (bid + ask) / 2 + \x1b[1;4mlabel\x1b[0m\n\n" +
+		"with label as \"x\".\n\n" +
+		"Unsuitable value for right operand: a number is required.\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
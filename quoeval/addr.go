@@ -0,0 +1,100 @@
+package quoeval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Addr is the address of an attribute, or of a block containing attributes,
+// as a sequence of names: the name of the root variable, followed by any
+// block labels and the attribute name.
+type Addr []string
+
+// ParseAddr parses an address written using the traversal syntax, such as
+// strategy.a.spread or strategy["a"].spread.
+func ParseAddr(s string) (Addr, hcl.Diagnostics) {
+	traversal, diags := quosyntax.ParseTraversalAbs([]byte(s), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	addr := Addr{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		name, ok := stepName(step)
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid address",
+				Detail:   "An address may contain only attribute names and string indices.",
+				Subject:  step.SourceRange().Ptr(),
+			})
+			return nil, diags
+		}
+		addr = append(addr, name)
+	}
+	return addr, diags
+}
+
+// String returns the address in the traversal syntax accepted by ParseAddr.
+func (a Addr) String() string {
+	var buf strings.Builder
+	for i, name := range a {
+		switch {
+		case i == 0:
+			buf.WriteString(name)
+		case quosyntax.ValidIdentifier(name):
+			buf.WriteByte('.')
+			buf.WriteString(name)
+		default:
+			fmt.Fprintf(&buf, "[%q]", name)
+		}
+	}
+	return buf.String()
+}
+
+// Equal returns true if the receiver and the other address are the same.
+func (a Addr) Equal(other Addr) bool {
+	if len(a) != len(other) {
+		return false
+	}
+	for i := range a {
+		if a[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns a string that uniquely identifies the address, for use as a
+// map key.
+func (a Addr) key() string {
+	return strings.Join(a, "\x00")
+}
+
+// child returns a new address with the given name appended, without
+// modifying the receiver.
+func (a Addr) child(name string) Addr {
+	ret := make(Addr, len(a), len(a)+1)
+	copy(ret, a)
+	return append(ret, name)
+}
+
+// stepName returns the name selected by the given traversal step, if it is
+// one that can appear in an address.
+func stepName(step hcl.Traverser) (string, bool) {
+	switch step := step.(type) {
+	case hcl.TraverseAttr:
+		return step.Name, true
+	case hcl.TraverseIndex:
+		if !step.Key.IsKnown() || step.Key.IsNull() || step.Key.Type() != cty.String {
+			return "", false
+		}
+		return step.Key.AsString(), true
+	default:
+		return "", false
+	}
+}
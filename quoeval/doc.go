@@ -0,0 +1,27 @@
+// Package quoeval evaluates the attributes of a Quo configuration body in an
+// order that respects the references between them.
+//
+// The attributes of the body, and of its blocks, are each given an address
+// made of the name of the variable they can be referenced through, the labels
+// of the block they belong to, and the attribute name. For example, the
+// attribute in the following block:
+//
+//	strategy "a" {
+//	  spread = 0.01
+//	}
+//
+// has the address strategy.a.spread, and can be referred to by that traversal
+// from any other attribute in the same body. Blocks without labels, such as
+// a "locals" block, contribute their attributes directly beneath the block
+// type, as in locals.name, and multiple such blocks are merged together.
+//
+// BuildGraph analyzes the references in each attribute's expression to
+// produce a Graph, which can then be evaluated with an EvalContext that
+// provides any further variables and functions. The result of evaluation is
+// a new EvalContext in which the evaluated attributes are available as
+// variables. Graph.EvaluateParallel produces the same result while
+// evaluating independent attributes concurrently.
+//
+// The Graph can also be inspected without evaluating it, for example to
+// determine which attributes depend on a particular attribute.
+package quoeval
@@ -0,0 +1,93 @@
+package quoeval
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Evaluate is a helper that builds the graph for the given body and then
+// evaluates it in the given context.
+func Evaluate(body hcl.Body, schema *hcl.BodySchema, ctx *hcl.EvalContext) (*hcl.EvalContext, hcl.Diagnostics) {
+	g, diags := BuildGraph(body, schema)
+	ret, evalDiags := g.Evaluate(ctx)
+	diags = append(diags, evalDiags...)
+	return ret, diags
+}
+
+// Evaluate evaluates the attributes in the graph, each after the attributes
+// it depends on, and returns a child of the given context in which the
+// results are available as variables.
+//
+// Each attribute is evaluated in a child of the given context that contains
+// the results of evaluating the attributes it refers to. The variable for a
+// block type is an object with an attribute for each block label and so on,
+// with the attributes of the blocks at the leaves.
+//
+// The diagnostics for the attributes are returned in source order.
+//
+// The attributes that are part of a cycle are not evaluated, and have the
+// value cty.DynamicVal. BuildGraph reports the cycles themselves.
+func (g *Graph) Evaluate(ctx *hcl.EvalContext) (*hcl.EvalContext, hcl.Diagnostics) {
+	vals := make(map[*Node]cty.Value, len(g.nodes))
+	nodeDiags := make(map[*Node]hcl.Diagnostics)
+	for _, node := range g.order {
+		val, diags := node.Attr.Expr.Value(g.scope(ctx, node.roots, vals))
+		vals[node] = val
+		nodeDiags[node] = diags
+	}
+
+	return g.scope(ctx, g.roots(), vals), g.mergeDiags(nodeDiags)
+}
+
+// mergeDiags returns the given diagnostics for each node, in the source
+// order of the nodes.
+func (g *Graph) mergeDiags(nodeDiags map[*Node]hcl.Diagnostics) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, node := range g.nodes {
+		diags = append(diags, nodeDiags[node]...)
+	}
+	return diags
+}
+
+// scope returns a child of the given context that contains the given root
+// variables, built from the given values.
+func (g *Graph) scope(ctx *hcl.EvalContext, roots []string, vals map[*Node]cty.Value) *hcl.EvalContext {
+	ret := ctx.NewChild()
+	ret.Variables = make(map[string]cty.Value, len(roots))
+	for _, root := range roots {
+		ret.Variables[root] = g.value(Addr{root}, vals)
+	}
+	return ret
+}
+
+// value returns the value at the given address, built from the given values
+// of the nodes. Nodes without a value have the value cty.DynamicVal.
+func (g *Graph) value(addr Addr, vals map[*Node]cty.Value) cty.Value {
+	if node := g.byKey[addr.key()]; node != nil {
+		if val, exists := vals[node]; exists {
+			return val
+		}
+		return cty.DynamicVal
+	}
+
+	attrs := make(map[string]cty.Value)
+	for _, name := range g.children[addr.key()] {
+		attrs[name] = g.value(addr.child(name), vals)
+	}
+	return cty.ObjectVal(attrs)
+}
+
+// roots returns the names of the root variables defined by the graph, in
+// source order.
+func (g *Graph) roots() []string {
+	var ret []string
+	seen := make(map[string]bool)
+	for _, node := range g.nodes {
+		root := node.Addr[0]
+		if !seen[root] {
+			seen[root] = true
+			ret = append(ret, root)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,97 @@
+package quoeval
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestEvaluate(t *testing.T) {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"price": quoty.NumberIntVal(200),
+		},
+	}
+
+	got, diags := Evaluate(parseBody(t, testConfig), testSchema, ctx)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	if got.Parent() != ctx {
+		t.Errorf("result is not a child of the given context")
+	}
+
+	spreadA := quoty.NumberIntVal(4)
+	spreadB := quoty.MustParseNumberVal("2.0025")
+	want := map[string]cty.Value{
+		"fee":   quoty.MustParseNumberVal("0.0025"),
+		"total": quoty.MustParseNumberVal("6.005"),
+		"strategy": cty.ObjectVal(map[string]cty.Value{
+			"a": cty.ObjectVal(map[string]cty.Value{
+				"spread": spreadA,
+			}),
+			"b": cty.ObjectVal(map[string]cty.Value{
+				"spread": spreadB,
+			}),
+		}),
+		"locals": cty.ObjectVal(map[string]cty.Value{
+			"base": quoty.NumberIntVal(2),
+			"name": cty.StringVal("a"),
+		}),
+		"pick": spreadA,
+		"whole": cty.ObjectVal(map[string]cty.Value{
+			"spread": spreadA,
+		}),
+	}
+
+	if len(got.Variables) != len(want) {
+		t.Errorf("wrong number of variables %d; want %d", len(got.Variables), len(want))
+	}
+	for name, wantVal := range want {
+		if gotVal := got.Variables[name]; !gotVal.RawEquals(wantVal) {
+			t.Errorf("wrong value for %s\ngot:  %#v\nwant: %#v", name, gotVal, wantVal)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	src := `
+e = a + 1
+a = b
+b = a
+c = strategy.b.spread
+d = nope
+
+strategy "a" {
+  spread = 1
+}
+`
+	got, diags := Evaluate(parseBody(t, src), testSchema, nil)
+
+	var summaries []string
+	for _, diag := range diags {
+		summaries = append(summaries, diag.Summary)
+	}
+	want := []string{
+		"Cycle in references",
+		"Unsupported attribute",
+		"Unknown variable",
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("wrong diagnostics %q; want %q", summaries, want)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("wrong diagnostic %d %q; want %q", i, summaries[i], want[i])
+		}
+	}
+
+	if val := got.Variables["a"]; !val.RawEquals(cty.DynamicVal) {
+		t.Errorf("wrong value for a %#v; want cty.DynamicVal", val)
+	}
+	if val := got.Variables["e"]; val.IsKnown() {
+		t.Errorf("value for e is known; want unknown")
+	}
+}
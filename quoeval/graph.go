@@ -0,0 +1,468 @@
+package quoeval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Node is an attribute in a Graph.
+type Node struct {
+	Addr Addr
+	Attr *hcl.Attribute
+
+	// deps are the references from this attribute's expression to other
+	// attributes, in the order they appear.
+	deps []reference
+
+	// roots are the names of the root variables defined by the graph that
+	// this attribute's expression refers to, whether or not the references
+	// resolve to attributes.
+	roots []string
+}
+
+// reference is a dependency of one attribute on another.
+type reference struct {
+	target *Node
+
+	// srcRange is the range of the traversal that makes the reference.
+	srcRange hcl.Range
+}
+
+// Graph describes the attributes of a body and the references between them.
+type Graph struct {
+	nodes []*Node
+
+	byKey map[string]*Node
+
+	// children maps the key of each address that has nodes beneath it to
+	// the names of its immediate children, in source order.
+	children map[string][]string
+
+	dependents map[*Node][]*Node
+
+	// order is the order in which the nodes can be evaluated, with each
+	// node after all of its dependencies. Nodes that are part of a cycle
+	// are excluded.
+	order []*Node
+}
+
+// BuildGraph analyzes the given body, using the given schema, to produce the
+// graph of references between its attributes.
+//
+// The attributes of the body itself are addressed by their names. The
+// bodies of blocks may contain only attributes, which are addressed by the
+// block type, the block labels and the attribute name.
+//
+// References that can't be resolved to any attribute in the body are
+// assumed to refer to variables that will be provided when evaluating the
+// graph, and so are not included in the graph.
+//
+// If there are cycles in the references between attributes, an error
+// diagnostic is returned for each, but the graph is still returned and can
+// still be evaluated: the attributes that are part of a cycle will have
+// unknown values.
+func BuildGraph(body hcl.Body, schema *hcl.BodySchema) (*Graph, hcl.Diagnostics) {
+	g := &Graph{
+		byKey:      make(map[string]*Node),
+		children:   make(map[string][]string),
+		dependents: make(map[*Node][]*Node),
+	}
+
+	content, diags := body.Content(schema)
+	if content == nil {
+		return g, diags
+	}
+
+	// We'll add the nodes in source order, so that diagnostics about
+	// duplicates refer to the later definitions.
+	var defs []*Node
+	for _, attr := range content.Attributes {
+		defs = append(defs, &Node{
+			Addr: Addr{attr.Name},
+			Attr: attr,
+		})
+	}
+	for _, block := range content.Blocks {
+		attrs, attrDiags := block.Body.JustAttributes()
+		diags = append(diags, attrDiags...)
+
+		base := append(Addr{block.Type}, block.Labels...)
+		for _, attr := range attrs {
+			defs = append(defs, &Node{
+				Addr: base.child(attr.Name),
+				Attr: attr,
+			})
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		a, b := defs[i].Attr.Range, defs[j].Attr.Range
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Start.Byte < b.Start.Byte
+	})
+	for _, node := range defs {
+		diags = append(diags, g.add(node)...)
+	}
+
+	for _, node := range g.nodes {
+		g.resolve(node)
+	}
+	for _, node := range g.nodes {
+		seen := make(map[*Node]bool)
+		for _, ref := range node.deps {
+			if seen[ref.target] {
+				continue
+			}
+			seen[ref.target] = true
+			g.dependents[ref.target] = append(g.dependents[ref.target], node)
+		}
+	}
+
+	diags = append(diags, g.topoSort()...)
+	return g, diags
+}
+
+// add adds the given node to the graph, unless its address conflicts with
+// that of an existing node.
+func (g *Graph) add(node *Node) hcl.Diagnostics {
+	addr, attr := node.Addr, node.Attr
+	if existing := g.byKey[addr.key()]; existing != nil {
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate definition",
+				Detail: fmt.Sprintf(
+					"%s was already defined at %s.",
+					addr, existing.Attr.NameRange,
+				),
+				Subject: &attr.NameRange,
+			},
+		}
+	}
+
+	// An attribute can't share its address with a block that contains
+	// other attributes, or be contained by another attribute.
+	var conflict *Node
+	if _, exists := g.children[addr.key()]; exists {
+		conflict = g.under(addr)[0]
+	}
+	for i := 1; i < len(addr) && conflict == nil; i++ {
+		conflict = g.byKey[addr[:i].key()]
+	}
+	if conflict != nil {
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Conflicting definitions",
+				Detail: fmt.Sprintf(
+					"%s can't be defined here, because it conflicts with %s defined at %s.",
+					addr, conflict.Addr, conflict.Attr.NameRange,
+				),
+				Subject: &attr.NameRange,
+			},
+		}
+	}
+
+	g.nodes = append(g.nodes, node)
+	g.byKey[addr.key()] = node
+	for i := 1; i < len(addr); i++ {
+		parent := addr[:i].key()
+		exists := false
+		for _, name := range g.children[parent] {
+			if name == addr[i] {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			g.children[parent] = append(g.children[parent], addr[i])
+		}
+	}
+	return nil
+}
+
+// resolve finds the dependencies of the given node.
+func (g *Graph) resolve(node *Node) {
+	roots := make(map[string]bool)
+	for _, traversal := range node.Attr.Expr.Variables() {
+		root := traversal.RootName()
+		addr := Addr{root}
+		if g.byKey[addr.key()] == nil && g.children[addr.key()] == nil {
+			// Not one of ours, so presumably a variable from the
+			// EvalContext that the graph will be evaluated in.
+			continue
+		}
+		if !roots[root] {
+			roots[root] = true
+			node.roots = append(node.roots, root)
+		}
+
+		for _, target := range g.targets(traversal) {
+			node.deps = append(node.deps, reference{
+				target:   target,
+				srcRange: traversal.SourceRange(),
+			})
+		}
+	}
+}
+
+// targets returns the nodes that the given traversal might refer to.
+func (g *Graph) targets(traversal hcl.Traversal) []*Node {
+	addr := Addr{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		if node := g.byKey[addr.key()]; node != nil {
+			return []*Node{node}
+		}
+		name, ok := stepName(step)
+		if !ok {
+			// We can't tell statically which of the nodes beneath the
+			// current address are selected, so we must assume all of them.
+			break
+		}
+		next := addr.child(name)
+		if g.byKey[next.key()] == nil && g.children[next.key()] == nil {
+			// This traversal will fail during evaluation, because there is
+			// nothing at this address.
+			return nil
+		}
+		addr = next
+	}
+	return g.under(addr)
+}
+
+// under returns the nodes at or beneath the given address, in source order.
+func (g *Graph) under(addr Addr) []*Node {
+	if node := g.byKey[addr.key()]; node != nil {
+		return []*Node{node}
+	}
+	var ret []*Node
+	for _, name := range g.children[addr.key()] {
+		ret = append(ret, g.under(addr.child(name))...)
+	}
+	return ret
+}
+
+// topoSort determines the evaluation order of the nodes, returning diagnostics
+// for any cycles.
+//
+// This uses Tarjan's strongly connected components algorithm, which
+// conveniently produces the components with each after all of the
+// components it depends on.
+func (g *Graph) topoSort() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	index := make(map[*Node]int, len(g.nodes))
+	lowlink := make(map[*Node]int, len(g.nodes))
+	onStack := make(map[*Node]bool, len(g.nodes))
+	var stack []*Node
+	next := 0
+
+	var visit func(node *Node)
+	visit = func(node *Node) {
+		index[node] = next
+		lowlink[node] = next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, ref := range node.deps {
+			target := ref.target
+			if _, visited := index[target]; !visited {
+				visit(target)
+				if lowlink[target] < lowlink[node] {
+					lowlink[node] = lowlink[target]
+				}
+			} else if onStack[target] && index[target] < lowlink[node] {
+				lowlink[node] = index[target]
+			}
+		}
+
+		if lowlink[node] != index[node] {
+			return
+		}
+
+		var component []*Node
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[member] = false
+			component = append(component, member)
+			if member == node {
+				break
+			}
+		}
+
+		if len(component) == 1 && !node.dependsOn(node) {
+			g.order = append(g.order, node)
+			return
+		}
+		diags = append(diags, g.cycleDiagnostic(component))
+	}
+
+	for _, node := range g.nodes {
+		if _, visited := index[node]; !visited {
+			visit(node)
+		}
+	}
+
+	return diags
+}
+
+// cycleDiagnostic returns a diagnostic describing a cycle through the given
+// nodes, which must form a strongly connected component of the graph.
+func (g *Graph) cycleDiagnostic(component []*Node) *hcl.Diagnostic {
+	members := make(map[*Node]bool, len(component))
+	for _, node := range component {
+		members[node] = true
+	}
+
+	// We'll describe the cycle starting from the node that appears first
+	// in the source, so that the result is predictable.
+	start := g.first(component)
+
+	// Find a path from the start back to itself using only the references
+	// within the component, of which there must be at least one.
+	visited := make(map[*Node]bool, len(component))
+	var path []reference
+	var find func(node *Node) bool
+	find = func(node *Node) bool {
+		for _, ref := range node.deps {
+			if !members[ref.target] {
+				continue
+			}
+			if ref.target == start {
+				path = append(path, ref)
+				return true
+			}
+			if visited[ref.target] {
+				continue
+			}
+			visited[ref.target] = true
+			path = append(path, ref)
+			if find(ref.target) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+	find(start)
+
+	names := make([]string, 0, len(path)+1)
+	names = append(names, start.Addr.String())
+	for _, ref := range path {
+		names = append(names, ref.target.Addr.String())
+	}
+
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Cycle in references",
+		Detail: fmt.Sprintf(
+			"The value of %s depends on itself, through the following references: %s.",
+			start.Addr, strings.Join(names, " -> "),
+		),
+		Subject: path[0].srcRange.Ptr(),
+		Context: start.Attr.Range.Ptr(),
+	}
+}
+
+// first returns the node among the given nodes that appears first in the
+// graph's source order.
+func (g *Graph) first(nodes []*Node) *Node {
+	pos := make(map[*Node]int, len(g.nodes))
+	for i, node := range g.nodes {
+		pos[node] = i
+	}
+	ret := nodes[0]
+	for _, node := range nodes[1:] {
+		if pos[node] < pos[ret] {
+			ret = node
+		}
+	}
+	return ret
+}
+
+func (n *Node) dependsOn(other *Node) bool {
+	for _, ref := range n.deps {
+		if ref.target == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Nodes returns all of the nodes in the graph, in source order.
+func (g *Graph) Nodes() []*Node {
+	return g.nodes
+}
+
+// Node returns the node with the given address, or nil if there is no such
+// node.
+func (g *Graph) Node(addr Addr) *Node {
+	return g.byKey[addr.key()]
+}
+
+// Order returns the nodes in the order they are evaluated, with each node
+// after all of the nodes it depends on. Nodes that are part of a cycle are
+// not included.
+func (g *Graph) Order() []*Node {
+	return g.order
+}
+
+// Dependencies returns the nodes that are referred to by the nodes at or
+// beneath the given address, in source order.
+func (g *Graph) Dependencies(addr Addr) []*Node {
+	seen := make(map[*Node]bool)
+	for _, node := range g.under(addr) {
+		for _, ref := range node.deps {
+			seen[ref.target] = true
+		}
+	}
+	return g.filter(seen)
+}
+
+// Dependents returns the nodes that refer directly to the nodes at or
+// beneath the given address, in source order.
+func (g *Graph) Dependents(addr Addr) []*Node {
+	seen := make(map[*Node]bool)
+	for _, node := range g.under(addr) {
+		for _, dependent := range g.dependents[node] {
+			seen[dependent] = true
+		}
+	}
+	return g.filter(seen)
+}
+
+// AllDependents returns the nodes that refer either directly or indirectly
+// to the nodes at or beneath the given address, in source order. These are
+// the nodes whose values may change if the values at the address change.
+func (g *Graph) AllDependents(addr Addr) []*Node {
+	seen := make(map[*Node]bool)
+	queue := g.under(addr)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.dependents[node] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return g.filter(seen)
+}
+
+// filter returns the nodes in the given set, in source order.
+func (g *Graph) filter(set map[*Node]bool) []*Node {
+	var ret []*Node
+	for _, node := range g.nodes {
+		if set[node] {
+			ret = append(ret, node)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,250 @@
+package quoeval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+)
+
+var testSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "fee"},
+		{Name: "total"},
+		{Name: "pick"},
+		{Name: "whole"},
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "c"},
+		{Name: "d"},
+		{Name: "e"},
+		{Name: "pair"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "locals"},
+		{Type: "strategy", LabelNames: []string{"name"}},
+		{Type: "pair", LabelNames: []string{"name"}},
+	},
+}
+
+const testConfig = `
+fee   = 0.0025
+total = strategy.a.spread + strategy.b.spread + fee
+
+strategy "a" {
+  spread = locals.base * 2
+}
+
+strategy "b" {
+  spread = locals.base + fee
+}
+
+locals {
+  base = price / 100
+}
+
+pick  = strategy[locals.name].spread
+whole = strategy.a
+
+locals {
+  name = "a"
+}
+`
+
+func parseBody(t *testing.T, src string) hcl.Body {
+	t.Helper()
+	f, diags := quosyntax.ParseConfig([]byte(src), "test.quo", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("unexpected parse diagnostics: %s", diags.Error())
+	}
+	return f.Body
+}
+
+func addrs(nodes []*Node) string {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Addr.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestBuildGraph(t *testing.T) {
+	g, diags := BuildGraph(parseBody(t, testConfig), testSchema)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	if got, want := addrs(g.Nodes()), "fee, total, strategy.a.spread, strategy.b.spread, locals.base, pick, whole, locals.name"; got != want {
+		t.Errorf("wrong nodes\ngot:  %s\nwant: %s", got, want)
+	}
+	if got, want := addrs(g.Order()), "fee, locals.base, strategy.a.spread, strategy.b.spread, total, locals.name, pick, whole"; got != want {
+		t.Errorf("wrong order\ngot:  %s\nwant: %s", got, want)
+	}
+
+	tests := []struct {
+		addr          string
+		deps          string
+		dependents    string
+		allDependents string
+	}{
+		{
+			"total",
+			"fee, strategy.a.spread, strategy.b.spread",
+			"",
+			"",
+		},
+		{
+			"locals.base",
+			"",
+			"strategy.a.spread, strategy.b.spread",
+			"total, strategy.a.spread, strategy.b.spread, pick, whole",
+		},
+		{
+			"strategy.a",
+			"locals.base",
+			"total, pick, whole",
+			"total, pick, whole",
+		},
+		{
+			`strategy["b"]`,
+			"fee, locals.base",
+			"total, pick",
+			"total, pick",
+		},
+		{
+			"pick",
+			"strategy.a.spread, strategy.b.spread, locals.name",
+			"",
+			"",
+		},
+		{
+			"fee",
+			"",
+			"total, strategy.b.spread",
+			"total, strategy.b.spread, pick",
+		},
+		{
+			"nope",
+			"",
+			"",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.addr, func(t *testing.T) {
+			addr, diags := ParseAddr(test.addr)
+			if len(diags) != 0 {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			if got := addrs(g.Dependencies(addr)); got != test.deps {
+				t.Errorf("wrong dependencies\ngot:  %s\nwant: %s", got, test.deps)
+			}
+			if got := addrs(g.Dependents(addr)); got != test.dependents {
+				t.Errorf("wrong dependents\ngot:  %s\nwant: %s", got, test.dependents)
+			}
+			if got := addrs(g.AllDependents(addr)); got != test.allDependents {
+				t.Errorf("wrong transitive dependents\ngot:  %s\nwant: %s", got, test.allDependents)
+			}
+		})
+	}
+}
+
+func TestBuildGraphCycles(t *testing.T) {
+	src := `
+e = a
+a = b + 1
+b = c
+c = a * b
+d = d
+`
+	g, diags := BuildGraph(parseBody(t, src), testSchema)
+
+	var got []string
+	for _, diag := range diags {
+		got = append(got, diag.Detail)
+	}
+	want := []string{
+		"The value of a depends on itself, through the following references: a -> b -> c -> a.",
+		"The value of d depends on itself, through the following references: d -> d.",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrong diagnostics\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(diags) == 2 {
+		if got, want := diags[0].Subject.Start.Line, 3; got != want {
+			t.Errorf("wrong subject line %d; want %d", got, want)
+		}
+		if got, want := diags[1].Subject.Start.Line, 6; got != want {
+			t.Errorf("wrong subject line %d; want %d", got, want)
+		}
+	}
+
+	if got, want := addrs(g.Order()), "e"; got != want {
+		t.Errorf("wrong order\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestBuildGraphDuplicates(t *testing.T) {
+	src := `
+locals {
+  base = 1
+}
+
+locals {
+  base = 2
+}
+
+pair = 1
+
+pair "a" {
+  b = 1
+}
+`
+	_, diags := BuildGraph(parseBody(t, src), testSchema)
+
+	var got []string
+	for _, diag := range diags {
+		got = append(got, diag.Summary+": "+diag.Detail)
+	}
+	want := []string{
+		"Duplicate definition: locals.base was already defined at test.quo:3,3-7.",
+		"Conflicting definitions: pair.a.b can't be defined here, because it conflicts with pair defined at test.quo:10,1-5.",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrong diagnostics\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Addr
+		str   string
+	}{
+		{"fee", Addr{"fee"}, "fee"},
+		{"strategy.a.spread", Addr{"strategy", "a", "spread"}, "strategy.a.spread"},
+		{`strategy["a b"].spread`, Addr{"strategy", "a b", "spread"}, `strategy["a b"].spread`},
+		{`strategy["a"]`, Addr{"strategy", "a"}, `strategy.a`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, diags := ParseAddr(test.input)
+			if len(diags) != 0 {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.want)
+			}
+			if got := got.String(); got != test.str {
+				t.Errorf("wrong string %q; want %q", got, test.str)
+			}
+		})
+	}
+
+	if _, diags := ParseAddr("strategy[0]"); !diags.HasErrors() {
+		t.Errorf("no error for numeric index")
+	}
+}
@@ -0,0 +1,151 @@
+package quoeval
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// EvaluateParallel is a helper that builds the graph for the given body and
+// then evaluates it in the given context using Graph.EvaluateParallel.
+func EvaluateParallel(ctx context.Context, body hcl.Body, schema *hcl.BodySchema, evalCtx *hcl.EvalContext, workers int) (*hcl.EvalContext, hcl.Diagnostics) {
+	g, diags := BuildGraph(body, schema)
+	ret, evalDiags := g.EvaluateParallel(ctx, evalCtx, workers)
+	diags = append(diags, evalDiags...)
+	return ret, diags
+}
+
+// EvaluateParallel is like Evaluate, except that attributes that don't
+// depend on one another are evaluated concurrently, using at most the given
+// number of goroutines. If workers is zero or negative, runtime.GOMAXPROCS
+// goroutines are used.
+//
+// The result and diagnostics are the same as would be produced by Evaluate,
+// as long as the functions in the given EvalContext are safe to call
+// concurrently and produce the same results regardless of the order they
+// are called in.
+//
+// If the given context is cancelled before evaluation is complete, no more
+// attributes are evaluated. The attributes that were not evaluated have the
+// value cty.DynamicVal, and an error diagnostic reports the cancellation.
+// The context isn't passed to the evaluations of the attributes already in
+// progress, which run to completion; to stop those too, pass an EvalContext
+// returned by quosyntax.WithLimits with the same context.
+func (g *Graph) EvaluateParallel(ctx context.Context, evalCtx *hcl.EvalContext, workers int) (*hcl.EvalContext, hcl.Diagnostics) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type result struct {
+		node  *Node
+		val   cty.Value
+		diags hcl.Diagnostics
+	}
+
+	// pending counts the dependencies of each node that have not been
+	// evaluated yet. Dependencies that are part of a cycle will never be
+	// evaluated, so they are not counted.
+	evaluated := make(map[*Node]bool, len(g.order))
+	for _, node := range g.order {
+		evaluated[node] = true
+	}
+	pending := make(map[*Node]int, len(g.order))
+	var ready []*Node
+	for _, node := range g.order {
+		seen := make(map[*Node]bool)
+		for _, ref := range node.deps {
+			if evaluated[ref.target] && !seen[ref.target] {
+				seen[ref.target] = true
+				pending[node]++
+			}
+		}
+		if pending[node] == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	var mu sync.RWMutex // guards vals
+	vals := make(map[*Node]cty.Value, len(g.nodes))
+	nodeDiags := make(map[*Node]hcl.Diagnostics)
+
+	work := make(chan *Node)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range work {
+				mu.RLock()
+				scope := g.scope(evalCtx, node.roots, vals)
+				mu.RUnlock()
+
+				val, diags := node.Attr.Expr.Value(scope)
+				results <- result{
+					node:  node,
+					val:   val,
+					diags: diags,
+				}
+			}
+		}()
+	}
+
+	var cancelErr error
+	done := ctx.Done()
+	inFlight := 0
+	for {
+		// Cancellation is only reported if it stops an attribute from
+		// being evaluated, and not if it comes after the last one starts.
+		if cancelErr == nil && len(ready) > 0 {
+			cancelErr = ctx.Err()
+		}
+
+		var send chan<- *Node
+		var next *Node
+		if len(ready) > 0 && cancelErr == nil {
+			send, next = work, ready[0]
+		}
+		if send == nil && inFlight == 0 {
+			break
+		}
+
+		select {
+		case send <- next:
+			ready = ready[1:]
+			inFlight++
+		case res := <-results:
+			inFlight--
+			mu.Lock()
+			vals[res.node] = res.val
+			mu.Unlock()
+			nodeDiags[res.node] = res.diags
+			for _, dependent := range g.dependents[res.node] {
+				if !evaluated[dependent] {
+					continue
+				}
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					ready = append(ready, dependent)
+				}
+			}
+		case <-done:
+			done = nil
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	diags := g.mergeDiags(nodeDiags)
+	if cancelErr != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Evaluation cancelled",
+			Detail:   fmt.Sprintf("Evaluation was stopped before it was complete: %s.", cancelErr),
+		})
+	}
+	return g.scope(evalCtx, g.roots(), vals), diags
+}
@@ -0,0 +1,142 @@
+package quoeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestEvaluateParallel(t *testing.T) {
+	// We generate a config with several independent chains of attributes,
+	// some of which produce errors, so that there is plenty of opportunity
+	// for the order of evaluation to vary.
+	var buf strings.Builder
+	schema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "chain", LabelNames: []string{"name"}},
+		},
+	}
+	for c := 0; c < 20; c++ {
+		fmt.Fprintf(&buf, "chain \"c%d\" {\n", c)
+		fmt.Fprintf(&buf, "  v0 = price * %d\n", c)
+		for i := 1; i < 10; i++ {
+			switch {
+			case c%5 == 0 && i == 5:
+				fmt.Fprintf(&buf, "  v%d = chain.c%d.v%d + \"x\"\n", i, c, i-1)
+			case c > 0 && i == 9:
+				fmt.Fprintf(&buf, "  v%d = chain.c%d.v%d + chain.c%d.v8\n", i, c, i-1, c-1)
+			default:
+				fmt.Fprintf(&buf, "  v%d = chain.c%d.v%d + %d\n", i, c, i-1, i)
+			}
+		}
+		buf.WriteString("}\n")
+	}
+	body := parseBody(t, buf.String())
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"price": quoty.NumberIntVal(3),
+		},
+	}
+
+	g, diags := BuildGraph(body, schema)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	want, wantDiags := g.Evaluate(ctx)
+	if len(wantDiags) != 4 {
+		t.Fatalf("wrong number of diagnostics %d from Evaluate; want 4", len(wantDiags))
+	}
+
+	for _, workers := range []int{0, 1, 2, 16} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			got, gotDiags := g.EvaluateParallel(context.Background(), ctx, workers)
+
+			if !got.Variables["chain"].RawEquals(want.Variables["chain"]) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got.Variables["chain"], want.Variables["chain"])
+			}
+			if len(gotDiags) != len(wantDiags) {
+				t.Fatalf("wrong number of diagnostics %d; want %d", len(gotDiags), len(wantDiags))
+			}
+			for i := range gotDiags {
+				if got, want := gotDiags[i].Error(), wantDiags[i].Error(); got != want {
+					t.Errorf("wrong diagnostic %d\ngot:  %s\nwant: %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateParallelCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ctx := &hcl.EvalContext{
+		Functions: map[string]function.Function{
+			"wait": function.New(&function.Spec{
+				Type: function.StaticReturnType(quoty.Number),
+				Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+					close(started)
+					<-release
+					return quoty.NumberIntVal(1), nil
+				},
+			}),
+		},
+	}
+	src := `
+a = wait()
+b = a + 1
+`
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+		close(release)
+	}()
+	got, diags := EvaluateParallel(cancelCtx, parseBody(t, src), testSchema, ctx, 2)
+
+	if len(diags) != 1 || diags[0].Summary != "Evaluation cancelled" {
+		t.Fatalf("wrong diagnostics: %s", diags.Error())
+	}
+	if val, want := got.Variables["a"], quoty.NumberIntVal(1); !val.RawEquals(want) {
+		t.Errorf("wrong value for a %#v; want %#v", val, want)
+	}
+	if val := got.Variables["b"]; !val.RawEquals(cty.DynamicVal) {
+		t.Errorf("wrong value for b %#v; want cty.DynamicVal", val)
+	}
+}
+
+func TestEvaluateParallelCancelAfterLast(t *testing.T) {
+	// Cancelling the context once the last attribute has started doesn't
+	// stop anything from being evaluated, so it isn't reported.
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	ctx := &hcl.EvalContext{
+		Functions: map[string]function.Function{
+			"finish": function.New(&function.Spec{
+				Type: function.StaticReturnType(quoty.Number),
+				Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+					cancel()
+					return quoty.NumberIntVal(1), nil
+				},
+			}),
+		},
+	}
+	src := `
+a = 1
+b = a + finish()
+`
+
+	got, diags := EvaluateParallel(cancelCtx, parseBody(t, src), testSchema, ctx, 2)
+
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	if val, want := got.Variables["b"], quoty.NumberIntVal(2); !val.RawEquals(want) {
+		t.Errorf("wrong value for b %#v; want %#v", val, want)
+	}
+}
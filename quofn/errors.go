@@ -0,0 +1,40 @@
+package quofn
+
+import (
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// NewArgError returns an error about the argument with the given index,
+// like function.NewArgError, for functions to return from their Impl and
+// Type functions.
+//
+// The error returned by function.NewArgError doesn't expose the error it
+// wraps, so the result also wraps the given error in a way that errors.As
+// and errors.Is can see. That lets the quosyntax package report the path of
+// a cty.PathError within the argument. As far as errors.As is concerned,
+// the result is also a function.ArgError.
+func NewArgError(i int, err error) error {
+	return argError{
+		ArgError: function.NewArgError(i, err).(function.ArgError),
+		err:      err,
+	}
+}
+
+// argError is a function.ArgError that exposes the error it wraps.
+type argError struct {
+	function.ArgError
+	err error
+}
+
+func (e argError) Unwrap() error {
+	return e.err
+}
+
+// As makes errors.As treat the error as a function.ArgError.
+func (e argError) As(target interface{}) bool {
+	if target, ok := target.(*function.ArgError); ok {
+		*target = e.ArgError
+		return true
+	}
+	return false
+}
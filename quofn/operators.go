@@ -100,6 +100,90 @@ var DivideFunc = function.New(&function.Spec{
 	},
 })
 
+var GreaterThanFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "a",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+		{
+			Name:             "b",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, retType cty.Type) (ret cty.Value, err error) {
+		abr := args[0].EncapsulatedValue().(*big.Rat)
+		bbr := args[1].EncapsulatedValue().(*big.Rat)
+		return cty.BoolVal(abr.Cmp(bbr) > 0), nil
+	},
+})
+
+var GreaterThanOrEqualToFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "a",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+		{
+			Name:             "b",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, retType cty.Type) (ret cty.Value, err error) {
+		abr := args[0].EncapsulatedValue().(*big.Rat)
+		bbr := args[1].EncapsulatedValue().(*big.Rat)
+		return cty.BoolVal(abr.Cmp(bbr) >= 0), nil
+	},
+})
+
+var LessThanFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "a",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+		{
+			Name:             "b",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, retType cty.Type) (ret cty.Value, err error) {
+		abr := args[0].EncapsulatedValue().(*big.Rat)
+		bbr := args[1].EncapsulatedValue().(*big.Rat)
+		return cty.BoolVal(abr.Cmp(bbr) < 0), nil
+	},
+})
+
+var LessThanOrEqualToFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "a",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+		{
+			Name:             "b",
+			Type:             quoty.Number,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, retType cty.Type) (ret cty.Value, err error) {
+		abr := args[0].EncapsulatedValue().(*big.Rat)
+		bbr := args[1].EncapsulatedValue().(*big.Rat)
+		return cty.BoolVal(abr.Cmp(bbr) <= 0), nil
+	},
+})
+
 // Add returns the sum of the two given numbers.
 func Add(a cty.Value, b cty.Value) (cty.Value, error) {
 	return AddFunc.Call([]cty.Value{a, b})
@@ -119,3 +203,23 @@ func Multiply(a cty.Value, b cty.Value) (cty.Value, error) {
 func Divide(a cty.Value, b cty.Value) (cty.Value, error) {
 	return DivideFunc.Call([]cty.Value{a, b})
 }
+
+// GreaterThan returns true if a is greater than b.
+func GreaterThan(a cty.Value, b cty.Value) (cty.Value, error) {
+	return GreaterThanFunc.Call([]cty.Value{a, b})
+}
+
+// GreaterThanOrEqualTo returns true if a is greater than or equal to b.
+func GreaterThanOrEqualTo(a cty.Value, b cty.Value) (cty.Value, error) {
+	return GreaterThanOrEqualToFunc.Call([]cty.Value{a, b})
+}
+
+// LessThan returns true if a is less than b.
+func LessThan(a cty.Value, b cty.Value) (cty.Value, error) {
+	return LessThanFunc.Call([]cty.Value{a, b})
+}
+
+// LessThanOrEqualTo returns true if a is less than or equal to b.
+func LessThanOrEqualTo(a cty.Value, b cty.Value) (cty.Value, error) {
+	return LessThanOrEqualToFunc.Call([]cty.Value{a, b})
+}
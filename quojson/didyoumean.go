@@ -0,0 +1,12 @@
+package quojson
+
+import "github.com/quomproject/quolang/quoty"
+
+var keywords = []string{"false", "true", "null"}
+
+// keywordSuggestion tries to find a valid JSON keyword that is close to the
+// given string and returns it if found. If no keyword is close enough, returns
+// the empty string.
+func keywordSuggestion(given string) string {
+	return quoty.NameSuggestion(given, keywords)
+}
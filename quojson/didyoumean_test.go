@@ -1,10 +1,8 @@
-package quosyntax
+package quojson
 
 import "testing"
 
-func TestNameSuggestion(t *testing.T) {
-	var keywords = []string{"false", "true", "null"}
-
+func TestKeywordSuggestion(t *testing.T) {
 	tests := []struct {
 		Input, Want string
 	}{
@@ -39,7 +37,7 @@ func TestNameSuggestion(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.Input, func(t *testing.T) {
-			got := nameSuggestion(test.Input, keywords)
+			got := keywordSuggestion(test.Input)
 			if got != test.Want {
 				t.Errorf(
 					"wrong result\ninput: %q\ngot:   %q\nwant:  %q",
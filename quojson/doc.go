@@ -0,0 +1,14 @@
+// Package quojson is the JSON parser for Quo. It parses JSON files and returns
+// implementations of the core HCL structural interfaces in terms of the
+// JSON data inside.
+//
+// This is not a generic JSON parser. Instead, it deals with the mapping from
+// the JSON information model to the HCL information model, using a number
+// of hard-coded structural conventions.
+//
+// Unlike HCL's own JSON syntax, numeric literals are decoded directly as
+// quoty.Number values without passing through a base-2 floating point
+// representation, and strings are interpreted as templates using the
+// quosyntax template parser, so JSON configuration has the same exact
+// decimal semantics as configuration written in the native syntax.
+package quojson
@@ -0,0 +1,136 @@
+package quojson
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParse_nonObject(t *testing.T) {
+	src := `true`
+	file, diags := Parse([]byte(src), "")
+	if len(diags) != 1 {
+		t.Errorf("got %d diagnostics; want 1", len(diags))
+	}
+	if file == nil {
+		t.Errorf("got nil File; want actual file")
+	}
+	if file.Body == nil {
+		t.Fatalf("got nil Body; want actual body")
+	}
+	if file.Body.(*body).val == nil {
+		t.Errorf("got nil Body object; want placeholder object")
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	src := `{"greeting": "hello ${\"world\"}"}`
+	file, diags := Parse([]byte(src), "")
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on parse; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+	if file == nil {
+		t.Errorf("got nil File; want actual file")
+	}
+	if file.Body == nil {
+		t.Fatalf("got nil Body; want actual body")
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on decode; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+
+	val, diags := attrs["greeting"].Expr.Value(&hcl.EvalContext{})
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on eval; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+
+	if !val.RawEquals(cty.StringVal("hello world")) {
+		t.Errorf("wrong result %#v; want %#v", val, cty.StringVal("hello world"))
+	}
+}
+
+func TestParseTemplateUnwrap(t *testing.T) {
+	src := `{"greeting": "${true}"}`
+	file, diags := Parse([]byte(src), "")
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on parse; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+	if file == nil {
+		t.Errorf("got nil File; want actual file")
+	}
+	if file.Body == nil {
+		t.Fatalf("got nil Body; want actual body")
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on decode; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+
+	val, diags := attrs["greeting"].Expr.Value(&hcl.EvalContext{})
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on eval; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+
+	if !val.RawEquals(cty.True) {
+		t.Errorf("wrong result %#v; want %#v", val, cty.True)
+	}
+}
+
+func TestParseNumberExact(t *testing.T) {
+	src := `{"price": 0.1, "big": 12345678901234567890.123456789, "exp": 1.5e-3}`
+	file, diags := Parse([]byte(src), "")
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on parse; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if len(diags) != 0 {
+		t.Errorf("got %d diagnostics on decode; want 0", len(diags))
+		for _, diag := range diags {
+			t.Logf("- %s", diag.Error())
+		}
+	}
+
+	tests := map[string]cty.Value{
+		"price": quoty.MustParseNumberVal("0.1"),
+		"big":   quoty.MustParseNumberVal("12345678901234567890.123456789"),
+		"exp":   quoty.MustParseNumberVal("0.0015"),
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, diags := attrs[name].Expr.Value(nil)
+			if len(diags) != 0 {
+				t.Errorf("got %d diagnostics on eval; want 0", len(diags))
+				for _, diag := range diags {
+					t.Logf("- %s", diag.Error())
+				}
+			}
+			if !got.RawEquals(want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+// Package quolint checks Quo configuration for common mistakes that are
+// valid syntax, such as unused 'for' symbols and deeply nested conditionals.
+//
+// Each check is a Rule, which provides a quosyntax.Walker that returns a
+// warning for each problem it finds in a file. Lint runs a set of rules
+// over a file, and DefaultRules returns the built-in rules.
+//
+// A rule can be disabled for a whole file with a comment anywhere in it
+// that names the rule, or disabled entirely by omitting the names:
+//
+//	# quolint:disable unused-for-key, long-decimal
+package quolint
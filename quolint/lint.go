@@ -0,0 +1,121 @@
+package quolint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+)
+
+// Rule is a check for a particular kind of mistake.
+type Rule interface {
+	// Name identifies the rule in suppression comments, such as
+	// "unused-for-key".
+	Name() string
+
+	// Walker returns a walker that checks the given file when walked over
+	// its body, returning a warning from Enter or Exit for each problem it
+	// finds. A new walker is requested for each file.
+	Walker(file *quosyntax.File) quosyntax.Walker
+}
+
+// DefaultRules returns the built-in rules, with their default settings.
+func DefaultRules() []Rule {
+	return []Rule{
+		UnusedForKey{},
+		ShadowedForValue{},
+		NullFallback{},
+		LongDecimal{MinDigits: 4},
+		NestedConditional{MaxDepth: 3},
+	}
+}
+
+// Lint checks the given file with each of the given rules, returning the
+// warnings they produce in the order of the rules. The detail of each warning
+// ends with the suppression comment that would disable its rule.
+//
+// Rules that are disabled by a suppression comment in the file are not run.
+// A suppression comment that names a rule that isn't among the given rules
+// produces a warning of its own, since it is likely to be misspelled.
+func Lint(file *quosyntax.File, rules []Rule) hcl.Diagnostics {
+	disabled, diags := suppressions(file, rules)
+	if file.Body == nil || disabled[""] {
+		return diags
+	}
+
+	for _, rule := range rules {
+		name := rule.Name()
+		if disabled[name] {
+			continue
+		}
+		for _, diag := range quosyntax.Walk(file.Body, rule.Walker(file)) {
+			diag.Severity = hcl.DiagWarning
+			diag.Detail += fmt.Sprintf(" To disable this check for the file, add the comment \"# %s %s\".", suppressionPrefix, name)
+			diags = append(diags, diag)
+		}
+	}
+	return diags
+}
+
+const suppressionPrefix = "quolint:disable"
+
+// suppressions returns the names of the rules disabled by the comments in
+// the given file, with the empty name meaning that all rules are disabled,
+// along with warnings for any names that aren't those of the given rules.
+func suppressions(file *quosyntax.File, rules []Rule) (map[string]bool, hcl.Diagnostics) {
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.Name()] = true
+	}
+
+	var diags hcl.Diagnostics
+	disabled := make(map[string]bool)
+	for _, tok := range file.Comments {
+		text := commentText(tok.Bytes)
+		if !strings.HasPrefix(text, suppressionPrefix) {
+			continue
+		}
+		names := strings.TrimPrefix(text, suppressionPrefix)
+		if names != "" && names[0] != ' ' && names[0] != '\t' {
+			// Something else that begins with the same text, which isn't
+			// a suppression comment.
+			continue
+		}
+		names = strings.TrimSpace(names)
+		if names == "" {
+			disabled[""] = true
+			continue
+		}
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			if !known[name] {
+				rng := tok.Range
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Unknown lint rule",
+					Detail:   fmt.Sprintf("There is no lint rule named %q to disable.", name),
+					Subject:  &rng,
+				})
+				continue
+			}
+			disabled[name] = true
+		}
+	}
+	return disabled, diags
+}
+
+// commentText returns the text of the given comment without its comment
+// markers and surrounding whitespace.
+func commentText(src []byte) string {
+	text := strings.TrimSpace(string(src))
+	switch {
+	case strings.HasPrefix(text, "#"):
+		text = text[1:]
+	case strings.HasPrefix(text, "//"):
+		text = text[2:]
+	case strings.HasPrefix(text, "/*"):
+		text = strings.TrimSuffix(text[2:], "*/")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,79 @@
+package quolint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+)
+
+func TestLint(t *testing.T) {
+	src := `spread = var.spread == null ? 0.0025 : var.spread
+legs = {for k, v in var.legs: v.side => v}
+`
+	tests := []struct {
+		comment string
+		want    []string
+	}{
+		{
+			``,
+			[]string{
+				"Unused 'for' key symbol",
+				"Null fallback written as a conditional",
+				"Long decimal literal",
+			},
+		},
+		{
+			"# quolint:disable long-decimal, null-fallback\n",
+			[]string{
+				"Unused 'for' key symbol",
+			},
+		},
+		{
+			"/* quolint:disable unused-for-key */\n",
+			[]string{
+				"Null fallback written as a conditional",
+				"Long decimal literal",
+			},
+		},
+		{
+			"// quolint:disable\n",
+			nil,
+		},
+		{
+			"# quolint:disable unused-key\n",
+			[]string{
+				"Unknown lint rule",
+				"Unused 'for' key symbol",
+				"Null fallback written as a conditional",
+				"Long decimal literal",
+			},
+		},
+		{
+			"# quolint:disabled long-decimal\n",
+			[]string{
+				"Unused 'for' key symbol",
+				"Null fallback written as a conditional",
+				"Long decimal literal",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.comment, func(t *testing.T) {
+			file, diags := quosyntax.ParseFile([]byte(src+test.comment), "test.quo", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			var got []string
+			for _, diag := range Lint(file, DefaultRules()) {
+				got = append(got, diag.Summary)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("wrong warnings\ngot:  %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package quolint
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+)
+
+// UnusedForKey is a rule that reports 'for' expressions and directives that
+// declare a key symbol but never refer to it.
+//
+// A key symbol named "_" is assumed to be unused deliberately.
+type UnusedForKey struct{}
+
+func (UnusedForKey) Name() string {
+	return "unused-for-key"
+}
+
+func (UnusedForKey) Walker(file *quosyntax.File) quosyntax.Walker {
+	return enterFunc(func(node quosyntax.Node) hcl.Diagnostics {
+		e, ok := node.(*quosyntax.ForExpr)
+		if !ok || e.KeyVar == "" || e.KeyVar == "_" {
+			return nil
+		}
+		if len(quosyntax.FindSymbolReferences(e, e.KeyVar)) != 0 {
+			return nil
+		}
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "Unused 'for' key symbol",
+				Detail:   fmt.Sprintf("The key symbol %q is never used. Declare only the value symbol, as in \"for %s in ...\".", e.KeyVar, e.ValVar),
+				Subject:  &e.KeyVarRange,
+			},
+		}
+	})
+}
+
+// ShadowedForValue is a rule that reports 'for' expressions and directives
+// whose value symbol has the same name as a symbol of an enclosing 'for'
+// expression, or as a variable used in its own collection expression, and
+// so hides it.
+type ShadowedForValue struct{}
+
+func (ShadowedForValue) Name() string {
+	return "shadowed-for-value"
+}
+
+func (ShadowedForValue) Walker(file *quosyntax.File) quosyntax.Walker {
+	return &shadowWalker{}
+}
+
+type shadowWalker struct {
+	// scopes are the symbols of the enclosing 'for' expressions.
+	scopes []map[string]struct{}
+}
+
+func (w *shadowWalker) Enter(node quosyntax.Node) hcl.Diagnostics {
+	switch node := node.(type) {
+	case quosyntax.ChildScope:
+		w.scopes = append(w.scopes, node.LocalNames)
+	case *quosyntax.ForExpr:
+		var hidden string
+		for _, scope := range w.scopes {
+			if _, exists := scope[node.ValVar]; exists {
+				hidden = "the symbol of an enclosing 'for' expression"
+			}
+		}
+		for _, traversal := range node.CollExpr.Variables() {
+			if traversal.RootName() == node.ValVar {
+				hidden = "the variable used in the collection expression"
+			}
+		}
+		if hidden == "" {
+			return nil
+		}
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "Shadowed variable",
+				Detail:   fmt.Sprintf("The value symbol %q hides %s of the same name. Choose a different name so that both can be referred to.", node.ValVar, hidden),
+				Subject:  &node.ValVarRange,
+			},
+		}
+	}
+	return nil
+}
+
+func (w *shadowWalker) Exit(node quosyntax.Node) hcl.Diagnostics {
+	if _, ok := node.(quosyntax.ChildScope); ok {
+		w.scopes = w.scopes[:len(w.scopes)-1]
+	}
+	return nil
+}
+
+// NullFallback is a rule that reports conditional expressions that compare
+// a variable with null only to substitute a fallback value for it, as in
+// x == null ? 0 : x.
+type NullFallback struct{}
+
+func (NullFallback) Name() string {
+	return "null-fallback"
+}
+
+func (NullFallback) Walker(file *quosyntax.File) quosyntax.Walker {
+	return enterFunc(func(node quosyntax.Node) hcl.Diagnostics {
+		e, ok := node.(*quosyntax.ConditionalExpr)
+		if !ok {
+			return nil
+		}
+		cond, ok := e.Condition.(*quosyntax.BinaryOpExpr)
+		if !ok {
+			return nil
+		}
+		// subject is the result when the compared expression isn't null,
+		// which must be that expression itself.
+		var subject, fallback quosyntax.Expression
+		switch cond.Op {
+		case quosyntax.OpEqual:
+			subject, fallback = e.FalseResult, e.TrueResult
+		case quosyntax.OpNotEqual:
+			subject, fallback = e.TrueResult, e.FalseResult
+		default:
+			return nil
+		}
+		compared := cond.LHS
+		if isNullLiteral(compared) {
+			compared = cond.RHS
+		} else if !isNullLiteral(cond.RHS) {
+			return nil
+		}
+		if _, ok := compared.(*quosyntax.ScopeTraversalExpr); !ok {
+			return nil
+		}
+		src := func(e quosyntax.Expression) string {
+			return string(file.RangeBytes(e.Range()))
+		}
+		if src(compared) != src(subject) {
+			return nil
+		}
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "Null fallback written as a conditional",
+				Detail: fmt.Sprintf(
+					"This compares %s with null only to use %s in its place when it is null, which repeats %s in two places that must be kept in sync. Consider giving it a default value where it is defined instead.",
+					src(compared), src(fallback), src(compared),
+				),
+				Subject: &e.SrcRange,
+			},
+		}
+	})
+}
+
+func isNullLiteral(e quosyntax.Expression) bool {
+	lit, ok := e.(*quosyntax.LiteralValueExpr)
+	return ok && lit.Val.IsNull()
+}
+
+// LongDecimal is a rule that reports number literals between zero and one
+// with at least the given number of significant decimal places, such as
+// 0.0025, which are easier to read as a number of basis points or a
+// percentage divided by 10000 or 100.
+type LongDecimal struct {
+	MinDigits int
+}
+
+func (LongDecimal) Name() string {
+	return "long-decimal"
+}
+
+var decimalLiteral = regexp.MustCompile(`^0\.([0-9]+)$`)
+
+func (r LongDecimal) Walker(file *quosyntax.File) quosyntax.Walker {
+	return enterFunc(func(node quosyntax.Node) hcl.Diagnostics {
+		e, ok := node.(*quosyntax.LiteralValueExpr)
+		if !ok {
+			return nil
+		}
+		src := string(file.RangeBytes(e.SrcRange))
+		match := decimalLiteral.FindStringSubmatch(src)
+		if match == nil {
+			return nil
+		}
+		digits := len(strings.TrimRight(match[1], "0"))
+		if digits == 0 || digits < r.MinDigits {
+			return nil
+		}
+
+		val, _ := new(big.Rat).SetString(src)
+		var suggestion, unit string
+		if bp := new(big.Rat).Mul(val, big.NewRat(10000, 1)); bp.IsInt() {
+			suggestion = bp.Num().String() + " / 10000"
+			unit = bp.Num().String() + " basis points"
+		} else {
+			pct := new(big.Rat).Mul(val, big.NewRat(100, 1)).FloatString(digits - 2)
+			suggestion = pct + " / 100"
+			unit = pct + " percent"
+		}
+		return hcl.Diagnostics{
+			{
+				Severity: hcl.DiagWarning,
+				Summary:  "Long decimal literal",
+				Detail:   fmt.Sprintf("The number %s is easier to read as %s, which is %s.", src, suggestion, unit),
+				Subject:  &e.SrcRange,
+			},
+		}
+	})
+}
+
+// NestedConditional is a rule that reports conditional expressions and
+// template if directives nested within more than the given number of
+// others, counting those in either their conditions or their results.
+type NestedConditional struct {
+	MaxDepth int
+}
+
+func (NestedConditional) Name() string {
+	return "nested-conditional"
+}
+
+func (r NestedConditional) Walker(file *quosyntax.File) quosyntax.Walker {
+	return &nestingWalker{max: r.MaxDepth}
+}
+
+type nestingWalker struct {
+	max   int
+	depth int
+}
+
+func (w *nestingWalker) Enter(node quosyntax.Node) hcl.Diagnostics {
+	e, ok := node.(*quosyntax.ConditionalExpr)
+	if !ok {
+		return nil
+	}
+	w.depth++
+	// We report only the outermost conditional that is too deep, since
+	// those within it are the same problem.
+	if w.depth != w.max+1 {
+		return nil
+	}
+	return hcl.Diagnostics{
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "Deeply nested conditional",
+			Detail:   fmt.Sprintf("This conditional is nested more than %d levels deep, which makes it hard to follow. Consider splitting it into separate attributes.", w.max),
+			Subject:  &e.SrcRange,
+		},
+	}
+}
+
+func (w *nestingWalker) Exit(node quosyntax.Node) hcl.Diagnostics {
+	if _, ok := node.(*quosyntax.ConditionalExpr); ok {
+		w.depth--
+	}
+	return nil
+}
+
+// enterFunc is a Walker that calls a function for each node on entry, for
+// rules that don't need to know the structure of the tree.
+type enterFunc func(node quosyntax.Node) hcl.Diagnostics
+
+func (f enterFunc) Enter(node quosyntax.Node) hcl.Diagnostics {
+	return f(node)
+}
+
+func (f enterFunc) Exit(node quosyntax.Node) hcl.Diagnostics {
+	return nil
+}
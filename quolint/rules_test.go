@@ -0,0 +1,165 @@
+package quolint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		input string
+		want  []string // the source code of the subject of each warning
+	}{
+		{
+			UnusedForKey{},
+			`a = {for k, v in x: v.name => v}`,
+			[]string{`k`},
+		},
+		{
+			UnusedForKey{},
+			`a = {for k, v in x: k => v if k != "b"}`,
+			nil,
+		},
+		{
+			UnusedForKey{},
+			`a = [for _, v in x: v]`,
+			nil,
+		},
+		{
+			UnusedForKey{},
+			`a = [for k, v in x: [for k in v: k]]`,
+			[]string{`k`},
+		},
+		{
+			UnusedForKey{},
+			`a = "%{ for i, v in x }${v}%{ endfor }"`,
+			[]string{`i`},
+		},
+		{
+			ShadowedForValue{},
+			`a = [for v in x: [for v in v.legs: v]]`,
+			[]string{`v`},
+		},
+		{
+			ShadowedForValue{},
+			`a = [for level in level.all: level.price]`,
+			[]string{`level`},
+		},
+		{
+			ShadowedForValue{},
+			`a = [for k, v in x: [for w in v: [for k in w: k]]]`,
+			[]string{`k`},
+		},
+		{
+			ShadowedForValue{},
+			`a = [[for v in x: v], [for v in y: v]]`,
+			nil,
+		},
+		{
+			NullFallback{},
+			`a = var.spread == null ? 0.01 : var.spread`,
+			[]string{`var.spread == null ? 0.01 : var.spread`},
+		},
+		{
+			NullFallback{},
+			`a = null != var.spread ? var.spread : 0.01`,
+			[]string{`null != var.spread ? var.spread : 0.01`},
+		},
+		{
+			NullFallback{},
+			`a = var.spread == null ? 0.01 : var.spread * 2`,
+			nil,
+		},
+		{
+			NullFallback{},
+			`a = var.spread == 0 ? 0.01 : var.spread`,
+			nil,
+		},
+		{
+			LongDecimal{MinDigits: 4},
+			`a = [0.0025, 0.00125, 0.25, 1.0025, 0.002500, 25e-4]`,
+			[]string{`0.0025`, `0.00125`, `0.002500`},
+		},
+		{
+			NestedConditional{MaxDepth: 3},
+			`a = x ? (y ? (z ? (w ? 1 : 2) : 3) : 4) : 5`,
+			[]string{`w ? 1 : 2`},
+		},
+		{
+			NestedConditional{MaxDepth: 3},
+			`a = x ? (y ? (z ? 1 : 2) : 3) : (v ? 4 : 5)`,
+			nil,
+		},
+		{
+			NestedConditional{MaxDepth: 1},
+			`a = "%{ if x }${y ? (z ? 1 : 2) : 3}%{ endif }"`,
+			[]string{`y ? (z ? 1 : 2) : 3`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule.Name()+" "+test.input, func(t *testing.T) {
+			file, diags := quosyntax.ParseFile([]byte(test.input), "test.quo", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			var got []string
+			for _, diag := range Lint(file, []Rule{test.rule}) {
+				if diag.Severity != hcl.DiagWarning {
+					t.Errorf("wrong severity for %q", diag.Summary)
+				}
+				got = append(got, string(diag.Subject.SliceBytes(file.Bytes)))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("wrong warnings\ngot:  %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLongDecimalDetail(t *testing.T) {
+	tests := map[string]string{
+		`0.0025`:  `The number 0.0025 is easier to read as 25 / 10000, which is 25 basis points.`,
+		`0.00125`: `The number 0.00125 is easier to read as 0.125 / 100, which is 0.125 percent.`,
+	}
+
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			file, diags := quosyntax.ParseFile([]byte("a = "+input), "test.quo", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			got := quosyntax.Walk(file.Body, LongDecimal{MinDigits: 4}.Walker(file))
+			if len(got) != 1 {
+				t.Fatalf("wrong number of warnings %d; want 1", len(got))
+			}
+			if got[0].Detail != want {
+				t.Errorf("wrong detail\ngot:  %s\nwant: %s", got[0].Detail, want)
+			}
+		})
+	}
+}
+
+func TestRulesStart(t *testing.T) {
+	// The file is a fragment of a larger document, so its positions don't
+	// start at the beginning.
+	src := "a = var.spread == null ? 0.01 : var.spread\nb = 0.0025\n"
+	file, diags := quosyntax.ParseFile([]byte(src), "test.quo", hcl.Pos{Line: 4, Column: 1, Byte: 60})
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	var got []string
+	for _, diag := range Lint(file, []Rule{NullFallback{}, LongDecimal{MinDigits: 4}}) {
+		got = append(got, string(file.RangeBytes(*diag.Subject)))
+	}
+	want := []string{`var.spread == null ? 0.01 : var.spread`, `0.0025`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong warnings\ngot:  %q\nwant: %q", got, want)
+	}
+}
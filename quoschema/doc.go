@@ -0,0 +1,41 @@
+// Package quoschema loads the schemas of Quo configuration bodies from
+// schema files that are themselves written in Quo, so that the blocks and
+// attributes a configuration may contain can be declared once and shared
+// between the programs that read it.
+//
+// A schema file declares the attributes and blocks of a body with
+// "attribute" and "block" blocks, where a "block" block declares the body of
+// the blocks of that type in the same way:
+//
+//	# A quoting strategy.
+//	block "strategy" {
+//	  labels = ["name"]
+//
+//	  attribute "spread" {
+//	    type        = number
+//	    default     = 0.01
+//	    description = "The spread around the mid price."
+//	  }
+//
+//	  block "leg" {
+//	    labels = ["side"]
+//
+//	    # The size of each order.
+//	    attribute "size" {
+//	      type     = number
+//	      required = true
+//	    }
+//	  }
+//	}
+//
+// The type of an attribute is a type constraint expression, as described in
+// the quotypeexpr package, and may be omitted to allow any type. An attribute
+// may have a default value, which is a constant expression of that type,
+// unless it is required. The description of an attribute or block is given
+// by its "description" attribute or, if there is none, by the doc comments of
+// its declaration.
+//
+// Schema.BodySchema produces the hcl.BodySchema to pass to the Content or
+// PartialContent method of a body, and Schema.SchemaFunc produces the schema
+// function used by quosyntax.Completions.
+package quoschema
@@ -0,0 +1,371 @@
+package quoschema
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quotypeexpr"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Schema describes the attributes and blocks of a body.
+type Schema struct {
+	// Attributes and Blocks are in the order they are declared.
+	Attributes []*Attribute
+	Blocks     []*Block
+}
+
+// Attribute describes an attribute of a body.
+type Attribute struct {
+	Name string
+
+	// Type is the type constraint of the attribute's value, which is
+	// cty.DynamicPseudoType if any value is allowed. Constraint is the same
+	// constraint along with any optional attributes of its object types.
+	Type       cty.Type
+	Constraint *quotypeexpr.Constraint
+
+	Required bool
+
+	// Default is the value of the attribute if it is omitted, which is
+	// cty.NilVal if it has no default.
+	Default cty.Value
+
+	Description string
+
+	// DeclRange is the range of the header of the declaration of the
+	// attribute in the schema file.
+	DeclRange hcl.Range
+}
+
+// Block describes the blocks of a particular type in a body.
+type Block struct {
+	Type       string
+	LabelNames []string
+
+	Description string
+
+	// Body is the schema of the body of each block.
+	Body *Schema
+
+	// DeclRange is the range of the header of the declaration of the block
+	// type in the schema file.
+	DeclRange hcl.Range
+}
+
+// LoadFile reads the schema file with the given name and returns the schema
+// it declares.
+func LoadFile(filename string) (*Schema, hcl.Diagnostics) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to read schema file",
+				Detail:   fmt.Sprintf("The schema file %q could not be read: %s.", filename, err),
+			},
+		}
+	}
+	return Parse(src, filename)
+}
+
+// Parse parses the given source code of a schema file and returns the schema
+// it declares.
+func Parse(src []byte, filename string) (*Schema, hcl.Diagnostics) {
+	file, diags := quosyntax.ParseFile(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	schema, moreDiags := Decode(file.Body)
+	return schema, append(diags, moreDiags...)
+}
+
+// Decode returns the schema declared by the given body of a schema file.
+//
+// The body must come from a file returned by quosyntax.ParseFile, or
+// descriptions will not be taken from doc comments.
+func Decode(body *quosyntax.Body) (*Schema, hcl.Diagnostics) {
+	schema, _, diags := decodeSchema(body, fileSchema)
+	return schema, diags
+}
+
+// BodySchema returns the hcl.BodySchema for the body described by the
+// schema, for use with the Content and PartialContent methods of hcl.Body.
+// It doesn't include the schemas of the bodies of blocks, which are
+// returned by BodySchema on the Body of each Block.
+func (s *Schema) BodySchema() *hcl.BodySchema {
+	ret := &hcl.BodySchema{}
+	for _, attr := range s.Attributes {
+		ret.Attributes = append(ret.Attributes, hcl.AttributeSchema{
+			Name:     attr.Name,
+			Required: attr.Required,
+		})
+	}
+	for _, block := range s.Blocks {
+		ret.Blocks = append(ret.Blocks, hcl.BlockHeaderSchema{
+			Type:       block.Type,
+			LabelNames: block.LabelNames,
+		})
+	}
+	return ret
+}
+
+// Attribute returns the attribute of the schema with the given name, or nil
+// if there is no such attribute.
+func (s *Schema) Attribute(name string) *Attribute {
+	for _, attr := range s.Attributes {
+		if attr.Name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+// Block returns the block type of the schema with the given name, or nil if
+// there is no such block type.
+func (s *Schema) Block(typeName string) *Block {
+	for _, block := range s.Blocks {
+		if block.Type == typeName {
+			return block
+		}
+	}
+	return nil
+}
+
+// SchemaFunc returns a function for quosyntax.Completions that finds the
+// schema of a body by following the types of the given nested blocks from
+// the receiver.
+func (s *Schema) SchemaFunc() quosyntax.SchemaFunc {
+	return func(blocks []*hcl.Block) *hcl.BodySchema {
+		schema := s
+		for _, block := range blocks {
+			blockS := schema.Block(block.Type)
+			if blockS == nil {
+				return nil
+			}
+			schema = blockS.Body
+		}
+		return schema.BodySchema()
+	}
+}
+
+// Value returns the value of the given attribute, which may be nil if it is
+// omitted, converted to the type of the attribute schema. The value of an
+// omitted attribute is its default value, or null if it has no default.
+func (a *Attribute) Value(attr *hcl.Attribute, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	if attr == nil {
+		if a.Default != cty.NilVal {
+			return a.Default, nil
+		}
+		return cty.NullVal(a.Type), nil
+	}
+
+	val, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.UnknownVal(a.Type), diags
+	}
+	val, err := a.Constraint.Convert(val)
+	if err != nil {
+		return cty.UnknownVal(a.Type), append(diags, &hcl.Diagnostic{
+			Severity:    hcl.DiagError,
+			Summary:     "Incorrect attribute value type",
+			Detail:      fmt.Sprintf("Inappropriate value for attribute %q: %s.", a.Name, err),
+			Subject:     attr.Expr.Range().Ptr(),
+			Expression:  attr.Expr,
+			EvalContext: ctx,
+		})
+	}
+	return val, diags
+}
+
+// The schemas of the bodies of a schema file.
+var (
+	fileSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "attribute", LabelNames: []string{"name"}},
+			{Type: "block", LabelNames: []string{"type"}},
+		},
+	}
+	blockSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "labels"},
+			{Name: "description"},
+		},
+		Blocks: fileSchema.Blocks,
+	}
+	attributeSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "type"},
+			{Name: "required"},
+			{Name: "default"},
+			{Name: "description"},
+		},
+	}
+)
+
+// decodeSchema returns the schema declared by the "attribute" and "block"
+// blocks of the given body, which has the given schema, along with the
+// content of the body for its other attributes.
+func decodeSchema(body *quosyntax.Body, bodySchema *hcl.BodySchema) (*Schema, *hcl.BodyContent, hcl.Diagnostics) {
+	ret := &Schema{}
+	content, diags := body.Content(bodySchema)
+
+	// The blocks in the content don't give access to their doc comments, so
+	// we'll find the blocks they came from by their positions.
+	decls := make(map[int]*quosyntax.Block, len(body.Blocks))
+	for _, block := range body.Blocks {
+		decls[block.TypeRange.Start.Byte] = block
+	}
+
+	declared := make(map[string]hcl.Range)
+	for _, block := range content.Blocks {
+		decl := decls[block.TypeRange.Start.Byte]
+		name := block.Labels[0]
+		key := block.Type + " " + name
+		if prev, exists := declared[key]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Duplicate %s declaration", block.Type),
+				Detail:   fmt.Sprintf("The %s %q was already declared at %s.", block.Type, name, prev),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		declared[key] = block.DefRange
+
+		switch block.Type {
+		case "attribute":
+			attr, moreDiags := decodeAttribute(decl)
+			diags = append(diags, moreDiags...)
+			ret.Attributes = append(ret.Attributes, attr)
+		case "block":
+			blockS, moreDiags := decodeBlock(decl)
+			diags = append(diags, moreDiags...)
+			ret.Blocks = append(ret.Blocks, blockS)
+		}
+	}
+	return ret, content, diags
+}
+
+func decodeAttribute(decl *quosyntax.Block) (*Attribute, hcl.Diagnostics) {
+	ret := &Attribute{
+		Name:       decl.Labels[0],
+		Type:       cty.DynamicPseudoType,
+		Constraint: anyConstraint,
+		DeclRange:  decl.DefRange(),
+	}
+	content, diags := decl.Body.Content(attributeSchema)
+
+	if attr, exists := content.Attributes["type"]; exists {
+		c, moreDiags := quotypeexpr.TypeConstraint(attr.Expr)
+		diags = append(diags, moreDiags...)
+		if !moreDiags.HasErrors() {
+			ret.Type = c.Type
+			ret.Constraint = c
+		}
+	}
+	if attr, exists := content.Attributes["required"]; exists {
+		val, moreDiags := constantValue(attr, boolConstraint)
+		diags = append(diags, moreDiags...)
+		if !moreDiags.HasErrors() {
+			ret.Required = val.True()
+		}
+	}
+	if attr, exists := content.Attributes["default"]; exists {
+		if ret.Required {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Default value for required attribute",
+				Detail:   fmt.Sprintf("The attribute %q is required, so it can't have a default value.", ret.Name),
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		} else {
+			val, moreDiags := constantValue(attr, ret.Constraint)
+			diags = append(diags, moreDiags...)
+			if !moreDiags.HasErrors() {
+				ret.Default = val
+			}
+		}
+	}
+	description, moreDiags := decodeDescription(content, decl)
+	diags = append(diags, moreDiags...)
+	ret.Description = description
+
+	return ret, diags
+}
+
+func decodeBlock(decl *quosyntax.Block) (*Block, hcl.Diagnostics) {
+	ret := &Block{
+		Type:      decl.Labels[0],
+		DeclRange: decl.DefRange(),
+	}
+	body, content, diags := decodeSchema(decl.Body, blockSchema)
+	ret.Body = body
+
+	if attr, exists := content.Attributes["labels"]; exists {
+		val, moreDiags := constantValue(attr, labelsConstraint)
+		diags = append(diags, moreDiags...)
+		if !moreDiags.HasErrors() {
+			for it := val.ElementIterator(); it.Next(); {
+				_, label := it.Element()
+				ret.LabelNames = append(ret.LabelNames, label.AsString())
+			}
+		}
+	}
+	description, moreDiags := decodeDescription(content, decl)
+	diags = append(diags, moreDiags...)
+	ret.Description = description
+
+	return ret, diags
+}
+
+// decodeDescription returns the description of the given declaration, from
+// its "description" attribute if present or else from its doc comments.
+func decodeDescription(content *hcl.BodyContent, decl *quosyntax.Block) (string, hcl.Diagnostics) {
+	attr, exists := content.Attributes["description"]
+	if !exists {
+		return decl.Doc.Text(), nil
+	}
+	val, diags := constantValue(attr, stringConstraint)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	return val.AsString(), diags
+}
+
+// The constraints of the arguments of declarations.
+var (
+	anyConstraint    = &quotypeexpr.Constraint{Type: cty.DynamicPseudoType}
+	boolConstraint   = &quotypeexpr.Constraint{Type: cty.Bool}
+	stringConstraint = &quotypeexpr.Constraint{Type: cty.String}
+	labelsConstraint = &quotypeexpr.Constraint{Type: cty.List(cty.String), Element: stringConstraint}
+)
+
+// constantValue returns the value of the given attribute of a declaration,
+// which must be a known, non-null constant that meets the given constraint.
+func constantValue(attr *hcl.Attribute, c *quotypeexpr.Constraint) (cty.Value, hcl.Diagnostics) {
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	if !val.IsWhollyKnown() || val.IsNull() {
+		return cty.NilVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid schema value",
+			Detail:   fmt.Sprintf("The %q argument must be a constant, non-null value.", attr.Name),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	val, err := c.Convert(val)
+	if err != nil {
+		return cty.NilVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid schema value",
+			Detail:   fmt.Sprintf("Inappropriate value for the %q argument: %s.", attr.Name, err),
+			Subject:  attr.Expr.Range().Ptr(),
+		})
+	}
+	return val, diags
+}
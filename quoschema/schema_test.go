@@ -0,0 +1,273 @@
+package quoschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/kr/pretty"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testSchema = `
+attribute "version" {
+  type     = string
+  required = true
+}
+
+# A quoting strategy.
+block "strategy" {
+  labels = ["name"]
+
+  attribute "spread" {
+    type        = number
+    default     = 0.01
+    description = "The spread around the mid price."
+  }
+
+  attribute "tags" {
+    type = map(string)
+  }
+
+  block "leg" {
+    labels = ["side"]
+
+    # The size of each order.
+    attribute "size" {
+      type     = number
+      required = true
+    }
+    attribute "extra" {}
+  }
+}
+`
+
+func TestParse(t *testing.T) {
+	got, diags := Parse([]byte(testSchema), "schema.quo")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	// We don't compare the declaration ranges, and the constraints are
+	// tested by the quotypeexpr package, so we only check their types.
+	strip := func(attr *Attribute) {
+		attr.DeclRange = hcl.Range{}
+		if !attr.Constraint.Type.Equals(attr.Type) {
+			t.Errorf("wrong constraint type for %s: %#v", attr.Name, attr.Constraint.Type)
+		}
+		attr.Constraint = nil
+	}
+	for _, attr := range got.Attributes {
+		strip(attr)
+	}
+	for _, block := range got.Blocks {
+		block.DeclRange = hcl.Range{}
+		for _, attr := range block.Body.Attributes {
+			strip(attr)
+		}
+		for _, block := range block.Body.Blocks {
+			block.DeclRange = hcl.Range{}
+			for _, attr := range block.Body.Attributes {
+				strip(attr)
+			}
+		}
+	}
+
+	// Capsule values can't be compared with reflect.DeepEqual, so we compare
+	// the default separately.
+	spread := got.Blocks[0].Body.Attributes[0]
+	if want := quoty.MustParseNumberVal("0.01"); !spread.Default.RawEquals(want) {
+		t.Errorf("wrong default %#v; want %#v", spread.Default, want)
+	}
+	spread.Default = cty.NilVal
+
+	want := &Schema{
+		Attributes: []*Attribute{
+			{Name: "version", Type: cty.String, Required: true},
+		},
+		Blocks: []*Block{
+			{
+				Type:        "strategy",
+				LabelNames:  []string{"name"},
+				Description: "A quoting strategy.",
+				Body: &Schema{
+					Attributes: []*Attribute{
+						{Name: "spread", Type: quoty.Number, Description: "The spread around the mid price."},
+						{Name: "tags", Type: cty.Map(cty.String)},
+					},
+					Blocks: []*Block{
+						{
+							Type:       "leg",
+							LabelNames: []string{"side"},
+							Body: &Schema{
+								Attributes: []*Attribute{
+									{Name: "size", Type: quoty.Number, Required: true, Description: "The size of each order."},
+									{Name: "extra", Type: cty.DynamicPseudoType},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong result\n%s", strings.Join(pretty.Diff(got, want), "\n"))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"duplicate attribute": {
+			"attribute \"a\" {}\nattribute \"a\" {}\n",
+			"Duplicate attribute declaration",
+		},
+		"invalid type": {
+			"attribute \"a\" {\n  type = numbr\n}\n",
+			"Invalid type specification",
+		},
+		"required with default": {
+			"attribute \"a\" {\n  required = true\n  default = 1\n}\n",
+			"Default value for required attribute",
+		},
+		"default of wrong type": {
+			"attribute \"a\" {\n  type = number\n  default = \"x\"\n}\n",
+			"Invalid schema value",
+		},
+		"invalid optional default": {
+			"attribute \"a\" {\n  type = object({ n = optional(number, \"x\") })\n}\n",
+			"Invalid default value",
+		},
+		"invalid labels": {
+			"block \"a\" {\n  labels = [{}]\n}\n",
+			"Invalid schema value",
+		},
+		"unsupported argument": {
+			"attribute \"a\" {\n  optional = true\n}\n",
+			"Unsupported argument",
+		},
+		"nested attribute": {
+			"attribute \"a\" {\n  attribute \"b\" {}\n}\n",
+			"Unsupported block type",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, diags := Parse([]byte(test.input), "schema.quo")
+			if len(diags) != 1 {
+				t.Fatalf("wrong number of diagnostics %d; want 1\n%s", len(diags), diags.Error())
+			}
+			if got := diags[0].Summary; got != test.want {
+				t.Errorf("wrong summary %q; want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSchemaContent(t *testing.T) {
+	schema, diags := Parse([]byte(testSchema), "schema.quo")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	src := `version = "1"
+strategy "grid" {
+  spread = "0.02"
+  leg "buy" {
+    price = 1
+  }
+}
+`
+	file, diags := quosyntax.ParseConfig([]byte(src), "config.quo", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	content, diags := file.Body.Content(schema.BodySchema())
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	strategy := content.Blocks[0]
+	strategyS := schema.Block(strategy.Type)
+	strategyContent, diags := strategy.Body.Content(strategyS.Body.BodySchema())
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	spreadS := strategyS.Body.Attribute("spread")
+	spread, diags := spreadS.Value(strategyContent.Attributes["spread"], nil)
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	if want := quoty.MustParseNumberVal("0.02"); !spread.RawEquals(want) {
+		t.Errorf("wrong spread %#v; want %#v", spread, want)
+	}
+	tags, _ := strategyS.Body.Attribute("tags").Value(nil, nil)
+	if want := cty.NullVal(cty.Map(cty.String)); !tags.RawEquals(want) {
+		t.Errorf("wrong tags %#v; want %#v", tags, want)
+	}
+
+	leg := strategyContent.Blocks[0]
+	_, diags = leg.Body.Content(strategyS.Body.Block("leg").Body.BodySchema())
+	var summaries []string
+	for _, diag := range diags {
+		summaries = append(summaries, diag.Summary)
+	}
+	if want := []string{"Missing required argument", "Unsupported argument"}; !reflect.DeepEqual(summaries, want) {
+		t.Errorf("wrong diagnostics\ngot:  %q\nwant: %q", summaries, want)
+	}
+}
+
+func TestSchemaFunc(t *testing.T) {
+	schema, diags := Parse([]byte(testSchema), "schema.quo")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	src := "strategy \"grid\" {\n  leg \"buy\" {\n    \n  }\n}\n"
+	file, _ := quosyntax.ParseConfig([]byte(src), "config.quo", hcl.Pos{Line: 1, Column: 1})
+	pos := hcl.Pos{Line: 3, Column: 5, Byte: 34}
+
+	var got []string
+	for _, c := range quosyntax.Completions(file, pos, nil, nil, schema.SchemaFunc()) {
+		got = append(got, c.Label)
+	}
+	if want := []string{"extra", "size"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong completions\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestAttributeValueOptional(t *testing.T) {
+	schema, diags := Parse([]byte(`
+attribute "limits" {
+  type = object({ max = amount, min = optional(number, 0) })
+}
+`), "schema.quo")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	file, diags := quosyntax.ParseConfig([]byte("limits = { max = 10 }\n"), "config.quo", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	content, diags := file.Body.Content(schema.BodySchema())
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	limits, diags := schema.Attribute("limits").Value(content.Attributes["limits"], nil)
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	if want := quoty.MustParseNumberVal("0"); !limits.GetAttr("min").RawEquals(want) {
+		t.Errorf("wrong min %#v; want %#v", limits.GetAttr("min"), want)
+	}
+}
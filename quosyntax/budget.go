@@ -0,0 +1,198 @@
+package quosyntax
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Limits describes the resources that may be used when evaluating
+// expressions with an EvalContext returned by WithLimits.
+//
+// A zero value for any of the limits means that there is no limit.
+type Limits struct {
+	// MaxSteps is the maximum number of expression evaluations. Each node
+	// of an expression counts separately each time it is evaluated, so for
+	// example the result expression of a "for" expression counts once for
+	// each element.
+	MaxSteps int64
+
+	// MaxCollectionSize is the maximum number of elements or attributes of
+	// any collection or structural value produced by a function call, a
+	// tuple or object constructor, or a "for" or splat expression.
+	MaxCollectionSize int
+
+	// MaxNumberBits is the maximum number of bits needed for the numerator
+	// and denominator together of any number produced by a function call or
+	// an operator.
+	MaxNumberBits int
+}
+
+// WithLimits returns a child of the given EvalContext that, when used to
+// evaluate native syntax expressions, constrains the evaluation to the given
+// limits and stops it if the given context is cancelled.
+//
+// The limits apply to all evaluations using the returned EvalContext or any
+// of its descendents together, which may be concurrent. Once any limit is
+// exceeded, each evaluation that is stopped because of it returns
+// cty.DynamicVal with an error diagnostic pointing at the sub-expression
+// that was being evaluated.
+func WithLimits(ctx context.Context, evalCtx *hcl.EvalContext, limits Limits) *hcl.EvalContext {
+	return withOptions(evalCtx, func(opts *evalOptions) {
+		opts.budget = &budget{
+			ctx:    ctx,
+			limits: limits,
+		}
+	})
+}
+
+// ValueWithLimits is a helper that evaluates the given expression with the
+// given limits, using WithLimits.
+func ValueWithLimits(ctx context.Context, expr Expression, evalCtx *hcl.EvalContext, limits Limits) (cty.Value, hcl.Diagnostics) {
+	return expr.Value(WithLimits(ctx, evalCtx, limits))
+}
+
+// budget tracks the resources used by the evaluations in an EvalContext
+// created by WithLimits.
+type budget struct {
+	// steps is first to ensure 64-bit alignment for atomic access.
+	steps int64 // updated atomically
+
+	ctx    context.Context
+	limits Limits
+}
+
+// budgetRun tracks a single evaluation within a budget. Each evaluation
+// stops separately once it exceeds any limit, so that each one that is
+// stopped reports why.
+type budgetRun struct {
+	budget *budget
+
+	// steps counts the steps of this evaluation alone, which decides when
+	// to check the context.
+	steps   int64
+	stopped bool
+}
+
+// eval evaluates the given expression within the budget.
+func (r *budgetRun) eval(e valueImpl, ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	if r.stopped {
+		return cty.DynamicVal, nil
+	}
+
+	r.steps++
+	steps := atomic.AddInt64(&r.budget.steps, 1)
+	if max := r.budget.limits.MaxSteps; max > 0 && steps > max {
+		return cty.DynamicVal, r.stop(
+			e, ctx,
+			"Evaluation step limit exceeded",
+			fmt.Sprintf("Evaluation was stopped here because it exceeded the maximum of %d steps.", max),
+		)
+	}
+	// Checking the context requires synchronization, so we'll do it only
+	// at the start of the evaluation and then periodically.
+	if r.steps%64 == 1 {
+		if err := r.budget.ctx.Err(); err != nil {
+			return cty.DynamicVal, r.stop(
+				e, ctx,
+				"Evaluation cancelled",
+				fmt.Sprintf("Evaluation was stopped before it was complete: %s.", err),
+			)
+		}
+	}
+
+	val, diags := e.value(ctx, fr)
+	if r.stopped {
+		// The result is probably incomplete, because some of the
+		// sub-expressions weren't evaluated.
+		return cty.DynamicVal, diags
+	}
+
+	switch e.(type) {
+	case *FunctionCallExpr, *TupleConsExpr, *ObjectConsExpr, *ForExpr, *SplatExpr, *BinaryOpExpr, *UnaryOpExpr:
+		// These are the expressions that produce new values, rather than
+		// selecting from existing ones.
+		if stopDiags := r.checkValue(e, ctx, val); stopDiags != nil || r.stopped {
+			return cty.DynamicVal, append(diags, stopDiags...)
+		}
+	}
+	return val, diags
+}
+
+// checkValue checks that the given result of evaluating the given
+// expression is within the limits.
+func (r *budgetRun) checkValue(e Expression, ctx *hcl.EvalContext, val cty.Value) hcl.Diagnostics {
+	if !val.IsKnown() || val.IsNull() {
+		return nil
+	}
+	ty := val.Type()
+
+	switch {
+	case ty.IsCollectionType():
+		return r.checkLength(e, ctx, val.LengthInt())
+	case ty.IsTupleType():
+		return r.checkLength(e, ctx, len(ty.TupleElementTypes()))
+	case ty.IsObjectType():
+		return r.checkLength(e, ctx, len(ty.AttributeTypes()))
+	case ty.Equals(quoty.Number):
+		max := r.budget.limits.MaxNumberBits
+		if max <= 0 {
+			return nil
+		}
+		br := val.EncapsulatedValue().(*big.Rat)
+		if bits := br.Num().BitLen() + br.Denom().BitLen(); bits > max {
+			return r.stop(
+				e, ctx,
+				"Number too large",
+				fmt.Sprintf("The result of this expression needs %d bits to represent exactly, but at most %d are allowed.", bits, max),
+			)
+		}
+	}
+	return nil
+}
+
+// checkLength checks that a collection with the given number of elements,
+// produced by the given expression, is within the limits.
+func (r *budgetRun) checkLength(e Expression, ctx *hcl.EvalContext, n int) hcl.Diagnostics {
+	if r == nil {
+		return nil
+	}
+	if max := r.budget.limits.MaxCollectionSize; max > 0 && n > max {
+		return r.stop(
+			e, ctx,
+			"Collection too large",
+			fmt.Sprintf("The result of this expression has more than the maximum of %d elements.", max),
+		)
+	}
+	return nil
+}
+
+// interrupted returns true if the evaluation has been stopped because a
+// limit was exceeded.
+func (r *budgetRun) interrupted() bool {
+	return r != nil && r.stopped
+}
+
+// stop stops the evaluation, returning a diagnostic with the given summary
+// and detail if this is the first time it is stopped.
+func (r *budgetRun) stop(e Expression, ctx *hcl.EvalContext, summary, detail string) hcl.Diagnostics {
+	if r.stopped {
+		return nil
+	}
+	r.stopped = true
+	return hcl.Diagnostics{
+		{
+			Severity:    hcl.DiagError,
+			Summary:     summary,
+			Detail:      detail,
+			Subject:     e.Range().Ptr(),
+			Expression:  e,
+			EvalContext: ctx,
+		},
+	}
+}
@@ -0,0 +1,219 @@
+package quosyntax
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestValueWithLimits(t *testing.T) {
+	book := make([]cty.Value, 100)
+	for i := range book {
+		book[i] = quoty.NumberIntVal(int64(i))
+	}
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"book": cty.ListVal(book),
+			"big":  quoty.NumberIntVal(1 << 40),
+		},
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		input   string
+		ctx     context.Context
+		limits  Limits
+		summary string // empty if no diagnostic is expected
+		subject string // the source text the diagnostic should point at
+	}{
+		{
+			`[for a in book: a * 2 if a > 50]`,
+			context.Background(),
+			Limits{MaxSteps: 1000, MaxCollectionSize: 100, MaxNumberBits: 64},
+			"",
+			"",
+		},
+		{
+			`[for a in book: [for b in book: a * b]]`,
+			context.Background(),
+			Limits{MaxSteps: 1000},
+			"Evaluation step limit exceeded",
+			"",
+		},
+		{
+			`[for a in book: a]`,
+			context.Background(),
+			Limits{MaxCollectionSize: 10},
+			"Collection too large",
+			`[for a in book: a]`,
+		},
+		{
+			`{for a in book: "${a}" => a}`,
+			context.Background(),
+			Limits{MaxCollectionSize: 10},
+			"Collection too large",
+			`{for a in book: "${a}" => a}`,
+		},
+		{
+			`book[*]`,
+			context.Background(),
+			Limits{MaxCollectionSize: 10},
+			"Collection too large",
+			`[*]`,
+		},
+		{
+			`[book, book][0]`,
+			context.Background(),
+			Limits{MaxCollectionSize: 10},
+			"",
+			"",
+		},
+		{
+			`1 + big * big`,
+			context.Background(),
+			Limits{MaxNumberBits: 64},
+			"Number too large",
+			`big * big`,
+		},
+		{
+			`big`,
+			context.Background(),
+			Limits{MaxNumberBits: 8},
+			"",
+			"",
+		},
+		{
+			`1 + 2`,
+			cancelled,
+			Limits{},
+			"Evaluation cancelled",
+			`1 + 2`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			got, diags := ValueWithLimits(test.ctx, expr, evalCtx, test.limits)
+
+			if test.summary == "" {
+				if len(diags) != 0 {
+					t.Fatalf("unexpected diagnostics: %s", diags.Error())
+				}
+				want, _ := expr.Value(evalCtx)
+				if !got.RawEquals(want) {
+					t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+				}
+				return
+			}
+
+			if len(diags) != 1 {
+				t.Fatalf("wrong number of diagnostics %d; want 1\n%s", len(diags), diags.Error())
+			}
+			if diags[0].Summary != test.summary {
+				t.Errorf("wrong diagnostic %q; want %q", diags[0].Summary, test.summary)
+			}
+			if test.subject != "" {
+				rng := diags[0].Subject
+				if got := test.input[rng.Start.Byte:rng.End.Byte]; got != test.subject {
+					t.Errorf("wrong subject %q; want %q", got, test.subject)
+				}
+			}
+			if !got.RawEquals(cty.DynamicVal) {
+				t.Errorf("wrong result %#v; want cty.DynamicVal", got)
+			}
+		})
+	}
+}
+
+func TestWithLimitsShared(t *testing.T) {
+	// The limits apply to all of the evaluations using the same context.
+	expr, parseDiags := ParseExpression([]byte(`1 + 2`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+
+	ctx := WithLimits(context.Background(), nil, Limits{MaxSteps: 5})
+
+	if _, diags := expr.Value(ctx); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	// Each evaluation that is stopped reports it, rather than only the
+	// first.
+	for i := 0; i < 2; i++ {
+		got, diags := expr.Value(ctx)
+		if len(diags) != 1 || diags[0].Summary != "Evaluation step limit exceeded" {
+			t.Fatalf("wrong diagnostics for evaluation %d: %s", i, diags.Error())
+		}
+		if !got.RawEquals(cty.DynamicVal) {
+			t.Errorf("wrong result %#v; want cty.DynamicVal", got)
+		}
+	}
+
+	// The limits don't apply to the parent context.
+	if _, diags := expr.Value(nil); len(diags) != 0 {
+		t.Fatalf("limits apply to the parent context: %s", diags.Error())
+	}
+}
+
+func TestWithLimitsVariables(t *testing.T) {
+	// The EvalContext created by WithLimits doesn't change which variables
+	// are available.
+	expr, parseDiags := ParseExpression([]byte(`prise`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+
+	_, diags := expr.Value(WithLimits(context.Background(), nil, Limits{}))
+	if len(diags) != 1 || diags[0].Summary != "Variables not allowed" {
+		t.Fatalf("wrong diagnostics: %s", diags.Error())
+	}
+
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{"price": quoty.NumberIntVal(1)},
+	}
+	limitedCtx := WithPrecision(WithLimits(context.Background(), evalCtx, Limits{}), quoty.Precision{MaxDigits: 4})
+	_, diags = expr.Value(limitedCtx)
+	if len(diags) != 1 || diags[0].Detail != `There is no variable named "prise". Did you mean "price"?` {
+		t.Fatalf("wrong diagnostics: %s", diags.Error())
+	}
+
+	// Nor does it add any variables or functions of its own.
+	for thisCtx := limitedCtx; thisCtx != evalCtx; thisCtx = thisCtx.Parent() {
+		if thisCtx.Variables != nil || thisCtx.Functions != nil {
+			t.Fatalf("options context has variables %#v and functions %#v", thisCtx.Variables, thisCtx.Functions)
+		}
+	}
+}
+
+func TestWithLimitsReleased(t *testing.T) {
+	// The options carried by an EvalContext are removed once it is garbage
+	// collected.
+	before := atomic.LoadInt64(&contextCount)
+	func() {
+		ctx := WithLimits(context.Background(), nil, Limits{MaxSteps: 1})
+		if optionsFor(ctx) == nil {
+			t.Fatalf("no options for the context")
+		}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&contextCount) > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("options were not released")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
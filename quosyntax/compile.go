@@ -0,0 +1,328 @@
+package quosyntax
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// CompileScope describes the variables and functions that will be available
+// when evaluating a compiled expression. It is the compile-time analog of
+// hcl.EvalContext.
+type CompileScope struct {
+	// Variables are the names of the variables that can be referenced by
+	// the expression. A compiled expression is evaluated with one value per
+	// variable, in the same order.
+	Variables []string
+
+	// Types optionally gives the expected type of each of the variables, in
+	// the same order as Variables. Type conversions are prepared in advance
+	// for values of these types, though values of other types are still
+	// accepted during evaluation.
+	Types []cty.Type
+
+	Functions map[string]function.Function
+}
+
+// CompiledExpr is an expression that has been prepared for repeated
+// evaluation using Compile.
+//
+// A CompiledExpr is safe to evaluate concurrently from multiple goroutines.
+type CompiledExpr struct {
+	expr  Expression
+	vars  []string
+	funcs map[string]function.Function
+	prog  *program
+}
+
+// program is what Compile resolves in advance for the sub-expressions of a
+// compiled expression. Evaluation of a compiled expression uses the same
+// Value methods as any other, which use the program of their frame, if any,
+// in place of resolving the same things during each evaluation.
+type program struct {
+	// roots are where the values that the roots of traversals refer to
+	// are found.
+	roots map[*ScopeTraversalExpr]root
+
+	// calls are the functions of the scope that function calls refer to.
+	calls map[*FunctionCallExpr]*compiledCall
+
+	// params are the parameters of the implementations of the operations
+	// that the expression uses.
+	params map[*Operation][]function.Parameter
+
+	// conversions are the conversions of the values of operands and
+	// arguments to the types of their parameters, for those whose static
+	// type is known but isn't the parameter type.
+	conversions map[Expression]conversion
+}
+
+// root is where the value of a variable or symbol is found during the
+// evaluation of a traversal that refers to it.
+type root struct {
+	// slot is the position of a variable of the scope within the variable
+	// values, or -1 for a symbol of an enclosing "for" expression.
+	slot int
+
+	// up is the number of levels above the EvalContext of the traversal of
+	// the EvalContext that the "for" expression binds a symbol in.
+	up int
+}
+
+// compiledCall is a function that a function call refers to, along with its
+// parameters.
+type compiledCall struct {
+	f        function.Function
+	params   []function.Parameter
+	varParam *function.Parameter
+}
+
+// conversion is a conversion prepared for the values of the given type.
+type conversion struct {
+	from cty.Type
+	conv convert.Conversion
+}
+
+// Compile prepares the given expression for repeated evaluation with the
+// variables and functions described by the given scope.
+//
+// Evaluating the result with CompiledExpr.Value produces the same result and
+// diagnostics as evaluating the original expression using its Value method
+// with an hcl.EvalContext containing the same variables and functions, but
+// does less work per evaluation: functions are resolved during compilation,
+// variables are retrieved by position rather than by name, and conversions
+// of operands and arguments are prepared in advance where their types are
+// known.
+//
+// A nil scope is equivalent to evaluating with a nil hcl.EvalContext, so
+// that neither variables nor functions are available.
+//
+// The given expression must not be modified while the result is in use.
+func Compile(expr Expression, scope *CompileScope) *CompiledExpr {
+	if scope == nil {
+		scope = &CompileScope{}
+	}
+	c := &compiler{
+		scope: scope,
+		prog: &program{
+			roots:       make(map[*ScopeTraversalExpr]root),
+			calls:       make(map[*FunctionCallExpr]*compiledCall),
+			params:      make(map[*Operation][]function.Parameter),
+			conversions: make(map[Expression]conversion),
+		},
+	}
+
+	ctx := &TypeContext{
+		Functions: scope.Functions,
+	}
+	names := make(map[string]name, len(scope.Variables))
+	if scope.Variables != nil {
+		ctx.Variables = make(map[string]cty.Type, len(scope.Variables))
+		for i, n := range scope.Variables {
+			ty := cty.DynamicPseudoType
+			if i < len(scope.Types) {
+				ty = scope.Types[i]
+			}
+			ctx.Variables[n] = ty
+			names[n] = name{slot: i}
+		}
+	}
+	c.compile(expr, ctx, names, 0)
+
+	return &CompiledExpr{
+		expr:  expr,
+		vars:  scope.Variables,
+		funcs: scope.Functions,
+		prog:  c.prog,
+	}
+}
+
+// Value evaluates the compiled expression with the given variable values,
+// which must be in the same order as the Variables in the scope given to
+// Compile.
+//
+// Value will panic if the number of given values does not match the number
+// of variables in the scope.
+func (e *CompiledExpr) Value(vars []cty.Value) (cty.Value, hcl.Diagnostics) {
+	return e.ValueIn(nil, vars)
+}
+
+// ValueIn is like Value, except that the compiled expression is evaluated
+// as if with a child of the given EvalContext containing the variables and
+// functions of the scope. Any options attached to the given EvalContext by
+// WithLimits, WithPrecision or WithTracer apply to the evaluation, and any
+// of its variables and functions that the scope doesn't have are available
+// too, though they aren't resolved in advance.
+func (e *CompiledExpr) ValueIn(parent *hcl.EvalContext, vars []cty.Value) (cty.Value, hcl.Diagnostics) {
+	if len(vars) != len(e.vars) {
+		panic(fmt.Sprintf("compiled expression requires %d variables, but %d values given", len(e.vars), len(vars)))
+	}
+
+	ctx := parent
+	if e.vars != nil || e.funcs != nil {
+		if parent != nil {
+			ctx = parent.NewChild()
+		} else {
+			ctx = &hcl.EvalContext{}
+		}
+		ctx.Functions = e.funcs
+		if e.vars != nil {
+			ctx.Variables = make(map[string]cty.Value, len(e.vars))
+			for i, name := range e.vars {
+				ctx.Variables[name] = vars[i]
+			}
+		}
+	}
+
+	fr := &evalFrame{}
+	if opts := frameFor(ctx); opts != nil {
+		*fr = *opts
+	}
+	fr.prog = e.prog
+	fr.vars = vars
+	return fr.eval(e.expr, ctx)
+}
+
+// Expression returns the expression that was compiled.
+func (e *CompiledExpr) Expression() Expression {
+	return e.expr
+}
+
+// Slot returns the position of the variable with the given name in the
+// values given to Value, or false if there is no such variable.
+func (e *CompiledExpr) Slot(name string) (int, bool) {
+	for i, n := range e.vars {
+		if n == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// compiler is the implementation of Compile.
+type compiler struct {
+	scope *CompileScope
+	prog  *program
+}
+
+// name describes a variable of the scope or a symbol of a "for" expression
+// during compilation.
+type name struct {
+	// slot is the position of a variable within the variable values, or -1
+	// for a symbol, and depth is the number of "for" expressions enclosing
+	// the clauses in which a symbol is bound.
+	slot  int
+	depth int
+}
+
+// compile adds what can be resolved in advance for the given node and its
+// descendents to the program, given the types of the variables and symbols
+// in scope, the names that are in scope and the number of "for" expressions
+// that enclose the node.
+func (c *compiler) compile(node Node, ctx *TypeContext, names map[string]name, depth int) {
+	switch e := node.(type) {
+	case *ScopeTraversalExpr:
+		if n, ok := names[e.Traversal.RootName()]; ok {
+			c.prog.roots[e] = root{
+				slot: n.slot,
+				up:   depth - n.depth,
+			}
+		}
+
+	case *FunctionCallExpr:
+		f, ok := c.scope.Functions[e.Name]
+		if !ok {
+			break
+		}
+		call := &compiledCall{
+			f:        f,
+			params:   f.Params(),
+			varParam: f.VarParam(),
+		}
+		c.prog.calls[e] = call
+		for i, arg := range e.Args {
+			if e.ExpandFinal && i == len(e.Args)-1 {
+				// The expanded values are converted individually.
+				break
+			}
+			param := call.varParam
+			if i < len(call.params) {
+				param = &call.params[i]
+			}
+			if param == nil {
+				break
+			}
+			c.prepareConversion(arg, param.Type, ctx)
+		}
+
+	case *BinaryOpExpr:
+		params := c.operationParams(e.Op)
+		c.prepareConversion(e.LHS, params[0].Type, ctx)
+		c.prepareConversion(e.RHS, params[1].Type, ctx)
+
+	case *UnaryOpExpr:
+		params := c.operationParams(e.Op)
+		c.prepareConversion(e.Val, params[0].Type, ctx)
+
+	case *ForExpr:
+		c.compile(e.CollExpr, ctx, names, depth)
+
+		// The other clauses are evaluated with a child EvalContext for
+		// each element, in which the symbols shadow any variables of the
+		// same names.
+		inner := ctx.NewChild()
+		inner.Variables = make(map[string]cty.Type, 2)
+		innerNames := make(map[string]name, len(names)+2)
+		for n, info := range names {
+			innerNames[n] = info
+		}
+		for _, n := range []string{e.KeyVar, e.ValVar} {
+			if n != "" {
+				inner.Variables[n] = cty.DynamicPseudoType
+				innerNames[n] = name{slot: -1, depth: depth + 1}
+			}
+		}
+		if e.KeyExpr != nil {
+			c.compile(e.KeyExpr, inner, innerNames, depth+1)
+		}
+		c.compile(e.ValExpr, inner, innerNames, depth+1)
+		if e.CondExpr != nil {
+			c.compile(e.CondExpr, inner, innerNames, depth+1)
+		}
+		return
+	}
+
+	node.walkChildNodes(func(child Node) {
+		c.compile(child, ctx, names, depth)
+	})
+}
+
+// operationParams returns the parameters of the implementation of the given
+// operation, adding them to the program if they aren't already there.
+func (c *compiler) operationParams(op *Operation) []function.Parameter {
+	params, ok := c.prog.params[op]
+	if !ok {
+		params = op.Impl.Params()
+		c.prog.params[op] = params
+	}
+	return params
+}
+
+// prepareConversion adds the conversion of the value of the given operand or
+// argument to the given type to the program, if its static type is known
+// and a conversion is needed.
+func (c *compiler) prepareConversion(expr Expression, want cty.Type, ctx *TypeContext) {
+	ty, _ := InferType(expr, ctx)
+	if ty == cty.NilType || ty.Equals(cty.DynamicPseudoType) || ty.Equals(want) {
+		return
+	}
+	if conv := convert.GetConversionUnsafe(ty, want); conv != nil {
+		c.prog.conversions[expr] = conversion{
+			from: ty,
+			conv: conv,
+		}
+	}
+}
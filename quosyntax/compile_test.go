@@ -0,0 +1,322 @@
+package quosyntax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+func TestCompiledExprValue(t *testing.T) {
+	assetTy := cty.Object(map[string]cty.Type{
+		"code":   cty.String,
+		"issuer": cty.String,
+	})
+	names := []string{"price", "name", "enabled", "asset", "assets", "prices", "pending"}
+	types := []cty.Type{quoty.Number, cty.String, cty.Bool, assetTy, cty.List(assetTy), cty.Map(quoty.Number), quoty.Number}
+	vals := []cty.Value{
+		quoty.NumberIntVal(8),
+		cty.StringVal("XLM"),
+		cty.True,
+		cty.ObjectVal(map[string]cty.Value{
+			"code":   cty.StringVal("USD"),
+			"issuer": cty.StringVal("GA"),
+		}),
+		cty.ListVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{
+				"code":   cty.StringVal("USD"),
+				"issuer": cty.StringVal("GA"),
+			}),
+			cty.ObjectVal(map[string]cty.Value{
+				"code":   cty.StringVal("EUR"),
+				"issuer": cty.StringVal("GB"),
+			}),
+		}),
+		cty.MapVal(map[string]cty.Value{
+			"XLM": quoty.NumberIntVal(2),
+			"BTC": quoty.NumberIntVal(0),
+		}),
+		cty.UnknownVal(quoty.Number),
+	}
+	funcs := map[string]function.Function{
+		"upper": stdlib.UpperFunc,
+		"max":   stdlib.MaxFunc,
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{},
+		Functions: funcs,
+	}
+	for i, name := range names {
+		ctx.Variables[name] = vals[i]
+	}
+
+	tests := []struct {
+		input string
+		scope *CompileScope
+		ctx   *hcl.EvalContext
+	}{
+		{`1 + 2`, nil, nil},
+		{`price`, nil, nil},
+		{`upper("a")`, nil, nil},
+		{`price * (1 + 25/10000)`, nil, ctx},
+		{`price + "USD"`, nil, ctx},
+		{`-price`, nil, ctx},
+		{`!enabled`, nil, ctx},
+		{`pending * 2`, nil, ctx},
+		{`prices["XLM"] > 1 && enabled`, nil, ctx},
+		{`asset.code`, nil, ctx},
+		{`asset.cod`, nil, ctx},
+		{`assets[1].issuer`, nil, ctx},
+		{`assets[2]`, nil, ctx},
+		{`nme`, nil, ctx},
+		{`upper(name)`, nil, ctx},
+		{`upper(asset)`, nil, ctx},
+		{`upper(name, name)`, nil, ctx},
+		{`upper()`, nil, ctx},
+		{`uper(name)`, nil, ctx},
+		{`max([1, price]...)`, nil, ctx},
+		{`max(name...)`, nil, ctx},
+		{`max(null...)`, nil, ctx},
+		{`upper([]...)`, nil, ctx},
+		{`enabled ? price : 0`, nil, ctx},
+		{`enabled ? asset : null`, nil, ctx},
+		{`enabled ? asset : price`, nil, ctx},
+		{`name ? 1 : 2`, nil, ctx},
+		{`null ? 1 : 2`, nil, ctx},
+		{`{ code = name, amount = price }`, nil, ctx},
+		{`{ (name) = price, (null) = 1 }`, nil, ctx},
+		{`{ a.b = 1 }`, nil, ctx},
+		{`[name, price][1]`, nil, ctx},
+		{`"${asset.code}/${price}"`, nil, ctx},
+		{`"x${asset}"`, nil, ctx},
+		{`"x${null}"`, nil, ctx},
+		{`[for a in assets: a.code]`, nil, ctx},
+		{`[for i, a in assets: "${i}:${a.code}" if a.code != "USD"]`, nil, ctx},
+		{`[for a in assets: a.cod]`, nil, ctx},
+		{`{for k, v in prices: k => v * 2 if v > 0}`, nil, ctx},
+		{`{for a in assets: a.issuer => a.code...}`, nil, ctx},
+		{`{for a in [1, 1]: a => a}`, nil, ctx},
+		{`[for a in assets: a if a]`, nil, ctx},
+		{`[for c in price: c]`, nil, ctx},
+		{`[for c in null: c]`, nil, ctx},
+		{`[for price in [1, 2]: price * 2]`, nil, ctx},
+		{`"%{ for a in assets }${a.code}%{ endfor }"`, nil, ctx},
+		{`assets[*].code`, nil, ctx},
+		{`assets[*].cod`, nil, ctx},
+		{`asset.*.issuer`, nil, ctx},
+		{`nope[*].code`, nil, ctx},
+		{`[for a in assets: [a][*].code]`, nil, ctx},
+		{`[for a in assets: [for b in assets: "${a.code}${b.code}"]]`, nil, ctx},
+		{`[for i, a in assets: [for a in [i]: a + price]]`, nil, ctx},
+		{`[for i, a in assets: [for j, b in prices: "${i}${b}${a.cod}"]]`, nil, ctx},
+		{`enabled && "true"`, nil, ctx},
+		{`!"maybe"`, nil, ctx},
+		{`upper(enabled)`, nil, ctx},
+		{`price > "1"`, nil, ctx},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			scope := test.scope
+			var args []cty.Value
+			if scope == nil && test.ctx != nil {
+				scope = &CompileScope{
+					Variables: names,
+					Types:     types,
+					Functions: funcs,
+				}
+				args = vals
+			}
+
+			wantVal, wantDiags := expr.Value(test.ctx)
+			gotVal, gotDiags := Compile(expr, scope).Value(args)
+
+			if !gotVal.RawEquals(wantVal) {
+				t.Errorf("wrong result value\ngot:  %#v\nwant: %#v", gotVal, wantVal)
+			}
+			if len(gotDiags) != len(wantDiags) {
+				for _, diag := range gotDiags {
+					t.Logf(" - %s", diag.Error())
+				}
+				t.Fatalf("wrong number of diagnostics %d; want %d", len(gotDiags), len(wantDiags))
+			}
+			for i := range gotDiags {
+				got, want := gotDiags[i], wantDiags[i]
+				if got.Error() != want.Error() {
+					t.Errorf("wrong diagnostic %d\ngot:  %s\nwant: %s", i, got.Error(), want.Error())
+				}
+				if (got.Context == nil) != (want.Context == nil) || (got.Context != nil && *got.Context != *want.Context) {
+					t.Errorf("wrong context for diagnostic %d\ngot:  %s\nwant: %s", i, got.Context, want.Context)
+				}
+				if got.Expression != want.Expression {
+					if _, isLit := want.Expression.(*LiteralValueExpr); !isLit {
+						t.Errorf("wrong expression for diagnostic %d", i)
+					}
+				}
+				if (got.EvalContext == nil) != (want.EvalContext == nil) {
+					t.Errorf("wrong eval context for diagnostic %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCompiledExprEvalContext(t *testing.T) {
+	expr, parseDiags := ParseExpression([]byte(`price + "USD"`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+	compiled := Compile(expr, &CompileScope{
+		Variables: []string{"price"},
+	})
+
+	_, diags := compiled.Value([]cty.Value{quoty.NumberIntVal(2)})
+	if len(diags) != 1 {
+		t.Fatalf("wrong number of diagnostics %d; want 1", len(diags))
+	}
+	ctx := diags[0].EvalContext
+	if ctx == nil {
+		t.Fatalf("diagnostic has no EvalContext")
+	}
+	if got, want := ctx.Variables["price"], quoty.NumberIntVal(2); !got.RawEquals(want) {
+		t.Errorf("wrong price in EvalContext\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if slot, ok := compiled.Slot("price"); !ok || slot != 0 {
+		t.Errorf("wrong slot for price: %d, %t", slot, ok)
+	}
+	if _, ok := compiled.Slot("name"); ok {
+		t.Errorf("found slot for undeclared variable name")
+	}
+}
+
+func TestCompiledExprOtherType(t *testing.T) {
+	// A value of a type other than the one given in the scope is still
+	// converted as it would be by Value.
+	expr, parseDiags := ParseExpression([]byte(`!flag`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+	compiled := Compile(expr, &CompileScope{
+		Variables: []string{"flag"},
+		Types:     []cty.Type{cty.String},
+	})
+
+	for _, val := range []cty.Value{cty.StringVal("true"), cty.False, cty.StringVal("maybe")} {
+		want, wantDiags := expr.Value(&hcl.EvalContext{
+			Variables: map[string]cty.Value{"flag": val},
+		})
+		got, gotDiags := compiled.Value([]cty.Value{val})
+		if !got.RawEquals(want) {
+			t.Errorf("wrong result for %#v\ngot:  %#v\nwant: %#v", val, got, want)
+		}
+		if gotDiags.Error() != wantDiags.Error() {
+			t.Errorf("wrong diagnostics for %#v\ngot:  %s\nwant: %s", val, gotDiags.Error(), wantDiags.Error())
+		}
+	}
+}
+
+// benchmarkExpr is the expression used by the evaluation benchmarks.
+const benchmarkExpr = `[for a in assets: "${upper(a.code)}: ${a.amount * price * (1 + fee)}" if a.amount > 0]`
+
+func benchmarkVars() ([]string, []cty.Value) {
+	assets := make([]cty.Value, 10)
+	for i := range assets {
+		assets[i] = cty.ObjectVal(map[string]cty.Value{
+			"code":   cty.StringVal("usd"),
+			"amount": quoty.NumberIntVal(int64(i)),
+		})
+	}
+	return []string{"assets", "price", "fee"}, []cty.Value{
+		cty.ListVal(assets),
+		quoty.NumberIntVal(8),
+		quoty.MustParseNumberVal("0.0025"),
+	}
+}
+
+func TestCompiledExprOptions(t *testing.T) {
+	expr, parseDiags := ParseExpression([]byte(`[for p in prices: p * 2]`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+	compiled := Compile(expr, &CompileScope{
+		Variables: []string{"prices"},
+	})
+	prices := cty.TupleVal([]cty.Value{quoty.NumberIntVal(1), quoty.NumberIntVal(2)})
+
+	// The options attached to the parent EvalContext apply to compiled
+	// expressions too.
+	ctx := WithLimits(context.Background(), nil, Limits{MaxSteps: 3})
+	_, diags := compiled.ValueIn(ctx, []cty.Value{prices})
+	if len(diags) != 1 || diags[0].Summary != "Evaluation step limit exceeded" {
+		t.Fatalf("wrong diagnostics: %s", diags.Error())
+	}
+
+	recorder := &TraceRecorder{}
+	got, diags := compiled.ValueIn(WithTracer(nil, recorder), []cty.Value{prices})
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+	events := recorder.Events()
+	if len(events) != 1 || events[0].Expr != expr || !events[0].Output.RawEquals(got) {
+		t.Fatalf("wrong trace events %#v", events)
+	}
+}
+
+func BenchmarkExpressionValue(b *testing.B) {
+	expr, diags := ParseExpression([]byte(benchmarkExpr), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if diags.HasErrors() {
+		b.Fatal(diags.Error())
+	}
+	names, vals := benchmarkVars()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx := &hcl.EvalContext{
+			Variables: make(map[string]cty.Value, len(names)),
+			Functions: map[string]function.Function{
+				"upper": stdlib.UpperFunc,
+			},
+		}
+		for i, name := range names {
+			ctx.Variables[name] = vals[i]
+		}
+		if _, diags := expr.Value(ctx); diags.HasErrors() {
+			b.Fatal(diags.Error())
+		}
+	}
+}
+
+func BenchmarkCompiledExprValue(b *testing.B) {
+	expr, diags := ParseExpression([]byte(benchmarkExpr), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if diags.HasErrors() {
+		b.Fatal(diags.Error())
+	}
+	names, vals := benchmarkVars()
+	compiled := Compile(expr, &CompileScope{
+		Variables: names,
+		Types:     []cty.Type{vals[0].Type(), vals[1].Type(), vals[2].Type()},
+		Functions: map[string]function.Function{
+			"upper": stdlib.UpperFunc,
+		},
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, diags := compiled.Value(vals); diags.HasErrors() {
+			b.Fatal(diags.Error())
+		}
+	}
+}
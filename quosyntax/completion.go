@@ -0,0 +1,468 @@
+package quosyntax
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// CompletionKind describes what a completion candidate refers to.
+type CompletionKind int
+
+const (
+	// CompleteVariable is a root variable from the TypeContext.
+	CompleteVariable CompletionKind = iota
+
+	// CompleteForVariable is a key or value symbol of a 'for' expression
+	// that encloses the position.
+	CompleteForVariable
+
+	// CompleteFunction is a function from the TypeContext.
+	CompleteFunction
+
+	// CompleteAttribute is an attribute of the object before a ".".
+	CompleteAttribute
+
+	// CompleteBodyAttribute is an attribute that the schema allows in the
+	// body containing the position.
+	CompleteBodyAttribute
+
+	// CompleteBlockType is a block type that the schema allows in the body
+	// containing the position.
+	CompleteBlockType
+)
+
+// Completion is a candidate for the text at a position in a file.
+type Completion struct {
+	// Label is the text to insert, such as a variable name.
+	Label string
+
+	Kind CompletionKind
+
+	// Detail is a short description of the candidate: the type of a
+	// variable or attribute, the signature of a function, or whether a
+	// body attribute is required.
+	Detail string
+
+	// Doc is the documentation of the candidate from the docs given to
+	// Completions, if any.
+	Doc string
+
+	// Range is the range of the partially-typed name that the candidate
+	// would replace, which is empty if nothing has been typed yet.
+	Range hcl.Range
+}
+
+// SchemaFunc returns the schema for the body of the innermost of the given
+// nested blocks, or for the root body of the file if there are none. It may
+// return nil if the schema of the body isn't known.
+type SchemaFunc func(blocks []*hcl.Block) *hcl.BodySchema
+
+// Completions returns the candidates for completing the text at the given
+// position in the given file, which must have been produced by this package,
+// sorted by label.
+//
+// Within an expression, the candidates are the variables and functions of
+// the given context along with the symbols of any enclosing 'for'
+// expressions, or the attributes of the object before a ".". Elsewhere in a
+// body, they are the attributes and block types that the schema returned by
+// the given function allows, other than attributes that are already present.
+// Either the context or the schema function may be nil, in which case they
+// contribute no candidates.
+//
+// The given docs, which may be nil, document the variables and their
+// attributes keyed by the traversal that refers to them, such as
+// "var.max_spread". Body.AttributeDocs produces docs in this form from the
+// doc comments of a body.
+//
+// The candidates are limited to those beginning with the name being typed
+// at the position, if any. The file doesn't need to be valid, since the
+// text around the position is analyzed directly, but the candidates for
+// some incomplete constructs may be limited. There are no candidates for a
+// position outside of the file.
+func Completions(file *hcl.File, pos hcl.Pos, ctx *TypeContext, docs map[string]string, schema SchemaFunc) []Completion {
+	body, ok := file.Body.(*Body)
+	if !ok {
+		return nil
+	}
+	start := hcl.Pos{Line: 1, Column: 1, Byte: 0}
+	if nav, ok := file.Nav.(navigation); ok {
+		start = nav.start
+	}
+	src := file.Bytes
+	if pos.Byte < start.Byte || pos.Byte-start.Byte > len(src) {
+		return nil
+	}
+	tokens, _ := LexConfig(src, body.SrcRange.Filename, start)
+
+	c := &completer{
+		src:    src,
+		start:  start,
+		pos:    pos,
+		tokens: tokens,
+		docs:   docs,
+		prefixRange: hcl.Range{
+			Filename: body.SrcRange.Filename,
+			Start:    pos,
+			End:      pos,
+		},
+	}
+
+	// prev is the index of the last token before the name being typed.
+	prev := -1
+	for i, tok := range tokens {
+		if tok.Range.End.Byte > pos.Byte || tok.Type == TokenEOF {
+			if tok.Type == TokenIdent && tok.Range.Start.Byte < pos.Byte {
+				c.prefix = string(c.text(tok.Range.Start, pos))
+				c.prefixRange = tok.Range
+			}
+			break
+		}
+		if tok.Type == TokenIdent && tok.Range.End.Byte == pos.Byte {
+			c.prefix = string(tok.Bytes)
+			c.prefixRange = tok.Range
+			break
+		}
+		prev = i
+	}
+
+	var ret []Completion
+	switch c.context(prev) {
+	case completeBody:
+		ret = c.bodyCompletions(body, schema)
+	case completeExpr:
+		ret = c.exprCompletions(file, ctx)
+	case completeAttr:
+		ret = c.attrCompletions(file, ctx, prev)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Label < ret[j].Label
+	})
+	return ret
+}
+
+type completer struct {
+	src    []byte
+	start  hcl.Pos // the position of the start of src
+	pos    hcl.Pos
+	tokens Tokens
+	docs   map[string]string
+
+	// prefix is the part of the name being typed that is before the
+	// position, and prefixRange is the range of the whole name.
+	prefix      string
+	prefixRange hcl.Range
+}
+
+type completionContext int
+
+const (
+	completeNothing completionContext = iota
+	completeBody
+	completeExpr
+	completeAttr
+)
+
+// text returns the source text between the given positions.
+func (c *completer) text(from, to hcl.Pos) []byte {
+	return c.src[from.Byte-c.start.Byte : to.Byte-c.start.Byte]
+}
+
+// context determines what kind of candidates are valid after the token with
+// the given index, by tracking the brackets that are open at that point.
+func (c *completer) context(prev int) completionContext {
+	type opener struct {
+		typ   TokenType
+		block bool
+	}
+	var open []opener
+	lastSignificant := TokenNil
+	for _, tok := range c.tokens[:prev+1] {
+		switch tok.Type {
+		case TokenOBrace:
+			// A brace opens a block if it follows the block type or a label,
+			// and an object constructor otherwise.
+			block := lastSignificant == TokenIdent || lastSignificant == TokenCQuote
+			if len(open) != 0 && !open[len(open)-1].block {
+				block = false
+			}
+			open = append(open, opener{typ: tok.Type, block: block})
+		case TokenOBrack, TokenOParen, TokenOQuote, TokenOHeredoc, TokenTemplateInterp, TokenTemplateControl:
+			open = append(open, opener{typ: tok.Type})
+		case TokenCBrace, TokenCBrack, TokenCParen, TokenCQuote, TokenCHeredoc, TokenTemplateSeqEnd:
+			if len(open) != 0 {
+				open = open[:len(open)-1]
+			}
+		}
+		if tok.Type != TokenNewline && tok.Type != TokenComment {
+			lastSignificant = tok.Type
+		}
+	}
+
+	var top opener
+	if len(open) != 0 {
+		top = open[len(open)-1]
+	}
+	switch top.typ {
+	case TokenOQuote, TokenOHeredoc:
+		// We're in the literal part of a template.
+		return completeNothing
+	case TokenNil:
+		top.block = true
+	}
+
+	var prevTok Token
+	if prev >= 0 {
+		prevTok = c.tokens[prev]
+	}
+	switch {
+	case prevTok.Type == TokenDot:
+		return completeAttr
+	case top.block && (prev < 0 || prevTok.Type == TokenNewline || prevTok.Type == TokenOBrace ||
+		(prevTok.Type == TokenComment && bytes.HasSuffix(prevTok.Bytes, []byte{'\n'}))):
+		return completeBody
+	case top.block && prevTok.Type != TokenEqual:
+		// Anything other than the start of an attribute's expression is
+		// something like a block label, which we can't complete.
+		return completeNothing
+	default:
+		return completeExpr
+	}
+}
+
+// bodyCompletions returns the attributes and block types allowed in the
+// body containing the position.
+func (c *completer) bodyCompletions(body *Body, schemaFn SchemaFunc) []Completion {
+	if schemaFn == nil {
+		return nil
+	}
+	blocks := body.BlocksAtPos(c.pos)
+	schema := schemaFn(blocks)
+	if schema == nil {
+		return nil
+	}
+	current := body
+	if _, innermost := body.blocksAtPos(c.pos, false); innermost != nil {
+		current = innermost.Body
+	}
+
+	var ret []Completion
+	for _, attrS := range schema.Attributes {
+		if attr, exists := current.Attributes[attrS.Name]; exists && attr.NameRange.Start.Byte != c.prefixRange.Start.Byte {
+			continue
+		}
+		detail := "optional"
+		if attrS.Required {
+			detail = "required"
+		}
+		ret = c.appendCandidate(ret, attrS.Name, CompleteBodyAttribute, detail, "")
+	}
+	for _, blockS := range schema.Blocks {
+		detail := "block"
+		if len(blockS.LabelNames) != 0 {
+			detail = fmt.Sprintf("block with labels %s", strings.Join(blockS.LabelNames, ", "))
+		}
+		ret = c.appendCandidate(ret, blockS.Type, CompleteBlockType, detail, "")
+	}
+	return ret
+}
+
+// exprCompletions returns the variables, 'for' symbols and functions that
+// are in scope at the position.
+func (c *completer) exprCompletions(file *hcl.File, ctx *TypeContext) []Completion {
+	var ret []Completion
+	seen := make(map[string]bool)
+
+	symbols := c.forSymbols(file, ctx)
+	for i := len(symbols) - 1; i >= 0; i-- {
+		// Inner symbols shadow outer ones of the same name.
+		for name, ty := range symbols[i] {
+			if !seen[name] {
+				seen[name] = true
+				ret = c.appendCandidate(ret, name, CompleteForVariable, ty.FriendlyName(), "")
+			}
+		}
+	}
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		for name, ty := range thisCtx.Variables {
+			if !seen[name] {
+				seen[name] = true
+				ret = c.appendCandidate(ret, name, CompleteVariable, ty.FriendlyName(), c.docs[name])
+			}
+		}
+	}
+
+	seen = make(map[string]bool)
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		for name, f := range thisCtx.Functions {
+			if !seen[name] {
+				seen[name] = true
+				ret = c.appendCandidate(ret, name, CompleteFunction, functionSignature(name, f), "")
+			}
+		}
+	}
+	return ret
+}
+
+// attrCompletions returns the attributes of the object produced by the
+// traversal that ends with the "." token at the given index.
+func (c *completer) attrCompletions(file *hcl.File, ctx *TypeContext, dot int) []Completion {
+	// We find the start of the traversal by walking backwards over its
+	// attribute and index steps.
+	start := dot - 1
+	for {
+		for start >= 0 && c.tokens[start].Type == TokenCBrack {
+			start = c.skipIndex(start)
+		}
+		if start < 0 || c.tokens[start].Type != TokenIdent {
+			return nil
+		}
+		if start == 0 || c.tokens[start-1].Type != TokenDot {
+			break
+		}
+		start -= 2
+	}
+
+	startTok, dotTok := c.tokens[start], c.tokens[dot]
+	traversal, diags := ParseTraversalAbs(c.text(startTok.Range.Start, dotTok.Range.Start), startTok.Range.Filename, startTok.Range.Start)
+	if diags.HasErrors() {
+		return nil
+	}
+
+	root := traversal.RootName()
+	ty := cty.NilType
+	symbols := c.forSymbols(file, ctx)
+	for i := len(symbols) - 1; i >= 0 && ty == cty.NilType; i-- {
+		if symTy, exists := symbols[i][root]; exists {
+			ty = symTy
+		}
+	}
+	for thisCtx := ctx; thisCtx != nil && ty == cty.NilType; thisCtx = thisCtx.Parent() {
+		if varTy, exists := thisCtx.Variables[root]; exists {
+			ty = varTy
+		}
+	}
+	if ty == cty.NilType {
+		return nil
+	}
+
+	val, diags := traverseRel(traversal.SimpleSplit().Rel, cty.UnknownVal(ty))
+	if diags.HasErrors() || !val.Type().IsObjectType() {
+		return nil
+	}
+
+	// Attributes are documented under the traversal that refers to them,
+	// as written before the ".".
+	prefix := string(c.text(startTok.Range.Start, dotTok.Range.Start))
+	var ret []Completion
+	for name, aty := range val.Type().AttributeTypes() {
+		ret = c.appendCandidate(ret, name, CompleteAttribute, aty.FriendlyName(), c.docs[prefix+"."+name])
+	}
+	return ret
+}
+
+// skipIndex returns the index of the token before the opening bracket that
+// matches the closing bracket at the given index, or -1 if it isn't a simple
+// index with a literal key.
+func (c *completer) skipIndex(close int) int {
+	if close < 2 {
+		return -1
+	}
+	if c.tokens[close-1].Type == TokenNumberLit && c.tokens[close-2].Type == TokenOBrack {
+		return close - 3
+	}
+	if close >= 4 && c.tokens[close-1].Type == TokenCQuote && c.tokens[close-2].Type == TokenQuotedLit &&
+		c.tokens[close-3].Type == TokenOQuote && c.tokens[close-4].Type == TokenOBrack {
+		return close - 5
+	}
+	return -1
+}
+
+// forSymbols returns the types of the symbols of the 'for' expressions that
+// enclose the position, from outermost to innermost.
+func (c *completer) forSymbols(file *hcl.File, ctx *TypeContext) []map[string]cty.Type {
+	// The expression around the position is likely to be incomplete, such
+	// as when only a "." has been typed so far, so we'll give the parser a
+	// name to work with to make it more likely that the enclosing
+	// expressions can be parsed.
+	src := file.Bytes
+	if c.prefix == "" {
+		offset := c.pos.Byte - c.start.Byte
+		patched := make([]byte, 0, len(src)+1)
+		patched = append(patched, src[:offset]...)
+		patched = append(patched, 'x')
+		patched = append(patched, src[offset:]...)
+		src = patched
+	}
+	patchedFile, _ := ParseConfig(src, c.prefixRange.Filename, c.start)
+	if patchedFile == nil {
+		return nil
+	}
+	body, ok := patchedFile.Body.(*Body)
+	if !ok {
+		return nil
+	}
+
+	var fors []*ForExpr
+	VisitAll(body, func(node Node) hcl.Diagnostics {
+		e, ok := node.(*ForExpr)
+		if !ok || !e.SrcRange.ContainsPos(c.pos) || e.CollExpr.Range().End.Byte > c.pos.Byte {
+			return nil
+		}
+		fors = append(fors, e)
+		return nil
+	})
+	// The visitor visits outer expressions before the ones they contain,
+	// but we'll sort anyway so we don't depend on that.
+	sort.SliceStable(fors, func(i, j int) bool {
+		return fors[i].SrcRange.Start.Byte < fors[j].SrcRange.Start.Byte
+	})
+
+	var ret []map[string]cty.Type
+	for _, e := range fors {
+		collTy, _ := InferType(e.CollExpr, ctx)
+		keyTy, valTy := iterationTypes(collTy)
+		symbols := map[string]cty.Type{e.ValVar: valTy}
+		if e.KeyVar != "" {
+			symbols[e.KeyVar] = keyTy
+		}
+		ret = append(ret, symbols)
+
+		childCtx := ctx.NewChild()
+		childCtx.Variables = symbols
+		ctx = childCtx
+	}
+	return ret
+}
+
+func (c *completer) appendCandidate(list []Completion, label string, kind CompletionKind, detail, doc string) []Completion {
+	if !strings.HasPrefix(label, c.prefix) {
+		return list
+	}
+	return append(list, Completion{
+		Label:  label,
+		Kind:   kind,
+		Detail: detail,
+		Doc:    doc,
+		Range:  c.prefixRange,
+	})
+}
+
+// functionSignature returns a description of the parameters of the given
+// function, such as "max(a number, b number)".
+func functionSignature(name string, f function.Function) string {
+	var params []string
+	for _, param := range f.Params() {
+		params = append(params, param.Name+" "+param.Type.FriendlyName())
+	}
+	if param := f.VarParam(); param != nil {
+		params = append(params, "..."+param.Name+" "+param.Type.FriendlyName())
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+}
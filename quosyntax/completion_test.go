@@ -0,0 +1,325 @@
+package quosyntax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestCompletions(t *testing.T) {
+	assetType := cty.Object(map[string]cty.Type{
+		"code":   cty.String,
+		"issuer": cty.String,
+	})
+	orderType := cty.Object(map[string]cty.Type{
+		"asset": assetType,
+		"price": quoty.Number,
+	})
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{
+			"asset":  assetType,
+			"orders": cty.List(orderType),
+			"price":  quoty.Number,
+		},
+		Functions: map[string]function.Function{
+			"max": function.New(&function.Spec{
+				VarParam: &function.Parameter{Name: "nums", Type: quoty.Number},
+				Type:     function.StaticReturnType(quoty.Number),
+			}),
+			"pow": function.New(&function.Spec{
+				Params: []function.Parameter{
+					{Name: "base", Type: quoty.Number},
+					{Name: "exp", Type: quoty.Number},
+				},
+				Type: function.StaticReturnType(quoty.Number),
+			}),
+		},
+	}
+	schema := func(blocks []*hcl.Block) *hcl.BodySchema {
+		if len(blocks) == 0 {
+			return &hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{
+					{Name: "name", Required: true},
+					{Name: "spread"},
+				},
+				Blocks: []hcl.BlockHeaderSchema{
+					{Type: "order", LabelNames: []string{"side"}},
+				},
+			}
+		}
+		if blocks[len(blocks)-1].Type == "order" {
+			return &hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{
+					{Name: "amount", Required: true},
+					{Name: "price"},
+				},
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		// input contains a "|" at the position to complete at, which is
+		// removed before parsing.
+		input string
+		want  []string
+	}{
+		{
+			"name = \"a\"\n|",
+			[]string{"order (block with labels side)", "spread (optional)"},
+		},
+		{
+			"sp|",
+			[]string{"spread (optional)"},
+		},
+		{
+			"order \"buy\" {\n  |\n}\n",
+			[]string{"amount (required)", "price (optional)"},
+		},
+		{
+			"order \"buy\" {\n  price = 1\n  pr|\n}\n",
+			nil,
+		},
+		{
+			"order \"buy\" {\n  pr|ice = 1\n}\n",
+			[]string{"price (optional)"},
+		},
+		{
+			"spread = |",
+			[]string{
+				"asset (variable object)",
+				"max (function max(...nums number))",
+				"orders (variable list of object)",
+				"pow (function pow(base number, exp number))",
+				"price (variable number)",
+			},
+		},
+		{
+			"spread = p|",
+			[]string{
+				"pow (function pow(base number, exp number))",
+				"price (variable number)",
+			},
+		},
+		{
+			"spread = max(price, pr|)",
+			[]string{"price (variable number)"},
+		},
+		{
+			"spread = asset.|",
+			[]string{"code (attribute string)", "issuer (attribute string)"},
+		},
+		{
+			"spread = asset.i|",
+			[]string{"issuer (attribute string)"},
+		},
+		{
+			"spread = orders[0].asset.|",
+			[]string{"code (attribute string)", "issuer (attribute string)"},
+		},
+		{
+			"spread = [for i, o in orders: o.|]",
+			[]string{"asset (attribute object)", "price (attribute number)"},
+		},
+		{
+			"spread = [for i, o in orders: o.price * |]",
+			[]string{
+				"asset (variable object)",
+				"i (for number)",
+				"max (function max(...nums number))",
+				"o (for object)",
+				"orders (variable list of object)",
+				"pow (function pow(base number, exp number))",
+				"price (variable number)",
+			},
+		},
+		{
+			"spread = [for asset in orders: asset.|]",
+			[]string{"asset (attribute object)", "price (attribute number)"},
+		},
+		{
+			"spread = [for o in orders: [for a in [o.asset]: a.|]]",
+			[]string{"code (attribute string)", "issuer (attribute string)"},
+		},
+		{
+			"spread = [for o in |orders: o]",
+			[]string{
+				"asset (variable object)",
+				"max (function max(...nums number))",
+				"orders (variable list of object)",
+				"pow (function pow(base number, exp number))",
+				"price (variable number)",
+			},
+		},
+		{
+			"spread = \"price |\"",
+			nil,
+		},
+		{
+			"spread = \"price ${p|}\"",
+			[]string{
+				"pow (function pow(base number, exp number))",
+				"price (variable number)",
+			},
+		},
+		{
+			"spread = { a = 1\n|}",
+			[]string{
+				"asset (variable object)",
+				"max (function max(...nums number))",
+				"orders (variable list of object)",
+				"pow (function pow(base number, exp number))",
+				"price (variable number)",
+			},
+		},
+		{
+			"spread = price.|",
+			nil,
+		},
+		{
+			"order |",
+			nil,
+		},
+	}
+
+	kinds := map[CompletionKind]string{
+		CompleteVariable:      "variable ",
+		CompleteForVariable:   "for ",
+		CompleteFunction:      "function ",
+		CompleteAttribute:     "attribute ",
+		CompleteBodyAttribute: "",
+		CompleteBlockType:     "",
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			offset := strings.Index(test.input, "|")
+			src := test.input[:offset] + test.input[offset+1:]
+			file, _ := ParseConfig([]byte(src), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			pos := posForOffset(src, offset)
+
+			var got []string
+			for _, c := range Completions(file, pos, ctx, nil, schema) {
+				got = append(got, c.Label+" ("+kinds[c.Kind]+c.Detail+")")
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompletionsRange(t *testing.T) {
+	src := "spread = asset.iss + 1"
+	file, _ := ParseConfig([]byte(src), "test.quo", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{
+			"asset": cty.Object(map[string]cty.Type{"issuer": cty.String}),
+		},
+	}
+
+	got := Completions(file, posForOffset(src, 17), ctx, nil, nil)
+	if len(got) != 1 {
+		t.Fatalf("wrong number of completions %d; want 1", len(got))
+	}
+	if rng := got[0].Range; src[rng.Start.Byte:rng.End.Byte] != "iss" {
+		t.Errorf("wrong range %q; want %q", src[rng.Start.Byte:rng.End.Byte], "iss")
+	}
+}
+
+func TestCompletionsStart(t *testing.T) {
+	// The file is a fragment of a larger document, so its positions don't
+	// start at the beginning.
+	src := "spread = [for o in orders: o.pr]"
+	start := hcl.Pos{Line: 5, Column: 3, Byte: 40}
+	file, _ := ParseConfig([]byte(src), "test.quo", start)
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{
+			"orders": cty.List(cty.Object(map[string]cty.Type{"price": quoty.Number})),
+		},
+	}
+
+	pos := hcl.Pos{Line: 5, Column: 3 + 31, Byte: 40 + 31}
+	got := Completions(file, pos, ctx, nil, nil)
+	if len(got) != 1 || got[0].Label != "price" {
+		t.Fatalf("wrong completions %#v; want price", got)
+	}
+	if rng := got[0].Range; rng.Start != (hcl.Pos{Line: 5, Column: 3 + 29, Byte: 40 + 29}) || rng.End != pos {
+		t.Errorf("wrong range %#v", rng)
+	}
+}
+
+func TestCompletionsOutsideFile(t *testing.T) {
+	src := "a = "
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{"asset": cty.String},
+	}
+
+	file, _ := ParseConfig([]byte(src), "test.quo", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if got := Completions(file, hcl.Pos{Line: 1, Column: 9, Byte: 8}, ctx, nil, nil); got != nil {
+		t.Errorf("wrong completions after the end: %#v; want none", got)
+	}
+	if got := Completions(file, hcl.Pos{Line: 1, Column: 5, Byte: 4}, ctx, nil, nil); len(got) != 1 || got[0].Label != "asset" {
+		t.Errorf("wrong completions at the end: %#v; want asset", got)
+	}
+
+	start := hcl.Pos{Line: 2, Column: 1, Byte: 4}
+	file, _ = ParseConfig([]byte(src), "test.quo", start)
+	if got := Completions(file, hcl.Pos{Line: 1, Column: 3, Byte: 2}, ctx, nil, nil); got != nil {
+		t.Errorf("wrong completions before the start: %#v; want none", got)
+	}
+	if got := Completions(file, hcl.Pos{Line: 2, Column: 5, Byte: 8}, ctx, nil, nil); len(got) != 1 || got[0].Label != "asset" {
+		t.Errorf("wrong completions at the end: %#v; want asset", got)
+	}
+}
+
+func TestCompletionsDoc(t *testing.T) {
+	src := "spread = var.\nlimit = va"
+	file, _ := ParseConfig([]byte(src), "test.quo", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{
+			"var": cty.Object(map[string]cty.Type{"max_spread": cty.Number}),
+		},
+	}
+	docs := map[string]string{
+		"var":            "Input variables.",
+		"var.max_spread": "The widest spread to quote.",
+	}
+
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{13, "The widest spread to quote."},
+		{24, "Input variables."},
+	}
+	for _, test := range tests {
+		got := Completions(file, posForOffset(src, test.offset), ctx.NewChild(), docs, nil)
+		if len(got) != 1 {
+			t.Fatalf("wrong number of completions %d at %d; want 1", len(got), test.offset)
+		}
+		if got[0].Doc != test.want {
+			t.Errorf("wrong doc at %d\ngot:  %q\nwant: %q", test.offset, got[0].Doc, test.want)
+		}
+	}
+}
+
+// posForOffset returns the position of the given byte offset in the given
+// source code.
+func posForOffset(src string, offset int) hcl.Pos {
+	pos := hcl.Pos{Line: 1, Column: 1, Byte: offset}
+	for _, r := range src[:offset] {
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
@@ -1,7 +1,16 @@
 package quosyntax
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
 	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
 )
 
 // setDiagEvalContext is an internal helper that will impose a particular
@@ -21,3 +30,264 @@ func setDiagEvalContext(diags hcl.Diagnostics, expr hcl.Expression, ctx *hcl.Eva
 		}
 	}
 }
+
+// argumentDiagnostic returns the diagnostic for the given error about the
+// value of the given argument of the given function call, which is either an
+// error converting a value of the given type or an error returned by the
+// function, in which case the given type is cty.NilType.
+func argumentDiagnostic(e *FunctionCallExpr, argExpr Expression, param *function.Parameter, given cty.Type, err error, ctx *hcl.EvalContext) *hcl.Diagnostic {
+	path, msg := errorPath(err)
+	subject := argExpr.StartRange()
+	subjectExpr := exprForPath(argExpr, path)
+	if subjectExpr != argExpr {
+		subject = subjectExpr.Range()
+	}
+
+	detail := fmt.Sprintf("Invalid value for %q parameter: %s.", param.Name, msg)
+	if len(path) != 0 {
+		detail = fmt.Sprintf("Invalid value for %q parameter at %s: %s.", param.Name, FormatPath(param.Name, path), msg)
+	} else {
+		detail = appendTypeDifference(detail, given, param.Type, "the given value", "the parameter type")
+	}
+
+	return &hcl.Diagnostic{
+		Severity:    hcl.DiagError,
+		Summary:     "Invalid function argument",
+		Detail:      detail,
+		Subject:     &subject,
+		Context:     e.Range().Ptr(),
+		Expression:  subjectExpr,
+		EvalContext: ctx,
+	}
+}
+
+// operandDiagnostic returns the diagnostic for the given error converting
+// the value of the given operand from the given type to the wanted type. The
+// operand is the left, right or unary operand of the operation with the given
+// range.
+func operandDiagnostic(which string, operand Expression, context hcl.Range, given, want cty.Type, err error, ctx *hcl.EvalContext) *hcl.Diagnostic {
+	path, msg := errorPath(err)
+	subjectExpr := exprForPath(operand, path)
+
+	detail := fmt.Sprintf("Unsuitable value for %s operand: %s.", which, msg)
+	if len(path) != 0 {
+		detail = fmt.Sprintf("Unsuitable value for %s operand at %s: %s.", which, FormatPath(exprPathBase(operand), path), msg)
+	} else {
+		detail = appendTypeDifference(detail, given, want, "the given value", "the operator's operand type")
+	}
+
+	return &hcl.Diagnostic{
+		Severity:    hcl.DiagError,
+		Summary:     "Invalid operand",
+		Detail:      detail,
+		Subject:     subjectExpr.Range().Ptr(),
+		Context:     &context,
+		Expression:  subjectExpr,
+		EvalContext: ctx,
+	}
+}
+
+// conditionalResultDiagnostic returns the diagnostic for the given error
+// converting the value of the given result, which is the true or false result
+// of the conditional expression with the given range.
+func conditionalResultDiagnostic(which string, result Expression, context hcl.Range, err error, ctx *hcl.EvalContext) *hcl.Diagnostic {
+	path, msg := errorPath(err)
+	subjectExpr := exprForPath(result, path)
+
+	detail := fmt.Sprintf("The %s result value has the wrong type: %s.", which, msg)
+	if len(path) != 0 {
+		detail = fmt.Sprintf("The %s result value has the wrong type at %s: %s.", which, FormatPath(exprPathBase(result), path), msg)
+	}
+
+	return &hcl.Diagnostic{
+		Severity:    hcl.DiagError,
+		Summary:     "Inconsistent conditional result types",
+		Detail:      detail,
+		Subject:     subjectExpr.Range().Ptr(),
+		Context:     &context,
+		Expression:  subjectExpr,
+		EvalContext: ctx,
+	}
+}
+
+// inconsistentResultsDetail returns the detail of the diagnostic for a
+// conditional expression whose results have the given types, which cannot be
+// unified.
+func inconsistentResultsDetail(trueType, falseType cty.Type) string {
+	const prefix = "The true and false result expressions must have consistent types."
+	if diff := quoty.TypeDifference(trueType, falseType, "the true result", "the false result"); diff != "" {
+		return prefix + " " + diff
+	}
+	return fmt.Sprintf(
+		prefix+" The given expressions are %s and %s, respectively.",
+		trueType.FriendlyName(), falseType.FriendlyName(),
+	)
+}
+
+// appendTypeDifference appends to the given detail a description of the
+// difference between the given types, if there is one. The given type may be
+// cty.NilType if it isn't known.
+//
+// The conversion error already says which type was required, so the
+// difference is only described if it is within the elements or attributes
+// of the types, or if it is between the number types, which the conversion
+// error doesn't distinguish.
+func appendTypeDifference(detail string, given, want cty.Type, givenName, wantName string) string {
+	if given == cty.NilType {
+		return detail
+	}
+	nested := sameShape(given, want)
+	numbers := !isContainerType(given) && !isContainerType(want) && (given.IsCapsuleType() || want.IsCapsuleType())
+	if !nested && !numbers {
+		return detail
+	}
+	if diff := quoty.TypeDifference(given, want, givenName, wantName); diff != "" {
+		return detail + " " + diff
+	}
+	return detail
+}
+
+func isContainerType(ty cty.Type) bool {
+	return ty.IsCollectionType() || ty.IsObjectType() || ty.IsTupleType()
+}
+
+// sameShape returns true if a value of the given type could be converted to
+// the wanted type as long as its elements or attributes can be converted.
+func sameShape(given, want cty.Type) bool {
+	switch {
+	case given.IsObjectType():
+		return want.IsObjectType() || want.IsMapType()
+	case given.IsTupleType():
+		return want.IsTupleType() || want.IsListType() || want.IsSetType()
+	case given.IsListType(), given.IsSetType():
+		return want.IsListType() || want.IsSetType()
+	case given.IsMapType():
+		return want.IsMapType() || want.IsObjectType()
+	default:
+		return false
+	}
+}
+
+// errorPath returns the path within a value that the given error relates to,
+// if it is or wraps a cty.PathError, along with the error message.
+//
+// The error returned by function.NewArgError doesn't expose the error it
+// wraps, so the path of an error that a function returns about one of its
+// arguments is only available if it used quofn.NewArgError instead.
+func errorPath(err error) (cty.Path, string) {
+	var pathErr cty.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Path, pathErr.Error()
+	}
+	return nil, err.Error()
+}
+
+// exprForPath returns the sub-expression of the given expression that
+// produces the part of its value at the given path, as far as it can be
+// determined from tuple and object constructors, or the given expression
+// itself if it isn't one of those.
+func exprForPath(expr Expression, path cty.Path) Expression {
+	for _, step := range path {
+		switch e := expr.(type) {
+		case *TupleConsExpr:
+			index, ok := step.(cty.IndexStep)
+			if !ok || index.Key.Type() != cty.Number || !index.Key.IsKnown() || index.Key.IsNull() {
+				return expr
+			}
+			i, accuracy := index.Key.AsBigFloat().Int64()
+			if accuracy != big.Exact || i < 0 || i >= int64(len(e.Exprs)) {
+				return expr
+			}
+			expr = e.Exprs[i]
+
+		case *ObjectConsExpr:
+			var name string
+			switch step := step.(type) {
+			case cty.GetAttrStep:
+				name = step.Name
+			case cty.IndexStep:
+				if step.Key.Type() != cty.String || !step.Key.IsKnown() || step.Key.IsNull() {
+					return expr
+				}
+				name = step.Key.AsString()
+			}
+			var item *ObjectConsItem
+			for i := range e.Items {
+				key, diags := e.Items[i].KeyExpr.Value(nil)
+				if !diags.HasErrors() && key.Type() == cty.String && key.IsKnown() && !key.IsNull() && key.AsString() == name {
+					item = &e.Items[i]
+				}
+			}
+			if item == nil {
+				return expr
+			}
+			expr = item.ValueExpr
+
+		default:
+			return expr
+		}
+	}
+	return expr
+}
+
+// exprPathBase returns the traversal that the given expression consists of,
+// for use as the base of a path within its value, or an empty string if it
+// isn't a traversal.
+func exprPathBase(expr Expression) string {
+	traversal, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() {
+		return ""
+	}
+	return FormatPath(traversal.RootName(), traversalPath(traversal[1:]))
+}
+
+// traversalPath returns the cty.Path equivalent to the given relative
+// traversal.
+func traversalPath(traversal hcl.Traversal) cty.Path {
+	var ret cty.Path
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseAttr:
+			ret = append(ret, cty.GetAttrStep{Name: step.Name})
+		case hcl.TraverseIndex:
+			ret = append(ret, cty.IndexStep{Key: step.Key})
+		}
+	}
+	return ret
+}
+
+// FormatPath returns the given path within a value as it would be written
+// in a traversal, after the given base, such as orders[3].price. The base
+// may be empty, such as for a path within the value of an expression.
+func FormatPath(base string, path cty.Path) string {
+	var buf strings.Builder
+	buf.WriteString(base)
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			if buf.Len() > 0 {
+				buf.WriteByte('.')
+			}
+			buf.WriteString(step.Name)
+		case cty.IndexStep:
+			key := step.Key
+			buf.WriteByte('[')
+			switch {
+			case !key.IsKnown() || key.IsNull():
+				buf.WriteString("...")
+			case key.Type() == cty.String:
+				buf.WriteString(strconv.Quote(key.AsString()))
+			case key.Type() == cty.Number:
+				buf.WriteString(key.AsBigFloat().Text('f', -1))
+			case key.Type().Equals(quoty.Number):
+				buf.WriteString(exactNumberString(key.EncapsulatedValue().(*big.Rat)))
+			default:
+				// Set elements are identified by their values, which
+				// may be arbitrarily complex.
+				buf.WriteString("...")
+			}
+			buf.WriteByte(']')
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,238 @@
+package quosyntax
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quofn"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestArgumentDiagnosticPath(t *testing.T) {
+	orderType := cty.Object(map[string]cty.Type{
+		"side":  cty.String,
+		"price": quoty.Number,
+	})
+	ordersFunc := func(newArgError func(i int, err error) error) function.Function {
+		return function.New(&function.Spec{
+			Params: []function.Parameter{
+				{Name: "orders", Type: cty.List(orderType)},
+			},
+			Type: function.StaticReturnType(cty.Bool),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				for it := args[0].ElementIterator(); it.Next(); {
+					i, order := it.Element()
+					if side := order.GetAttr("side").AsString(); side != "buy" && side != "sell" {
+						path := cty.Path{cty.IndexStep{Key: i}, cty.GetAttrStep{Name: "side"}}
+						return cty.NilVal, newArgError(0, path.NewErrorf("must be \"buy\" or \"sell\""))
+					}
+				}
+				return cty.True, nil
+			},
+		})
+	}
+	functions := map[string]function.Function{
+		"place":  ordersFunc(quofn.NewArgError),
+		"cancel": ordersFunc(function.NewArgError),
+	}
+	book := cty.TupleVal([]cty.Value{
+		cty.ObjectVal(map[string]cty.Value{
+			"side":  cty.StringVal("buy"),
+			"price": quoty.NumberIntVal(1),
+		}),
+		cty.ObjectVal(map[string]cty.Value{
+			"side":  cty.StringVal("sell"),
+			"price": cty.StringVal("high"),
+		}),
+	})
+
+	tests := []struct {
+		input        string
+		detail       string
+		subject      string
+		subjectStart int
+	}{
+		{
+			`place([{side = "buy", price = 1}, {side = "sell", price = "high"}])`,
+			`Invalid value for "orders" parameter at orders[1].price: a number is required.`,
+			`"high"`,
+			58,
+		},
+		{
+			`place([{side = "buy", price = 1}, {side = "hold", price = 2}])`,
+			`Invalid value for "orders" parameter at orders[1].side: must be "buy" or "sell".`,
+			`"hold"`,
+			42,
+		},
+		{
+			// The error returned by function.NewArgError doesn't expose
+			// its path, so this refers to the whole argument.
+			`cancel([{side = "buy", price = 1}, {side = "hold", price = 2}])`,
+			`Invalid value for "orders" parameter: must be "buy" or "sell".`,
+			`[`,
+			7,
+		},
+		{
+			`place([{"side" = "buy", price = 1}, {"side" = "sell", "price" = "high"}])`,
+			`Invalid value for "orders" parameter at orders[1].price: a number is required.`,
+			`"high"`,
+			64,
+		},
+		{
+			`place(book)`,
+			`Invalid value for "orders" parameter at orders[1].price: a number is required.`,
+			`book`,
+			6,
+		},
+		{
+			`place("buy")`,
+			`Invalid value for "orders" parameter: list of object required.`,
+			`buy`,
+			7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics: %s", parseDiags.Error())
+			}
+
+			check := func(diags hcl.Diagnostics) {
+				t.Helper()
+				if len(diags) != 1 {
+					t.Fatalf("wrong number of diagnostics %d; want 1\n%s", len(diags), diags.Error())
+				}
+				if got := diags[0].Detail; got != test.detail {
+					t.Errorf("wrong detail\ngot:  %s\nwant: %s", got, test.detail)
+				}
+				rng := diags[0].Subject
+				if rng.Start.Byte != test.subjectStart || rng.End.Byte != test.subjectStart+len(test.subject) {
+					t.Errorf("wrong subject %q at %d; want %q at %d", test.input[rng.Start.Byte:rng.End.Byte], rng.Start.Byte, test.subject, test.subjectStart)
+				}
+			}
+
+			_, diags := expr.Value(&hcl.EvalContext{
+				Variables: map[string]cty.Value{"book": book},
+				Functions: functions,
+			})
+			check(diags)
+
+			compiled := Compile(expr, &CompileScope{
+				Variables: []string{"book"},
+				Functions: functions,
+			})
+			_, diags = compiled.Value([]cty.Value{book})
+			check(diags)
+		})
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	tests := []struct {
+		base string
+		path cty.Path
+		want string
+	}{
+		{"orders", nil, "orders"},
+		{"orders", cty.Path{cty.IndexStep{Key: cty.NumberIntVal(3)}, cty.GetAttrStep{Name: "price"}}, "orders[3].price"},
+		{"", cty.Path{cty.GetAttrStep{Name: "price"}}, "price"},
+		{"", cty.Path{cty.IndexStep{Key: cty.NumberIntVal(0)}}, "[0]"},
+		{"assets", cty.Path{cty.IndexStep{Key: cty.StringVal("XLM")}}, `assets["XLM"]`},
+		{"sides", cty.Path{cty.IndexStep{Key: cty.ObjectVal(map[string]cty.Value{"a": cty.True})}}, "sides[...]"},
+	}
+
+	for _, test := range tests {
+		if got := FormatPath(test.base, test.path); got != test.want {
+			t.Errorf("wrong result for %#v %#v\ngot:  %s\nwant: %s", test.base, test.path, got, test.want)
+		}
+	}
+}
+
+func TestTypeDifferenceDiagnostics(t *testing.T) {
+	orderType := cty.Object(map[string]cty.Type{
+		"side":  cty.String,
+		"price": quoty.Number,
+	})
+	functions := map[string]function.Function{
+		"place": function.New(&function.Spec{
+			Params: []function.Parameter{
+				{Name: "orders", Type: cty.List(orderType)},
+			},
+			Type: function.StaticReturnType(cty.Bool),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				return cty.True, nil
+			},
+		}),
+	}
+	vars := map[string]cty.Value{
+		"amount": quoty.StellarAssetAmountVal(1),
+		"orders": cty.ListVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{
+				"side":  cty.StringVal("buy"),
+				"price": quoty.NumberIntVal(1),
+			}),
+		}),
+	}
+
+	tests := []struct {
+		input  string
+		detail string
+	}{
+		{
+			`true ? {orders = [{price = 1}]} : {orders = [{price = [1]}]}`,
+			`The true and false result expressions must have consistent types. Attribute "price" of element 0 of attribute "orders" is a number in the true result, but a tuple in the false result.`,
+		},
+		{
+			`true ? orders : {side = "buy"}`,
+			`The true and false result expressions must have consistent types. The true result is a list, but the false result is an object.`,
+		},
+		{
+			`place({side = "buy", price = 1})`,
+			`Invalid value for "orders" parameter: list of object required.`,
+		},
+		{
+			`place([{side = "buy"}])`,
+			`Invalid value for "orders" parameter: element 0: attribute "price" is required. The parameter type has attribute "price" in element 0, but the given value does not.`,
+		},
+		{
+			`-amount`,
+			`Unsuitable value for unary operand: number required. The given value is a Stellar asset amount, but the operator's operand type is a floating-point number.`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics: %s", parseDiags.Error())
+			}
+
+			check := func(diags hcl.Diagnostics) {
+				t.Helper()
+				if len(diags) != 1 {
+					t.Fatalf("wrong number of diagnostics %d; want 1\n%s", len(diags), diags.Error())
+				}
+				if got := diags[0].Detail; got != test.detail {
+					t.Errorf("wrong detail\ngot:  %s\nwant: %s", got, test.detail)
+				}
+			}
+
+			_, diags := expr.Value(&hcl.EvalContext{
+				Variables: vars,
+				Functions: functions,
+			})
+			check(diags)
+
+			compiled := Compile(expr, &CompileScope{
+				Variables: []string{"amount", "orders"},
+				Functions: functions,
+			})
+			_, diags = compiled.Value([]cty.Value{vars["amount"], vars["orders"]})
+			check(diags)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package quosyntax
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Doc is the documentation of an attribute or block, given by the comments
+// around it.
+type Doc struct {
+	// Leading are the comments on the lines directly above the item, with
+	// no blank line between them and the item, along with any inline
+	// comments before the item on its first line.
+	Leading Tokens
+
+	// Trailing are the comments after the item on its last line.
+	Trailing Tokens
+}
+
+// Text returns the text of the leading and then the trailing comments with
+// their comment markers removed, or the empty string if the receiver is nil.
+func (d *Doc) Text() string {
+	if d == nil {
+		return ""
+	}
+	var lines []string
+	for _, tok := range append(append(Tokens(nil), d.Leading...), d.Trailing...) {
+		lines = append(lines, commentLines(tok.Bytes)...)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// commentLines returns the lines of text of the given comment, without its
+// comment markers.
+func commentLines(src []byte) []string {
+	text := string(bytes.TrimRight(src, "\r\n"))
+	switch {
+	case strings.HasPrefix(text, "#"):
+		return []string{strings.TrimSpace(text[1:])}
+	case strings.HasPrefix(text, "//"):
+		return []string{strings.TrimSpace(text[2:])}
+	}
+
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		// Block comments are often written with a "*" at the start of each
+		// line, which isn't part of the text.
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimSpace(line[1:])
+		}
+		lines[i] = line
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// attachDocs sets the Doc field of each of the attributes and blocks within
+// the given body, including those in nested blocks, from the comments among
+// the given tokens.
+func attachDocs(body *Body, tokens Tokens) {
+	for _, item := range bodyItems(body) {
+		switch item := item.(type) {
+		case *Attribute:
+			item.Doc = itemDoc(item, tokens)
+		case *Block:
+			item.Doc = itemDoc(item, tokens)
+			if item.Body != nil {
+				attachDocs(item.Body, tokens)
+			}
+		}
+	}
+}
+
+// itemDoc returns the documentation of the given attribute or block from
+// the comments among the given tokens, or nil if there are none.
+func itemDoc(item Node, tokens Tokens) *Doc {
+	rng := item.Range()
+	first := sort.Search(len(tokens), func(i int) bool {
+		return tokens[i].Range.Start.Byte >= rng.Start.Byte
+	})
+	after := sort.Search(len(tokens), func(i int) bool {
+		return tokens[i].Range.Start.Byte >= rng.End.Byte
+	})
+
+	// The leading comments are found by walking backwards over comments
+	// until we reach a blank line or some other token. A line comment
+	// absorbs the newline that ends it, so a newline token after one means
+	// there is a blank line, but a block comment may be followed by a single
+	// newline.
+	start := first
+	for {
+		i := start - 1
+		if i >= 1 && tokens[i].Type == TokenNewline && tokens[i-1].Type == TokenComment && !isLineComment(tokens[i-1]) {
+			i--
+		}
+		if i < 0 || tokens[i].Type != TokenComment {
+			break
+		}
+		start = i
+	}
+	var leading Tokens
+	for i := start; i < first; i++ {
+		// Comments that follow something else on the same line, such as
+		// the trailing comment of the item before, aren't part of the doc.
+		if len(leading) == 0 && i > 0 && !endsLineToken(tokens[i-1]) {
+			continue
+		}
+		if tokens[i].Type == TokenComment {
+			leading = append(leading, tokens[i])
+		}
+	}
+
+	var trailing Tokens
+	for i := after; i < len(tokens) && tokens[i].Type == TokenComment; i++ {
+		trailing = append(trailing, tokens[i])
+		if isLineComment(tokens[i]) {
+			break
+		}
+	}
+
+	if len(leading) == 0 && len(trailing) == 0 {
+		return nil
+	}
+	return &Doc{
+		Leading:  leading,
+		Trailing: trailing,
+	}
+}
+
+// endsLineToken returns true if the given token is a newline or a line
+// comment, which absorbs the newline that ends it.
+func endsLineToken(tok Token) bool {
+	return tok.Type == TokenNewline || (tok.Type == TokenComment && isLineComment(tok))
+}
+
+// isLineComment returns true if the given comment token is a "#" or "//"
+// comment, rather than a "/* */" comment.
+func isLineComment(tok Token) bool {
+	return !bytes.HasPrefix(tok.Bytes, []byte("/*"))
+}
+
+// AttributeDocs returns the text of the doc comments of each attribute of
+// the body that has any, keyed by the given root name and the attribute
+// name separated by a ".", such as "var.max_spread". This is the form of
+// the docs given to Completions, for a body whose attributes are exposed to
+// expressions as the attributes of the given root variable.
+func (b *Body) AttributeDocs(root string) map[string]string {
+	ret := make(map[string]string)
+	for name, attr := range b.Attributes {
+		if text := attr.Doc.Text(); text != "" {
+			ret[root+"."+name] = text
+		}
+	}
+	return ret
+}
@@ -0,0 +1,65 @@
+package quosyntax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestAttachDocs(t *testing.T) {
+	cfg := `# Generated grid levels
+
+# The grid strategy.
+// Quotes both sides.
+strategy "grid" { # not a doc
+  /* The spread
+   * around the mid price. */
+  spread = 0.5 // half
+
+  /* inline */ skew = 0
+  leg "buy" {
+    size = 10 /* lots */ /* each */
+  }
+}
+level_1 = 1 # first
+# second
+level_2 = 2
+level_3 = 3
+`
+	file, diags := ParseFile([]byte(cfg), "", hcl.Pos{Byte: 0, Line: 1, Column: 1})
+	if len(diags) != 0 {
+		t.Fatalf("Unexpected diagnostics: %s", diags)
+	}
+
+	got := make(map[string]string)
+	VisitAll(file.Body, func(node Node) hcl.Diagnostics {
+		switch node := node.(type) {
+		case *Attribute:
+			got[node.Name] = node.Doc.Text()
+		case *Block:
+			got[node.Type] = node.Doc.Text()
+		}
+		return nil
+	})
+	want := map[string]string{
+		"strategy": "The grid strategy.\nQuotes both sides.",
+		"spread":   "The spread\naround the mid price.\nhalf",
+		"skew":     "inline",
+		"leg":      "",
+		"size":     "lots\neach",
+		"level_1":  "first",
+		"level_2":  "second",
+		"level_3":  "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong docs\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if doc := file.Body.Attributes["level_3"].Doc; doc != nil {
+		t.Errorf("unexpected doc for level_3: %#v", doc)
+	}
+	if got, want := file.Body.AttributeDocs("var"), map[string]string{"var.level_1": "first", "var.level_2": "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong attribute docs\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
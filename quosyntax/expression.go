@@ -1,8 +1,8 @@
 package quosyntax
 
 import (
+	"errors"
 	"fmt"
-	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/quomproject/quolang/quoty"
@@ -38,6 +38,10 @@ func (e *LiteralValueExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *LiteralValueExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *LiteralValueExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	return e.Val, nil
 }
 
@@ -106,7 +110,17 @@ func (e *ScopeTraversalExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *ScopeTraversalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	val, diags := e.Traversal.TraverseAbs(ctx)
+	return evaluate(e, ctx)
+}
+
+func (e *ScopeTraversalExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	var val cty.Value
+	var diags hcl.Diagnostics
+	if root, ok := fr.root(e, ctx); ok {
+		val, diags = traverseRel(e.Traversal[1:], root)
+	} else {
+		val, diags = traverseAbs(e.Traversal, ctx)
+	}
 	setDiagEvalContext(diags, e, ctx)
 	return val, diags
 }
@@ -137,8 +151,12 @@ func (e *RelativeTraversalExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *RelativeTraversalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	src, diags := e.Source.Value(ctx)
-	ret, travDiags := e.Traversal.TraverseRel(src)
+	return evaluate(e, ctx)
+}
+
+func (e *RelativeTraversalExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	src, diags := fr.eval(e.Source, ctx)
+	ret, travDiags := traverseRel(e.Traversal, src)
 	setDiagEvalContext(travDiags, e, ctx)
 	diags = append(diags, travDiags...)
 	return ret, diags
@@ -188,8 +206,12 @@ func (e *FunctionCallExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	var diags hcl.Diagnostics
+	return evaluate(e, ctx)
+}
 
+// function returns the function that the call refers to in the given
+// EvalContext, or diagnostics if there is no such function.
+func (e *FunctionCallExpr) function(ctx *hcl.EvalContext) (function.Function, hcl.Diagnostics) {
 	var f function.Function
 	exists := false
 	hasNonNilMap := false
@@ -209,7 +231,7 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 
 	if !exists {
 		if !hasNonNilMap {
-			return cty.DynamicVal, hcl.Diagnostics{
+			return f, hcl.Diagnostics{
 				{
 					Severity:    hcl.DiagError,
 					Summary:     "Function calls not allowed",
@@ -221,16 +243,12 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 			}
 		}
 
-		avail := make([]string, 0, len(ctx.Functions))
-		for name := range ctx.Functions {
-			avail = append(avail, name)
-		}
-		suggestion := nameSuggestion(e.Name, avail)
+		suggestion := quoty.NameSuggestion(e.Name, functionNames(ctx))
 		if suggestion != "" {
 			suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
 		}
 
-		return cty.DynamicVal, hcl.Diagnostics{
+		return f, hcl.Diagnostics{
 			{
 				Severity:    hcl.DiagError,
 				Summary:     "Call to unknown function",
@@ -243,8 +261,27 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 		}
 	}
 
-	params := f.Params()
-	varParam := f.VarParam()
+
+	return f, nil
+}
+
+func (e *FunctionCallExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	var f function.Function
+	var params []function.Parameter
+	var varParam *function.Parameter
+	if call := fr.call(e); call != nil {
+		f, params, varParam = call.f, call.params, call.varParam
+	} else {
+		var lookupDiags hcl.Diagnostics
+		f, lookupDiags = e.function(ctx)
+		if lookupDiags != nil {
+			return cty.DynamicVal, lookupDiags
+		}
+		params = f.Params()
+		varParam = f.VarParam()
+	}
 
 	args := e.Args
 	if e.ExpandFinal {
@@ -253,7 +290,7 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 			panic("ExpandFinal set on function call with no arguments")
 		}
 		expandExpr := args[len(args)-1]
-		expandVal, expandDiags := expandExpr.Value(ctx)
+		expandVal, expandDiags := fr.eval(expandExpr, ctx)
 		diags = append(diags, expandDiags...)
 		if expandDiags.HasErrors() {
 			return cty.DynamicVal, diags
@@ -351,26 +388,16 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 			param = varParam
 		}
 
-		val, argDiags := argExpr.Value(ctx)
+		val, argDiags := fr.eval(argExpr, ctx)
 		if len(argDiags) > 0 {
 			diags = append(diags, argDiags...)
 		}
 
 		// Try to convert our value to the parameter type
-		val, err := convert.Convert(val, param.Type)
+		givenType := val.Type()
+		val, err := fr.convert(argExpr, val, param.Type)
 		if err != nil {
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  "Invalid function argument",
-				Detail: fmt.Sprintf(
-					"Invalid value for %q parameter: %s.",
-					param.Name, err,
-				),
-				Subject:     argExpr.StartRange().Ptr(),
-				Context:     e.Range().Ptr(),
-				Expression:  argExpr,
-				EvalContext: ctx,
-			})
+			diags = append(diags, argumentDiagnostic(e, argExpr, param, givenType, err, ctx))
 		}
 
 		argVals[i] = val
@@ -385,9 +412,9 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 
 	resultVal, err := f.Call(argVals)
 	if err != nil {
-		switch terr := err.(type) {
-		case function.ArgError:
-			i := terr.Index
+		var argErr function.ArgError
+		if errors.As(err, &argErr) {
+			i := argErr.Index
 			var param *function.Parameter
 			if i < len(params) {
 				param = &params[i]
@@ -396,22 +423,11 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 			}
 			argExpr := e.Args[i]
 
-			// TODO: we should also unpick a PathError here and show the
-			// path to the deep value where the error was detected.
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  "Invalid function argument",
-				Detail: fmt.Sprintf(
-					"Invalid value for %q parameter: %s.",
-					param.Name, err,
-				),
-				Subject:     argExpr.StartRange().Ptr(),
-				Context:     e.Range().Ptr(),
-				Expression:  argExpr,
-				EvalContext: ctx,
-			})
-
-		default:
+			// The diagnostic shows the path to the deep value where the
+			// error was detected, if the function returned a cty.PathError
+			// using quofn.NewArgError.
+			diags = append(diags, argumentDiagnostic(e, argExpr, param, cty.NilType, err, ctx))
+		} else {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
 				Summary:  "Error in function call",
@@ -429,6 +445,9 @@ func (e *FunctionCallExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 		return cty.DynamicVal, diags
 	}
 
+	resultVal, precisionDiags := applyPrecision(fr.precision(), e, ctx, resultVal)
+	diags = append(diags, precisionDiags...)
+
 	return resultVal, diags
 }
 
@@ -440,6 +459,17 @@ func (e *FunctionCallExpr) StartRange() hcl.Range {
 	return hcl.RangeBetween(e.NameRange, e.OpenParenRange)
 }
 
+// ArgIndexAtPos returns the index of the argument whose range contains the
+// given position, or -1 if the position is not within any argument.
+func (e *FunctionCallExpr) ArgIndexAtPos(pos hcl.Pos) int {
+	for i, arg := range e.Args {
+		if arg.Range().ContainsPos(pos) {
+			return i
+		}
+	}
+	return -1
+}
+
 // Implementation for hcl.ExprCall.
 func (e *FunctionCallExpr) ExprCall() *hcl.StaticCall {
 	ret := &hcl.StaticCall{
@@ -470,8 +500,12 @@ func (e *ConditionalExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *ConditionalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	trueResult, trueDiags := e.TrueResult.Value(ctx)
-	falseResult, falseDiags := e.FalseResult.Value(ctx)
+	return evaluate(e, ctx)
+}
+
+func (e *ConditionalExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	trueResult, trueDiags := fr.eval(e.TrueResult, ctx)
+	falseResult, falseDiags := fr.eval(e.FalseResult, ctx)
 	var diags hcl.Diagnostics
 
 	resultType := cty.DynamicPseudoType
@@ -508,14 +542,7 @@ func (e *ConditionalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostic
 			{
 				Severity: hcl.DiagError,
 				Summary:  "Inconsistent conditional result types",
-				Detail: fmt.Sprintf(
-					// FIXME: Need a helper function for showing natural-language type diffs,
-					// since this will generate some useless messages in some cases, like
-					// "These expressions are object and object respectively" if the
-					// object types don't exactly match.
-					"The true and false result expressions must have consistent types. The given expressions are %s and %s, respectively.",
-					trueResult.Type().FriendlyName(), falseResult.Type().FriendlyName(),
-				),
+				Detail:      inconsistentResultsDetail(trueResult.Type(), falseResult.Type()),
 				Subject:     hcl.RangeBetween(e.TrueResult.Range(), e.FalseResult.Range()).Ptr(),
 				Context:     &e.SrcRange,
 				Expression:  e,
@@ -524,7 +551,7 @@ func (e *ConditionalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostic
 		}
 	}
 
-	condResult, condDiags := e.Condition.Value(ctx)
+	condResult, condDiags := fr.eval(e.Condition, ctx)
 	diags = append(diags, condDiags...)
 	if condResult.IsNull() {
 		diags = append(diags, &hcl.Diagnostic{
@@ -562,18 +589,7 @@ func (e *ConditionalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostic
 			trueResult, err = convs[0](trueResult)
 			if err != nil {
 				// Unsafe conversion failed with the concrete result value
-				diags = append(diags, &hcl.Diagnostic{
-					Severity: hcl.DiagError,
-					Summary:  "Inconsistent conditional result types",
-					Detail: fmt.Sprintf(
-						"The true result value has the wrong type: %s.",
-						err.Error(),
-					),
-					Subject:     e.TrueResult.Range().Ptr(),
-					Context:     &e.SrcRange,
-					Expression:  e.TrueResult,
-					EvalContext: ctx,
-				})
+				diags = append(diags, conditionalResultDiagnostic("true", e.TrueResult, e.SrcRange, err, ctx))
 				trueResult = cty.UnknownVal(resultType)
 			}
 		}
@@ -585,18 +601,7 @@ func (e *ConditionalExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostic
 			falseResult, err = convs[1](falseResult)
 			if err != nil {
 				// Unsafe conversion failed with the concrete result value
-				diags = append(diags, &hcl.Diagnostic{
-					Severity: hcl.DiagError,
-					Summary:  "Inconsistent conditional result types",
-					Detail: fmt.Sprintf(
-						"The false result value has the wrong type: %s.",
-						err.Error(),
-					),
-					Subject:     e.FalseResult.Range().Ptr(),
-					Context:     &e.SrcRange,
-					Expression:  e.FalseResult,
-					EvalContext: ctx,
-				})
+				diags = append(diags, conditionalResultDiagnostic("false", e.FalseResult, e.SrcRange, err, ctx))
 				falseResult = cty.UnknownVal(resultType)
 			}
 		}
@@ -626,9 +631,13 @@ func (e *IndexExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *IndexExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *IndexExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
-	coll, collDiags := e.Collection.Value(ctx)
-	key, keyDiags := e.Key.Value(ctx)
+	coll, collDiags := fr.eval(e.Collection, ctx)
+	key, keyDiags := fr.eval(e.Key, ctx)
 	diags = append(diags, collDiags...)
 	diags = append(diags, keyDiags...)
 
@@ -660,12 +669,16 @@ func (e *TupleConsExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *TupleConsExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *TupleConsExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	var vals []cty.Value
 	var diags hcl.Diagnostics
 
 	vals = make([]cty.Value, len(e.Exprs))
 	for i, expr := range e.Exprs {
-		val, valDiags := expr.Value(ctx)
+		val, valDiags := fr.eval(expr, ctx)
 		vals[i] = val
 		diags = append(diags, valDiags...)
 	}
@@ -710,6 +723,10 @@ func (e *ObjectConsExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *ObjectConsExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *ObjectConsExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	var vals map[string]cty.Value
 	var diags hcl.Diagnostics
 
@@ -725,10 +742,10 @@ func (e *ObjectConsExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics
 
 	vals = make(map[string]cty.Value, len(e.Items))
 	for _, item := range e.Items {
-		key, keyDiags := item.KeyExpr.Value(ctx)
+		key, keyDiags := fr.eval(item.KeyExpr, ctx)
 		diags = append(diags, keyDiags...)
 
-		val, valDiags := item.ValueExpr.Value(ctx)
+		val, valDiags := fr.eval(item.ValueExpr, ctx)
 		diags = append(diags, valDiags...)
 
 		if keyDiags.HasErrors() {
@@ -750,7 +767,7 @@ func (e *ObjectConsExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics
 		}
 
 		var err error
-		key, err = convert.Convert(key, cty.String)
+		key, err = quoty.ToString(key)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity:    hcl.DiagError,
@@ -827,6 +844,10 @@ func (e *ObjectConsKeyExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *ObjectConsKeyExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *ObjectConsKeyExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	// Because we accept a naked identifier as a literal key rather than a
 	// reference, it's confusing to accept a traversal containing periods
 	// here since we can't tell if the user intends to create a key with
@@ -852,7 +873,7 @@ func (e *ObjectConsKeyExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnost
 			return cty.StringVal(ln), nil
 		}
 	}
-	return e.Wrapped.Value(ctx)
+	return fr.eval(e.Wrapped, ctx)
 }
 
 func (e *ObjectConsKeyExpr) Range() hcl.Range {
@@ -904,12 +925,22 @@ type ForExpr struct {
 	SrcRange   hcl.Range
 	OpenRange  hcl.Range
 	CloseRange hcl.Range
+
+	// KeyVarRange and ValVarRange are the ranges of the declarations of
+	// KeyVar and ValVar. KeyVarRange is the zero range if KeyVar is empty.
+	KeyVarRange hcl.Range
+	ValVarRange hcl.Range
 }
 
 func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *ForExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
+	b := fr.budgetRun()
 
-	collVal, collDiags := e.CollExpr.Value(ctx)
+	collVal, collDiags := fr.eval(e.CollExpr, ctx)
 	diags = append(diags, collDiags...)
 
 	if collVal.IsNull() {
@@ -958,7 +989,7 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 		}
 		childCtx.Variables[e.ValVar] = cty.DynamicVal
 
-		result, condDiags := e.CondExpr.Value(childCtx)
+		result, condDiags := fr.eval(e.CondExpr, childCtx)
 		diags = append(diags, condDiags...)
 		if result.IsNull() {
 			diags = append(diags, &hcl.Diagnostic{
@@ -1004,6 +1035,12 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 
 		known := true
 		for it.Next() {
+			if b.interrupted() {
+				// Evaluation was stopped while evaluating a previous
+				// element, so there's no point in continuing.
+				return cty.DynamicVal, diags
+			}
+
 			k, v := it.Element()
 			childCtx := ctx.NewChild()
 			childCtx.Variables = map[string]cty.Value{}
@@ -1013,7 +1050,7 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 			childCtx.Variables[e.ValVar] = v
 
 			if e.CondExpr != nil {
-				includeRaw, condDiags := e.CondExpr.Value(childCtx)
+				includeRaw, condDiags := fr.eval(e.CondExpr, childCtx)
 				diags = append(diags, condDiags...)
 				if includeRaw.IsNull() {
 					if known {
@@ -1057,7 +1094,7 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 				}
 			}
 
-			keyRaw, keyDiags := e.KeyExpr.Value(childCtx)
+			keyRaw, keyDiags := fr.eval(e.KeyExpr, childCtx)
 			diags = append(diags, keyDiags...)
 			if keyRaw.IsNull() {
 				if known {
@@ -1079,7 +1116,7 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 				continue
 			}
 
-			key, err := convert.Convert(keyRaw, cty.String)
+			key, err := quoty.ToString(keyRaw)
 			if err != nil {
 				if known {
 					diags = append(diags, &hcl.Diagnostic{
@@ -1096,12 +1133,15 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 				continue
 			}
 
-			val, valDiags := e.ValExpr.Value(childCtx)
+			val, valDiags := fr.eval(e.ValExpr, childCtx)
 			diags = append(diags, valDiags...)
 
 			if e.Group {
 				k := key.AsString()
 				groupVals[k] = append(groupVals[k], val)
+				if stopDiags := b.checkLength(e, ctx, len(groupVals)); stopDiags != nil {
+					return cty.DynamicVal, append(diags, stopDiags...)
+				}
 			} else {
 				k := key.AsString()
 				if _, exists := vals[k]; exists {
@@ -1119,6 +1159,9 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 					})
 				} else {
 					vals[key.AsString()] = val
+					if stopDiags := b.checkLength(e, ctx, len(vals)); stopDiags != nil {
+						return cty.DynamicVal, append(diags, stopDiags...)
+					}
 				}
 			}
 		}
@@ -1144,6 +1187,12 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 
 		known := true
 		for it.Next() {
+			if b.interrupted() {
+				// Evaluation was stopped while evaluating a previous
+				// element, so there's no point in continuing.
+				return cty.DynamicVal, diags
+			}
+
 			k, v := it.Element()
 			childCtx := ctx.NewChild()
 			childCtx.Variables = map[string]cty.Value{}
@@ -1153,7 +1202,7 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 			childCtx.Variables[e.ValVar] = v
 
 			if e.CondExpr != nil {
-				includeRaw, condDiags := e.CondExpr.Value(childCtx)
+				includeRaw, condDiags := fr.eval(e.CondExpr, childCtx)
 				diags = append(diags, condDiags...)
 				if includeRaw.IsNull() {
 					if known {
@@ -1201,9 +1250,12 @@ func (e *ForExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 				}
 			}
 
-			val, valDiags := e.ValExpr.Value(childCtx)
+			val, valDiags := fr.eval(e.ValExpr, childCtx)
 			diags = append(diags, valDiags...)
 			vals = append(vals, val)
+			if stopDiags := b.checkLength(e, ctx, len(vals)); stopDiags != nil {
+				return cty.DynamicVal, append(diags, stopDiags...)
+			}
 		}
 
 		if !known {
@@ -1261,13 +1313,17 @@ type SplatExpr struct {
 }
 
 func (e *SplatExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	sourceVal, diags := e.Source.Value(ctx)
+	return evaluate(e, ctx)
+}
+
+func (e *SplatExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	sourceVal, diags := fr.eval(e.Source, ctx)
 	if diags.HasErrors() {
 		// We'll evaluate our "Each" expression here just to see if it
 		// produces any more diagnostics we can report. Since we're not
 		// assigning a value to our AnonSymbolExpr here it will return
 		// DynamicVal, which should short-circuit any use of it.
-		_, itemDiags := e.Item.Value(ctx)
+		_, itemDiags := fr.eval(e.Item, ctx)
 		diags = append(diags, itemDiags...)
 		return cty.DynamicVal, diags
 	}
@@ -1310,24 +1366,19 @@ func (e *SplatExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 	// We'll compute our result type lazily if we need it. In the normal case
 	// it's inferred automatically from the value we construct.
 	resultTy := func() (cty.Type, hcl.Diagnostics) {
-		chiCtx := ctx.NewChild()
 		var diags hcl.Diagnostics
 		switch {
 		case sourceTy.IsListType() || sourceTy.IsSetType():
 			ety := sourceTy.ElementType()
-			e.Item.setValue(chiCtx, cty.UnknownVal(ety))
-			val, itemDiags := e.Each.Value(chiCtx)
+			val, itemDiags := e.eachValue(ctx, fr, cty.UnknownVal(ety))
 			diags = append(diags, itemDiags...)
-			e.Item.clearValue(chiCtx) // clean up our temporary value
 			return cty.List(val.Type()), diags
 		case sourceTy.IsTupleType():
 			etys := sourceTy.TupleElementTypes()
 			resultTys := make([]cty.Type, 0, len(etys))
 			for _, ety := range etys {
-				e.Item.setValue(chiCtx, cty.UnknownVal(ety))
-				val, itemDiags := e.Each.Value(chiCtx)
+				val, itemDiags := e.eachValue(ctx, fr, cty.UnknownVal(ety))
 				diags = append(diags, itemDiags...)
-				e.Item.clearValue(chiCtx) // clean up our temporary value
 				resultTys = append(resultTys, val.Type())
 			}
 			return cty.Tuple(resultTys), diags
@@ -1346,25 +1397,23 @@ func (e *SplatExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 		return cty.UnknownVal(ty), diags
 	}
 
+	b := fr.budgetRun()
 	vals := make([]cty.Value, 0, sourceVal.LengthInt())
 	it := sourceVal.ElementIterator()
-	if ctx == nil {
-		// we need a context to use our AnonSymbolExpr, so we'll just
-		// make an empty one here to use as a placeholder.
-		ctx = ctx.NewChild()
-	}
 	isKnown := true
 	for it.Next() {
+		if b.interrupted() {
+			return cty.DynamicVal, diags
+		}
+
 		_, sourceItem := it.Element()
-		e.Item.setValue(ctx, sourceItem)
-		newItem, itemDiags := e.Each.Value(ctx)
+		newItem, itemDiags := e.eachValue(ctx, fr, sourceItem)
 		diags = append(diags, itemDiags...)
 		if itemDiags.HasErrors() {
 			isKnown = false
 		}
 		vals = append(vals, newItem)
 	}
-	e.Item.clearValue(ctx) // clean up our temporary value
 
 	if !isKnown {
 		// We'll ingore the resultTy diagnostics in this case since they
@@ -1386,6 +1435,15 @@ func (e *SplatExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
 	}
 }
 
+// eachValue evaluates the Each expression with the given value for the item.
+//
+// The value is bound in a copy of the given frame that is used only for this
+// evaluation, so concurrent evaluations of the same expression never observe
+// each other's items, even when given the same context.
+func (e *SplatExpr) eachValue(ctx *hcl.EvalContext, fr *evalFrame, item cty.Value) (cty.Value, hcl.Diagnostics) {
+	return fr.withItem(e.Item, item).eval(e.Each, ctx)
+}
+
 func (e *SplatExpr) walkChildNodes(w internalWalkFunc) {
 	w(e.Source)
 	w(e.Each)
@@ -1410,59 +1468,24 @@ func (e *SplatExpr) StartRange() hcl.Range {
 // directly then cty.DynamicVal will be returned. Instead, it is evaluated
 // in terms of another node (i.e. a splat expression) which temporarily
 // assigns it a value.
+//
+// The value is bound only for the evaluation of the splat expression that
+// assigns it, rather than stored in the expression itself, so it is safe to
+// evaluate the same expression concurrently, with the same or with different
+// EvalContexts.
 type AnonSymbolExpr struct {
 	SrcRange hcl.Range
-
-	// values and its associated lock are used to isolate concurrent
-	// evaluations of a symbol from one another. It is the calling application's
-	// responsibility to ensure that the same splat expression is not evalauted
-	// concurrently within the _same_ EvalContext, but it is fine and safe to
-	// do cuncurrent evaluations with distinct EvalContexts.
-	values     map[*hcl.EvalContext]cty.Value
-	valuesLock sync.RWMutex
 }
 
 func (e *AnonSymbolExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	if ctx == nil {
-		return cty.DynamicVal, nil
-	}
-
-	e.valuesLock.RLock()
-	defer e.valuesLock.RUnlock()
-
-	val, exists := e.values[ctx]
-	if !exists {
-		return cty.DynamicVal, nil
-	}
-	return val, nil
-}
-
-// setValue sets a temporary local value for the expression when evaluated
-// in the given context, which must be non-nil.
-func (e *AnonSymbolExpr) setValue(ctx *hcl.EvalContext, val cty.Value) {
-	e.valuesLock.Lock()
-	defer e.valuesLock.Unlock()
-
-	if e.values == nil {
-		e.values = make(map[*hcl.EvalContext]cty.Value)
-	}
-	if ctx == nil {
-		panic("can't setValue for a nil EvalContext")
-	}
-	e.values[ctx] = val
+	return evaluate(e, ctx)
 }
 
-func (e *AnonSymbolExpr) clearValue(ctx *hcl.EvalContext) {
-	e.valuesLock.Lock()
-	defer e.valuesLock.Unlock()
-
-	if e.values == nil {
-		return
-	}
-	if ctx == nil {
-		panic("can't clearValue for a nil EvalContext")
+func (e *AnonSymbolExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	if val, bound := fr.item(e); bound {
+		return val, nil
 	}
-	delete(e.values, ctx)
+	return cty.DynamicVal, nil
 }
 
 func (e *AnonSymbolExpr) walkChildNodes(w internalWalkFunc) {
@@ -7,7 +7,6 @@ import (
 	"github.com/quomproject/quolang/quofn"
 	"github.com/quomproject/quolang/quoty"
 	"github.com/zclconf/go-cty/cty"
-	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/function/stdlib"
 )
@@ -41,19 +40,19 @@ var (
 	}
 
 	OpGreaterThan = &Operation{
-		Impl: stdlib.GreaterThanFunc,
+		Impl: quofn.GreaterThanFunc,
 		Type: cty.Bool,
 	}
 	OpGreaterThanOrEqual = &Operation{
-		Impl: stdlib.GreaterThanOrEqualToFunc,
+		Impl: quofn.GreaterThanOrEqualToFunc,
 		Type: cty.Bool,
 	}
 	OpLessThan = &Operation{
-		Impl: stdlib.LessThanFunc,
+		Impl: quofn.LessThanFunc,
 		Type: cty.Bool,
 	}
 	OpLessThanOrEqual = &Operation{
-		Impl: stdlib.LessThanOrEqualToFunc,
+		Impl: quofn.LessThanOrEqualToFunc,
 		Type: cty.Bool,
 	}
 
@@ -136,41 +135,29 @@ func (e *BinaryOpExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *BinaryOpExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *BinaryOpExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	impl := e.Op.Impl // assumed to be a function taking exactly two arguments
-	params := impl.Params()
+	params := fr.operationParams(e.Op)
 	lhsParam := params[0]
 	rhsParam := params[1]
 
 	var diags hcl.Diagnostics
 
-	givenLHSVal, lhsDiags := e.LHS.Value(ctx)
-	givenRHSVal, rhsDiags := e.RHS.Value(ctx)
+	givenLHSVal, lhsDiags := fr.eval(e.LHS, ctx)
+	givenRHSVal, rhsDiags := fr.eval(e.RHS, ctx)
 	diags = append(diags, lhsDiags...)
 	diags = append(diags, rhsDiags...)
 
-	lhsVal, err := convert.Convert(givenLHSVal, lhsParam.Type)
+	lhsVal, err := fr.convert(e.LHS, givenLHSVal, lhsParam.Type)
 	if err != nil {
-		diags = append(diags, &hcl.Diagnostic{
-			Severity:    hcl.DiagError,
-			Summary:     "Invalid operand",
-			Detail:      fmt.Sprintf("Unsuitable value for left operand: %s.", err),
-			Subject:     e.LHS.Range().Ptr(),
-			Context:     &e.SrcRange,
-			Expression:  e.LHS,
-			EvalContext: ctx,
-		})
+		diags = append(diags, operandDiagnostic("left", e.LHS, e.SrcRange, givenLHSVal.Type(), lhsParam.Type, err, ctx))
 	}
-	rhsVal, err := convert.Convert(givenRHSVal, rhsParam.Type)
+	rhsVal, err := fr.convert(e.RHS, givenRHSVal, rhsParam.Type)
 	if err != nil {
-		diags = append(diags, &hcl.Diagnostic{
-			Severity:    hcl.DiagError,
-			Summary:     "Invalid operand",
-			Detail:      fmt.Sprintf("Unsuitable value for right operand: %s.", err),
-			Subject:     e.RHS.Range().Ptr(),
-			Context:     &e.SrcRange,
-			Expression:  e.RHS,
-			EvalContext: ctx,
-		})
+		diags = append(diags, operandDiagnostic("right", e.RHS, e.SrcRange, givenRHSVal.Type(), rhsParam.Type, err, ctx))
 	}
 
 	if diags.HasErrors() {
@@ -194,6 +181,9 @@ func (e *BinaryOpExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics)
 		return cty.UnknownVal(e.Op.Type), diags
 	}
 
+	result, precisionDiags := applyPrecision(fr.precision(), e, ctx, result)
+	diags = append(diags, precisionDiags...)
+
 	return result, diags
 }
 
@@ -218,23 +208,19 @@ func (e *UnaryOpExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *UnaryOpExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *UnaryOpExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	impl := e.Op.Impl // assumed to be a function taking exactly one argument
-	params := impl.Params()
+	params := fr.operationParams(e.Op)
 	param := params[0]
 
-	givenVal, diags := e.Val.Value(ctx)
+	givenVal, diags := fr.eval(e.Val, ctx)
 
-	val, err := convert.Convert(givenVal, param.Type)
+	val, err := fr.convert(e.Val, givenVal, param.Type)
 	if err != nil {
-		diags = append(diags, &hcl.Diagnostic{
-			Severity:    hcl.DiagError,
-			Summary:     "Invalid operand",
-			Detail:      fmt.Sprintf("Unsuitable value for unary operand: %s.", err),
-			Subject:     e.Val.Range().Ptr(),
-			Context:     &e.SrcRange,
-			Expression:  e.Val,
-			EvalContext: ctx,
-		})
+		diags = append(diags, operandDiagnostic("unary", e.Val, e.SrcRange, givenVal.Type(), param.Type, err, ctx))
 	}
 
 	if diags.HasErrors() {
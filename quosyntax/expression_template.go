@@ -5,8 +5,8 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
 	"github.com/zclconf/go-cty/cty"
-	"github.com/zclconf/go-cty/cty/convert"
 )
 
 type TemplateExpr struct {
@@ -22,12 +22,16 @@ func (e *TemplateExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *TemplateExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return evaluate(e, ctx)
+}
+
+func (e *TemplateExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
 	buf := &bytes.Buffer{}
 	var diags hcl.Diagnostics
 	isKnown := true
 
 	for _, part := range e.Parts {
-		partVal, partDiags := part.Value(ctx)
+		partVal, partDiags := fr.eval(part, ctx)
 		diags = append(diags, partDiags...)
 
 		if partVal.IsNull() {
@@ -54,7 +58,7 @@ func (e *TemplateExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics)
 			continue
 		}
 
-		strVal, err := convert.Convert(partVal, cty.String)
+		strVal, err := quoty.ToString(partVal)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
@@ -122,7 +126,11 @@ func (e *TemplateJoinExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *TemplateJoinExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	tuple, diags := e.Tuple.Value(ctx)
+	return evaluate(e, ctx)
+}
+
+func (e *TemplateJoinExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	tuple, diags := fr.eval(e.Tuple, ctx)
 
 	if tuple.IsNull() {
 		// This indicates a bug in the code that constructed the AST.
@@ -160,7 +168,7 @@ func (e *TemplateJoinExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnosti
 		if val.Type() == cty.DynamicPseudoType {
 			return cty.UnknownVal(cty.String), diags
 		}
-		strVal, err := convert.Convert(val, cty.String)
+		strVal, err := quoty.ToString(val)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
@@ -208,7 +216,11 @@ func (e *TemplateWrapExpr) walkChildNodes(w internalWalkFunc) {
 }
 
 func (e *TemplateWrapExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
-	return e.Wrapped.Value(ctx)
+	return evaluate(e, ctx)
+}
+
+func (e *TemplateWrapExpr) value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics) {
+	return fr.eval(e.Wrapped, ctx)
 }
 
 func (e *TemplateWrapExpr) Range() hcl.Range {
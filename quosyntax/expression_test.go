@@ -1,6 +1,8 @@
 package quosyntax
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
@@ -1775,3 +1777,115 @@ func TestStaticExpressionList(t *testing.T) {
 		t.Fatalf("wrong first value %#v; want cty.Zero", first.Val)
 	}
 }
+
+func TestSplatExprConcurrent(t *testing.T) {
+	// Splat expressions must be safe to evaluate concurrently, both with
+	// the same EvalContext and with different ones. This test is most
+	// useful when run with the race detector enabled.
+	exprs := []string{
+		`assets[*].code`,
+		`assets.*.code`,
+		`groups[*][*].code`,
+		`[for g in groups: g[*].code]`,
+		`"%{ for c in assets[*].code }${c}%{ endfor }"`,
+	}
+
+	ctxFor := func(n int) *hcl.EvalContext {
+		assets := make([]cty.Value, n)
+		for i := range assets {
+			assets[i] = cty.ObjectVal(map[string]cty.Value{
+				"code": cty.StringVal(fmt.Sprintf("A%d", i)),
+			})
+		}
+		return &hcl.EvalContext{
+			Variables: map[string]cty.Value{
+				"assets": cty.ListVal(assets),
+				"groups": cty.TupleVal([]cty.Value{cty.ListVal(assets), cty.ListVal(assets)}),
+			},
+		}
+	}
+	shared := ctxFor(20)
+
+	for _, input := range exprs {
+		t.Run(input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				ctx := shared
+				if g%2 == 1 {
+					ctx = ctxFor(g)
+				}
+				want, diags := expr.Value(ctx)
+				if len(diags) != 0 {
+					t.Fatalf("unexpected diagnostics: %s", diags.Error())
+				}
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						got, diags := expr.Value(ctx)
+						if len(diags) != 0 {
+							t.Errorf("unexpected diagnostics: %s", diags.Error())
+							return
+						}
+						if !got.RawEquals(want) {
+							t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestNumberComparisonAndStringConversion(t *testing.T) {
+	// Numbers are compared exactly, and are written in decimal notation
+	// wherever the language converts them to strings.
+	tests := []struct {
+		input     string
+		want      cty.Value
+		wantError string
+	}{
+		{`0.1 + 0.2 > 0.3`, cty.False, ``},
+		{`0.1 + 0.2 >= 0.3`, cty.True, ``},
+		{`1 / 3 < 0.33333333333333333333`, cty.False, ``},
+		{`1 / 3 <= 0.33333333333333333334`, cty.True, ``},
+		{`"2" < 10`, cty.True, ``},
+		{`1 < "a"`, cty.UnknownVal(cty.Bool), `Invalid operand`},
+		{`"p=${0.1 + 0.2}"`, cty.StringVal("p=0.3"), ``},
+		{`"p=${1 / 3}"`, cty.StringVal("p=0.33333333333333333333"), ``},
+		{`{ (1.50) = true }`, cty.ObjectVal(map[string]cty.Value{"1.5": cty.True}), ``},
+		{`{ for v in [1, 2.5]: v => true }`, cty.ObjectVal(map[string]cty.Value{"1": cty.True, "2.5": cty.True}), ``},
+		{`[10, 20][1]`, quoty.NumberIntVal(20), ``},
+		{`[10, 20].1`, quoty.NumberIntVal(20), ``},
+		{`[10, 20][0.5]`, cty.DynamicVal, `Invalid index`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics: %s", parseDiags.Error())
+			}
+
+			got, diags := expr.Value(nil)
+			if test.wantError == "" {
+				if len(diags) != 0 {
+					t.Fatalf("unexpected diagnostics: %s", diags.Error())
+				}
+			} else if !diags.HasErrors() || diags[0].Summary != test.wantError {
+				t.Fatalf("wrong diagnostics; want %q\n%s", test.wantError, diags.Error())
+			}
+			if !got.RawEquals(test.want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.want)
+			}
+		})
+	}
+}
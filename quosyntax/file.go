@@ -8,13 +8,78 @@ import (
 type File struct {
 	Body  *Body
 	Bytes []byte
+
+	// Tokens is the full sequence of tokens the file was parsed from,
+	// including comments, ending with a TokenEOF.
+	Tokens Tokens
+
+	// Comments is the subset of Tokens that are comments, in the order they
+	// appear in the file.
+	Comments Tokens
+
+	// These record the arguments and outcome of the parse that produced the
+	// file, for use by ReparseFile and MarkdownReference.
+	filename  string
+	start     hcl.Pos
+	hasErrors bool
+}
+
+// ContextString returns a string describing the nested blocks, attribute
+// and template directives that contain the given byte offset, such as
+// strategy "mm" > leg "buy" > attribute "price".
+//
+// This is the implementation of the method of the same name on the Nav
+// object of the file returned by AsHCLFile.
+func (f *File) ContextString(offset int) string {
+	return f.navigation().ContextString(offset)
+}
+
+// ContextDefRange returns the definition range of the innermost item that
+// ContextString would describe for the given byte offset, or the zero range
+// if there is no such item.
+func (f *File) ContextDefRange(offset int) hcl.Range {
+	return f.navigation().ContextDefRange(offset)
+}
+
+// ContextDoc returns the text of the doc comments of the innermost block or
+// attribute that contains the given byte offset, or the empty string if
+// there is no such item or it has no doc comments.
+func (f *File) ContextDoc(offset int) string {
+	return f.navigation().ContextDoc(offset)
+}
+
+// RangeBytes returns the source code of the given range of the file. Unlike
+// hcl.Range.SliceBytes, it allows for a file whose positions don't start at
+// the beginning of a document.
+func (f *File) RangeBytes(rng hcl.Range) []byte {
+	return f.Bytes[rng.Start.Byte-f.start.Byte : rng.End.Byte-f.start.Byte]
 }
 
+// AsHCLFile returns the file as an *hcl.File, whose Nav object provides
+// the ContextString, ContextDefRange and ContextDoc methods of this file.
 func (f *File) AsHCLFile() *hcl.File {
 	return &hcl.File{
 		Body:  f.Body,
 		Bytes: f.Bytes,
+		Nav:   f.navigation(),
+	}
+}
+
+func (f *File) navigation() navigation {
+	return navigation{
+		root:  f.Body,
+		src:   f.Bytes,
+		start: f.start,
+	}
+}
 
-		// TODO: The Nav object, once we have an implementation of it
+// commentTokens returns the comment tokens from the given tokens.
+func commentTokens(tokens Tokens) Tokens {
+	var ret Tokens
+	for _, tok := range tokens {
+		if tok.Type == TokenComment {
+			ret = append(ret, tok)
+		}
 	}
+	return ret
 }
@@ -0,0 +1,208 @@
+package quosyntax
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// evalFrame carries the state of an evaluation that belongs to the
+// evaluation itself rather than to the EvalContext, which may be shared by
+// concurrent evaluations. Each expression passes its frame, or a copy of it
+// with additional state, to the evaluations of its sub-expressions.
+//
+// A nil frame is valid, and is the frame of an evaluation that has no such
+// state yet.
+type evalFrame struct {
+	// run is the state of the evaluation within the budget set by
+	// WithLimits, if any.
+	run *budgetRun
+
+	// policy is the precision policy set by WithPrecision, if any.
+	policy *quoty.Precision
+
+	// tracer is the tracer set by WithTracer, if any, and event is the
+	// event for the expression whose sub-expressions are being evaluated,
+	// if any.
+	tracer Tracer
+	event  *TraceEvent
+
+	// items are the values bound to the items of the splat expressions
+	// that are currently being evaluated, innermost first.
+	items *splatItem
+
+	// prog is the program of the expression being evaluated, if it was
+	// compiled with Compile, and vars are the values of the variables of
+	// its scope.
+	prog *program
+	vars []cty.Value
+}
+
+// splatItem is the value of the item of a splat expression during one
+// evaluation of its Each expression.
+type splatItem struct {
+	sym  *AnonSymbolExpr
+	val  cty.Value
+	next *splatItem
+}
+
+// valueImpl is implemented by the expression types of this package, whose
+// evaluation is implemented by this method, which evaluates the expression
+// within the given frame.
+type valueImpl interface {
+	Expression
+	value(ctx *hcl.EvalContext, fr *evalFrame) (cty.Value, hcl.Diagnostics)
+}
+
+// evaluate is the implementation of the Value methods of the expression
+// types, which starts a new evaluation of the given expression.
+func evaluate(e valueImpl, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	return frameFor(ctx).eval(e, ctx)
+}
+
+// frameFor returns the frame for a new evaluation with the options carried
+// by the given EvalContext, or nil if there are none.
+func frameFor(ctx *hcl.EvalContext) *evalFrame {
+	opts := optionsFor(ctx)
+	if opts == nil {
+		return nil
+	}
+	fr := &evalFrame{
+		policy: opts.precision,
+		tracer: opts.tracer,
+	}
+	if opts.budget != nil {
+		fr.run = &budgetRun{budget: opts.budget}
+	}
+	return fr
+}
+
+// eval evaluates the given sub-expression within the receiving frame.
+//
+// Expressions of other types, which can't have a frame, are evaluated with
+// their own Value method.
+func (fr *evalFrame) eval(e Expression, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	impl, ok := e.(valueImpl)
+	if !ok {
+		return e.Value(ctx)
+	}
+	switch {
+	case fr == nil:
+		return impl.value(ctx, nil)
+	case fr.tracer != nil:
+		return fr.trace(impl, ctx)
+	default:
+		return fr.evalLimited(impl, ctx)
+	}
+}
+
+// evalLimited evaluates the given expression within the budget of the
+// receiving frame, if any.
+func (fr *evalFrame) evalLimited(e valueImpl, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	if fr.run != nil {
+		return fr.run.eval(e, ctx, fr)
+	}
+	return e.value(ctx, fr)
+}
+
+// budgetRun returns the state of the evaluation within its budget, or nil
+// if it has none.
+func (fr *evalFrame) budgetRun() *budgetRun {
+	if fr == nil {
+		return nil
+	}
+	return fr.run
+}
+
+// precision returns the precision policy that applies to the evaluation, or
+// nil if there is none.
+func (fr *evalFrame) precision() *quoty.Precision {
+	if fr == nil {
+		return nil
+	}
+	return fr.policy
+}
+
+// withItem returns a copy of the receiving frame in which the given symbol
+// has the given value.
+func (fr *evalFrame) withItem(sym *AnonSymbolExpr, val cty.Value) *evalFrame {
+	ret := &evalFrame{}
+	if fr != nil {
+		*ret = *fr
+	}
+	ret.items = &splatItem{
+		sym:  sym,
+		val:  val,
+		next: ret.items,
+	}
+	return ret
+}
+
+// item returns the value bound to the given symbol in the receiving frame,
+// if any.
+func (fr *evalFrame) item(sym *AnonSymbolExpr) (cty.Value, bool) {
+	if fr == nil {
+		return cty.NilVal, false
+	}
+	for item := fr.items; item != nil; item = item.next {
+		if item.sym == sym {
+			return item.val, true
+		}
+	}
+	return cty.NilVal, false
+}
+
+// root returns the value that the root of the given traversal, evaluated
+// with the given EvalContext, refers to, if Compile resolved where to find
+// it.
+func (fr *evalFrame) root(e *ScopeTraversalExpr, ctx *hcl.EvalContext) (cty.Value, bool) {
+	if fr == nil || fr.prog == nil {
+		return cty.NilVal, false
+	}
+	r, ok := fr.prog.roots[e]
+	if !ok {
+		return cty.NilVal, false
+	}
+	if r.slot >= 0 {
+		return fr.vars[r.slot], true
+	}
+	for i := 0; i < r.up; i++ {
+		ctx = ctx.Parent()
+	}
+	val, ok := ctx.Variables[e.Traversal.RootName()]
+	return val, ok
+}
+
+// call returns the function of the compiled scope that the given function
+// call refers to, if Compile resolved it.
+func (fr *evalFrame) call(e *FunctionCallExpr) *compiledCall {
+	if fr == nil || fr.prog == nil {
+		return nil
+	}
+	return fr.prog.calls[e]
+}
+
+// operationParams returns the parameters of the implementation of the given
+// operation.
+func (fr *evalFrame) operationParams(op *Operation) []function.Parameter {
+	if fr != nil && fr.prog != nil {
+		if params, ok := fr.prog.params[op]; ok {
+			return params
+		}
+	}
+	return op.Impl.Params()
+}
+
+// convert converts the given value of the given operand or argument to the
+// given type like convert.Convert, using the conversion that Compile
+// prepared for it if the value has the type it was prepared for.
+func (fr *evalFrame) convert(expr Expression, val cty.Value, want cty.Type) (cty.Value, error) {
+	if fr != nil && fr.prog != nil {
+		if c, ok := fr.prog.conversions[expr]; ok && val.Type().Equals(c.from) {
+			return c.conv(val)
+		}
+	}
+	return convert.Convert(val, want)
+}
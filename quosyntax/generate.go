@@ -7,3 +7,4 @@ package quosyntax
 //go:generate ragel -Z scan_string_lit.rl
 //go:generate gofmt -w scan_string_lit.go
 //go:generate go run golang.org/x/tools/cmd/stringer -type TokenType -output token_type_string.go
+//go:generate go run golang.org/x/tools/cmd/stringer -type SemanticTokenType -output semantic_token_type_string.go
@@ -0,0 +1,570 @@
+package quosyntax
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/apparentlymart/go-textseg/textseg"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ReparseFile returns the result of parsing the source code of the given
+// file with the given edit applied. The file must have been returned by
+// ParseFile or by an earlier call to ReparseFile.
+//
+// Only the top-level attributes and blocks that the edit touches are lexed
+// and parsed again. The others are reused, so the result is the same as
+// calling ParseFile with the edited source code. Those before the edit are
+// shared with the given file, while those that follow it are copied with
+// their ranges shifted, unless the edit doesn't move them, so the given
+// file is left unchanged and remains usable. Copying them and the tokens
+// still takes time proportional to the size of the file, but that is a
+// small fraction of the time taken to lex and parse them again.
+//
+// If the edit could change how the rest of the file is parsed, such as by
+// opening a block or string that it doesn't close, or if either the file or
+// the part that is parsed again has errors, then the whole of the edited
+// source code is parsed again instead.
+//
+// The range of the edit must have a correct line and column as well as a
+// byte offset for both its start and its end.
+func ReparseFile(prev *File, edit TextEdit) (*File, hcl.Diagnostics) {
+	base := prev.start.Byte
+	editStart, editEnd := edit.Range.Start.Byte-base, edit.Range.End.Byte-base
+	if editStart < 0 || editEnd < editStart || editEnd > len(prev.Bytes) {
+		return nil, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid edit",
+				Detail:   fmt.Sprintf("The edit at %s is outside of the source code.", edit.Range),
+			},
+		}
+	}
+
+	src := make([]byte, 0, len(prev.Bytes)-(editEnd-editStart)+len(edit.NewText))
+	src = append(src, prev.Bytes[:editStart]...)
+	src = append(src, edit.NewText...)
+	src = append(src, prev.Bytes[editEnd:]...)
+
+	if !prev.hasErrors && prev.Body != nil && len(prev.Tokens) > 0 {
+		if file, ok := reparseItems(prev, edit, src); ok {
+			return file, nil
+		}
+	}
+	return ParseFile(src, prev.filename, prev.start)
+}
+
+// reparseItems is the incremental part of ReparseFile, which returns false
+// if the result might not be the same as that of a full parse.
+func reparseItems(prev *File, edit TextEdit, src []byte) (*File, bool) {
+	base := prev.start.Byte
+	shift := newPosShift(edit)
+	items := bodyItems(prev.Body)
+
+	// first is the index of the first item that the edit touches, and after
+	// is the index of the first item after it that the edit doesn't touch.
+	// An edit that starts right at the end of an item touches it, because
+	// it might extend it.
+	first := sort.Search(len(items), func(i int) bool {
+		return items[i].Range().End.Byte >= edit.Range.Start.Byte
+	})
+	after := sort.Search(len(items), func(i int) bool {
+		return items[i].Range().Start.Byte > edit.Range.End.Byte
+	})
+
+	// We'll lex and parse again from the end of the last item before the
+	// edit, so that we can check that item is still terminated, up to the
+	// start of the first item after the edit.
+	regionStart := prev.start
+	if first > 0 {
+		regionStart = items[first-1].Range().End
+	}
+	regionEnd := base + len(src)
+	if after < len(items) {
+		regionEnd = shift.Pos(items[after].Range().Start).Byte
+	}
+
+	tokens := scanTokens(src[regionStart.Byte-base:regionEnd-base], prev.filename, regionStart, scanNormal)
+	if checkInvalidTokens(tokens).HasErrors() {
+		return nil, false
+	}
+	peeker := newPeeker(tokens, false)
+	parser := &parser{peeker: peeker}
+	region, diags := parser.ParseBody(TokenEOF)
+	if diags.HasErrors() {
+		return nil, false
+	}
+	peeker.AssertEmptyIncludeNewlinesStack()
+
+	// The reparsed items must still be separated from the reused items by
+	// newlines, or else a full parse would see them as a single item.
+	regionTokens := tokens[:len(tokens)-1] // excluding the EOF token
+	if first > 0 && !endsLine(regionTokens, false) {
+		return nil, false
+	}
+	if after < len(items) && !endsLine(regionTokens, true) {
+		return nil, false
+	}
+
+	split := sort.Search(len(prev.Tokens), func(i int) bool {
+		return prev.Tokens[i].Range.Start.Byte >= regionStart.Byte
+	})
+	tail := len(prev.Tokens)
+	if after < len(items) {
+		tail = sort.Search(len(prev.Tokens), func(i int) bool {
+			return prev.Tokens[i].Range.Start.Byte >= items[after].Range().Start.Byte
+		})
+	}
+	newTokens := make(Tokens, 0, split+len(tokens)+len(prev.Tokens)-tail)
+	newTokens = append(newTokens, prev.Tokens[:split]...)
+	if after < len(items) {
+		newTokens = append(newTokens, regionTokens...)
+		for _, tok := range prev.Tokens[tail:] {
+			tok.Range = shift.Range(tok.Range)
+			newTokens = append(newTokens, tok)
+		}
+	} else {
+		newTokens = append(newTokens, tokens...)
+	}
+	for i := range newTokens {
+		rng := newTokens[i].Range
+		newTokens[i].Bytes = src[rng.Start.Byte-base : rng.End.Byte-base]
+	}
+
+	reused := items[after:]
+	newItems := make([]Node, 0, len(items))
+	newItems = append(newItems, items[:first]...)
+	newItems = append(newItems, bodyItems(region)...)
+	newItems = append(newItems, reused...)
+
+	// A full parse would report an attribute that the region redefines, and
+	// we must find that out before we take over the items after the edit.
+	attrs := Attributes{}
+	for _, item := range newItems {
+		if attr, ok := item.(*Attribute); ok {
+			if _, exists := attrs[attr.Name]; exists {
+				return nil, false
+			}
+			attrs[attr.Name] = attr
+		}
+	}
+	if shift.newEnd != shift.oldEnd {
+		for i := len(newItems) - len(reused); i < len(newItems); i++ {
+			newItems[i] = shift.shiftNode(newItems[i])
+		}
+	}
+
+	// The comments in the region can document the items either side of it
+	// as well as those within it.
+	attachDocs(region, newTokens)
+	if first > 0 {
+		newItems[first-1] = withDoc(newItems[first-1], newTokens)
+	}
+	if len(reused) > 0 {
+		i := len(newItems) - len(reused)
+		newItems[i] = withDoc(newItems[i], newTokens)
+	}
+	blocks := Blocks{}
+	for _, item := range newItems {
+		switch item := item.(type) {
+		case *Attribute:
+			attrs[item.Name] = item
+		case *Block:
+			blocks = append(blocks, item)
+		}
+	}
+
+	// The range of the body is derived from the tokens in the same way as
+	// in parser.ParseBody.
+	startRange := newPeeker(newTokens, false).PrevRange()
+	endRange := newTokens[len(newTokens)-1].Range
+	body := &Body{
+		Attributes: attrs,
+		Blocks:     blocks,
+
+		SrcRange: hcl.RangeBetween(startRange, endRange),
+		EndRange: hcl.Range{
+			Filename: endRange.Filename,
+			Start:    endRange.End,
+			End:      endRange.End,
+		},
+	}
+
+	return &File{
+		Body:  body,
+		Bytes: src,
+
+		Tokens:   newTokens,
+		Comments: commentTokens(newTokens),
+
+		filename: prev.filename,
+		start:    prev.start,
+	}, true
+}
+
+// bodyItems returns the attributes and blocks of the given body in the order
+// they appear in the source code.
+func bodyItems(body *Body) []Node {
+	items := make([]Node, 0, len(body.Attributes)+len(body.Blocks))
+	for _, attr := range body.Attributes {
+		items = append(items, attr)
+	}
+	for _, block := range body.Blocks {
+		items = append(items, block)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Range().Start.Byte < items[j].Range().Start.Byte
+	})
+	return items
+}
+
+// withDoc returns the given attribute or block with its documentation taken
+// from the given tokens, which is a copy of it if that has changed.
+func withDoc(item Node, tokens Tokens) Node {
+	doc := itemDoc(item, tokens)
+	switch item := item.(type) {
+	case *Attribute:
+		if reflect.DeepEqual(item.Doc, doc) {
+			return item
+		}
+		ret := *item
+		ret.Doc = doc
+		return &ret
+	case *Block:
+		if reflect.DeepEqual(item.Doc, doc) {
+			return item
+		}
+		ret := *item
+		ret.Doc = doc
+		return &ret
+	default:
+		return item
+	}
+}
+
+// endsLine returns true if the first (or, if last is true, the final) of
+// the given tokens that isn't an inline comment ends a line.
+func endsLine(tokens Tokens, last bool) bool {
+	for i := range tokens {
+		tok := tokens[i]
+		if last {
+			tok = tokens[len(tokens)-1-i]
+		}
+		switch {
+		case tok.Type == TokenNewline:
+			return true
+		case tok.Type != TokenComment:
+			return false
+		case bytes.HasPrefix(tok.Bytes, []byte("/*")):
+			// An inline comment that isn't closed continues after the
+			// tokens we have.
+			if len(tok.Bytes) < 4 || !bytes.HasSuffix(tok.Bytes, []byte("*/")) {
+				return false
+			}
+		default:
+			// A line comment absorbs the newline that ends it, unless it
+			// continues after the tokens we have.
+			return bytes.HasSuffix(tok.Bytes, []byte("\n"))
+		}
+	}
+	return false
+}
+
+// posShift describes how the positions after an edit move when it is
+// applied.
+type posShift struct {
+	oldEnd, newEnd hcl.Pos
+
+	// symbols maps the items of the splat expressions being shifted to
+	// their copies.
+	symbols map[*AnonSymbolExpr]*AnonSymbolExpr
+}
+
+func newPosShift(edit TextEdit) posShift {
+	newEnd := edit.Range.Start
+	newEnd.Byte += len(edit.NewText)
+	b := []byte(edit.NewText)
+	for len(b) > 0 {
+		advance, seq, _ := textseg.ScanGraphemeClusters(b, true)
+		if (len(seq) == 1 && seq[0] == '\n') || (len(seq) == 2 && seq[0] == '\r' && seq[1] == '\n') {
+			newEnd.Line++
+			newEnd.Column = 1
+		} else {
+			newEnd.Column++
+		}
+		b = b[advance:]
+	}
+	return posShift{
+		oldEnd:  edit.Range.End,
+		newEnd:  newEnd,
+		symbols: make(map[*AnonSymbolExpr]*AnonSymbolExpr),
+	}
+}
+
+// Pos returns the new position of the given position. Positions before the
+// end of the edit, such as the zero positions of the ranges the parser leaves
+// unset, are returned unchanged.
+func (s posShift) Pos(pos hcl.Pos) hcl.Pos {
+	if pos.Byte < s.oldEnd.Byte || pos == (hcl.Pos{}) {
+		return pos
+	}
+	ret := hcl.Pos{
+		Line:   pos.Line + s.newEnd.Line - s.oldEnd.Line,
+		Column: pos.Column,
+		Byte:   pos.Byte + s.newEnd.Byte - s.oldEnd.Byte,
+	}
+	if pos.Line == s.oldEnd.Line {
+		ret.Column += s.newEnd.Column - s.oldEnd.Column
+	}
+	return ret
+}
+
+// Range returns the new range of the given range.
+func (s posShift) Range(rng hcl.Range) hcl.Range {
+	rng.Start = s.Pos(rng.Start)
+	rng.End = s.Pos(rng.End)
+	return rng
+}
+
+// shiftNode returns a copy of the given node, which follows the edit, with
+// each of the positions within it shifted. The given node is left as it is,
+// since it still belongs to the file before the edit.
+//
+// The Item of a SplatExpr is copied along with the splat expression itself,
+// and then replaced by that copy wherever it appears within the copy of its
+// Each expression.
+func (s posShift) shiftNode(n Node) Node {
+	switch n := n.(type) {
+	case *Body:
+		ret := *n
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.EndRange = s.Range(n.EndRange)
+		if n.Attributes != nil {
+			ret.Attributes = make(Attributes, len(n.Attributes))
+			for name, attr := range n.Attributes {
+				ret.Attributes[name] = s.shiftNode(attr).(*Attribute)
+			}
+		}
+		if n.Blocks != nil {
+			ret.Blocks = make(Blocks, len(n.Blocks))
+			for i, block := range n.Blocks {
+				ret.Blocks[i] = s.shiftNode(block).(*Block)
+			}
+		}
+		return &ret
+	case *Attribute:
+		ret := *n
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.NameRange = s.Range(n.NameRange)
+		ret.EqualsRange = s.Range(n.EqualsRange)
+		ret.Doc = s.shiftDoc(n.Doc)
+		ret.Expr = s.shiftExpr(n.Expr)
+		return &ret
+	case *Block:
+		ret := *n
+		ret.TypeRange = s.Range(n.TypeRange)
+		if n.LabelRanges != nil {
+			ret.LabelRanges = make([]hcl.Range, len(n.LabelRanges))
+			for i, rng := range n.LabelRanges {
+				ret.LabelRanges[i] = s.Range(rng)
+			}
+		}
+		ret.OpenBraceRange = s.Range(n.OpenBraceRange)
+		ret.CloseBraceRange = s.Range(n.CloseBraceRange)
+		ret.Doc = s.shiftDoc(n.Doc)
+		if n.Body != nil {
+			ret.Body = s.shiftNode(n.Body).(*Body)
+		}
+		return &ret
+	case *LiteralValueExpr:
+		ret := *n
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	case *ScopeTraversalExpr:
+		ret := *n
+		ret.Traversal = s.shiftTraversal(n.Traversal)
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	case *RelativeTraversalExpr:
+		ret := *n
+		ret.Source = s.shiftExpr(n.Source)
+		ret.Traversal = s.shiftTraversal(n.Traversal)
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	case *FunctionCallExpr:
+		ret := *n
+		ret.Args = s.shiftExprs(n.Args)
+		ret.NameRange = s.Range(n.NameRange)
+		ret.OpenParenRange = s.Range(n.OpenParenRange)
+		ret.CloseParenRange = s.Range(n.CloseParenRange)
+		return &ret
+	case *ConditionalExpr:
+		ret := *n
+		ret.Condition = s.shiftExpr(n.Condition)
+		ret.TrueResult = s.shiftExpr(n.TrueResult)
+		ret.FalseResult = s.shiftExpr(n.FalseResult)
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	case *IndexExpr:
+		ret := *n
+		ret.Collection = s.shiftExpr(n.Collection)
+		ret.Key = s.shiftExpr(n.Key)
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.OpenRange = s.Range(n.OpenRange)
+		return &ret
+	case *TupleConsExpr:
+		ret := *n
+		ret.Exprs = s.shiftExprs(n.Exprs)
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.OpenRange = s.Range(n.OpenRange)
+		return &ret
+	case *ObjectConsExpr:
+		ret := *n
+		if n.Items != nil {
+			ret.Items = make([]ObjectConsItem, len(n.Items))
+			for i, item := range n.Items {
+				ret.Items[i] = ObjectConsItem{
+					KeyExpr:   s.shiftExpr(item.KeyExpr),
+					ValueExpr: s.shiftExpr(item.ValueExpr),
+				}
+			}
+		}
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.OpenRange = s.Range(n.OpenRange)
+		return &ret
+	case *ObjectConsKeyExpr:
+		// Unlike walkChildNodes, we shift the wrapped expression even when
+		// it's interpreted as a literal, because it still has a range.
+		ret := *n
+		ret.Wrapped = s.shiftExpr(n.Wrapped)
+		return &ret
+	case *ForExpr:
+		ret := *n
+		ret.CollExpr = s.shiftExpr(n.CollExpr)
+		if n.KeyExpr != nil {
+			ret.KeyExpr = s.shiftExpr(n.KeyExpr)
+		}
+		ret.ValExpr = s.shiftExpr(n.ValExpr)
+		if n.CondExpr != nil {
+			ret.CondExpr = s.shiftExpr(n.CondExpr)
+		}
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.OpenRange = s.Range(n.OpenRange)
+		ret.CloseRange = s.Range(n.CloseRange)
+		ret.KeyVarRange = s.Range(n.KeyVarRange)
+		ret.ValVarRange = s.Range(n.ValVarRange)
+		return &ret
+	case *SplatExpr:
+		ret := *n
+		ret.Source = s.shiftExpr(n.Source)
+		ret.Item = &AnonSymbolExpr{SrcRange: s.Range(n.Item.SrcRange)}
+		s.symbols[n.Item] = ret.Item
+		ret.Each = s.shiftExpr(n.Each)
+		delete(s.symbols, n.Item)
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.MarkerRange = s.Range(n.MarkerRange)
+		return &ret
+	case *AnonSymbolExpr:
+		return s.symbols[n]
+	case *BinaryOpExpr:
+		ret := *n
+		ret.LHS = s.shiftExpr(n.LHS)
+		ret.RHS = s.shiftExpr(n.RHS)
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	case *UnaryOpExpr:
+		ret := *n
+		ret.Val = s.shiftExpr(n.Val)
+		ret.SrcRange = s.Range(n.SrcRange)
+		ret.SymbolRange = s.Range(n.SymbolRange)
+		return &ret
+	case *TemplateExpr:
+		ret := *n
+		ret.Parts = s.shiftExprs(n.Parts)
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	case *TemplateJoinExpr:
+		ret := *n
+		ret.Tuple = s.shiftExpr(n.Tuple)
+		return &ret
+	case *TemplateWrapExpr:
+		ret := *n
+		ret.Wrapped = s.shiftExpr(n.Wrapped)
+		ret.SrcRange = s.Range(n.SrcRange)
+		return &ret
+	default:
+		// should never happen, since the parser produces only the node
+		// types above
+		panic(fmt.Sprintf("unsupported node type %T", n))
+	}
+}
+
+func (s posShift) shiftExpr(expr Expression) Expression {
+	return s.shiftNode(expr).(Expression)
+}
+
+func (s posShift) shiftExprs(exprs []Expression) []Expression {
+	if exprs == nil {
+		return nil
+	}
+	ret := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		ret[i] = s.shiftExpr(expr)
+	}
+	return ret
+}
+
+// shiftTraversal returns a copy of the given traversal with the ranges of
+// its steps shifted.
+func (s posShift) shiftTraversal(traversal hcl.Traversal) hcl.Traversal {
+	if traversal == nil {
+		return nil
+	}
+	ret := make(hcl.Traversal, len(traversal))
+	for i, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			step.SrcRange = s.Range(step.SrcRange)
+			ret[i] = step
+		case hcl.TraverseAttr:
+			step.SrcRange = s.Range(step.SrcRange)
+			ret[i] = step
+		case hcl.TraverseIndex:
+			step.SrcRange = s.Range(step.SrcRange)
+			ret[i] = step
+		case hcl.TraverseSplat:
+			step.Each = s.shiftTraversal(step.Each)
+			step.SrcRange = s.Range(step.SrcRange)
+			ret[i] = step
+		default:
+			ret[i] = step
+		}
+	}
+	return ret
+}
+
+// shiftDoc returns a copy of the given documentation, if any, with the
+// ranges of its comments shifted.
+func (s posShift) shiftDoc(doc *Doc) *Doc {
+	if doc == nil {
+		return nil
+	}
+	return &Doc{
+		Leading:  s.shiftTokens(doc.Leading),
+		Trailing: s.shiftTokens(doc.Trailing),
+	}
+}
+
+func (s posShift) shiftTokens(tokens Tokens) Tokens {
+	if tokens == nil {
+		return nil
+	}
+	ret := make(Tokens, len(tokens))
+	for i, tok := range tokens {
+		tok.Range = s.Range(tok.Range)
+		ret[i] = tok
+	}
+	return ret
+}
@@ -0,0 +1,277 @@
+package quosyntax
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/kr/pretty"
+)
+
+const incrementalTestSource = `# Generated grid levels
+strategy "grid" {
+  spread = var.max_spread * 0.5 // half
+  leg "buy" {
+    price = [for i, l in levels: mid - l.step * i if i > 0]
+    note  = "level ${name}%{ if up } up%{ else } down%{ endif }"
+    all   = levels[*].price
+  }
+}
+
+level_1 = { price = 1.01, amount = 10 }
+level_2 = { price = 1.02, amount = 20 }
+/* inline */ level_3 = { price = 1.03, amount = 30 }
+
+message = <<EOT
+hello ${name}
+EOT
+
+level "4" {
+  price  = 1.04
+  amount = max(40, var.min_amount)
+}
+`
+
+func TestReparseFile(t *testing.T) {
+	tests := map[string]struct {
+		Edit string // "old|new" replacing the first occurrence of old
+
+		// WantShared are the items that are reused from the previous file
+		// rather than being parsed again or copied.
+		WantShared []string
+	}{
+		"attribute value": {
+			"1.02|1.025",
+			[]string{"strategy", "level_1"},
+		},
+		"attribute value of the same length": {
+			"1.02|1.07",
+			[]string{"strategy", "level_1", "level_3", "message", "level"},
+		},
+		"block body": {
+			"var.min_amount|var.max_amount",
+			[]string{"strategy", "level_1", "level_2", "level_3", "message"},
+		},
+		"new attribute between items": {
+			"\n\nmessage|\nlevel_5 = 5\n\nmessage",
+			[]string{"strategy", "level_1", "level_2"},
+		},
+		"joined lines": {
+			"10 }\n|10 } ",
+			nil,
+		},
+		"unclosed block": {
+			"level \"4\" {|level \"4\" { a {",
+			nil,
+		},
+		"unclosed heredoc": {
+			"message = <<EOT\n|message = <<EOX\n",
+			nil,
+		},
+		"redefined attribute": {
+			"level_2 =|level_3 =",
+			nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := []byte(incrementalTestSource)
+			prev, diags := ParseFile(src, "test.quo", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			parts := strings.SplitN(test.Edit, "|", 2)
+			start := bytes.Index(src, []byte(parts[0]))
+			edit := TextEdit{
+				Range:   testRangeBetweenOffsets(src, start, start+len(parts[0])),
+				NewText: parts[1],
+			}
+			prevItems := bodyItems(prev.Body)
+			got := assertReparseMatches(t, prev, edit)
+
+			var shared []string
+			for _, item := range bodyItems(got.Body) {
+				for _, prevItem := range prevItems {
+					if item == prevItem {
+						shared = append(shared, testItemName(item))
+					}
+				}
+			}
+			if !reflect.DeepEqual(shared, test.WantShared) {
+				t.Errorf("wrong shared items\ngot:  %#v\nwant: %#v", shared, test.WantShared)
+			}
+		})
+	}
+}
+
+// TestReparseFileRandom is a differential test of ReparseFile against
+// ParseFile using a sequence of random edits.
+func TestReparseFileRandom(t *testing.T) {
+	snippets := []string{
+		"", "\n", " ", "x", "1", ".5", "=", ",", "\"", "{", "}", "[", "]", "(", ")",
+		"${", "%{", "#", "//", "/*", "*/", "<<EOT\n", "EOT", "~",
+		"a = 1\n", "b {}\n", "c \"d\" {\n  e = f\n}\n", "foo.bar", "for", "in", "if",
+		"level_9 = 2\n", "[for v in x: v]", "\"${y}\"",
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	src := []byte(incrementalTestSource)
+	prev, diags := ParseFile(src, "test.quo", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	for i := 0; i < 1000; i++ {
+		start := rnd.Intn(len(prev.Bytes) + 1)
+		end := start + rnd.Intn(8)
+		if end > len(prev.Bytes) {
+			end = len(prev.Bytes)
+		}
+		edit := TextEdit{
+			Range:   testRangeBetweenOffsets(prev.Bytes, start, end),
+			NewText: snippets[rnd.Intn(len(snippets))],
+		}
+
+		got := assertReparseMatches(t, prev, edit)
+		if t.Failed() {
+			t.Fatalf("failed after edit %d replacing %q with %q in:\n%s", i, prev.Bytes[start:end], edit.NewText, prev.Bytes)
+		}
+
+		// We only continue from edits that leave the source valid, so that
+		// most of the edits are applied to valid source.
+		if !got.hasErrors {
+			prev = got
+		}
+	}
+}
+
+func BenchmarkReparseFile(b *testing.B) {
+	src := benchmarkReparseSource(3000)
+	prev, diags := ParseFile(src, "test.quo", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		b.Fatalf("unexpected diagnostics: %s", diags.Error())
+	}
+
+	// Each edit changes a value near the start of the file, so that the
+	// ranges of nearly all of the items are shifted, and the edits
+	// alternately lengthen and shorten it so that the file doesn't grow.
+	offset := bytes.Index(src, []byte("1.5")) + 1
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		edit := TextEdit{
+			Range:   testRangeBetweenOffsets(prev.Bytes, offset, offset),
+			NewText: "0",
+		}
+		if i%2 == 1 {
+			edit.Range = testRangeBetweenOffsets(prev.Bytes, offset, offset+1)
+			edit.NewText = ""
+		}
+		prev, diags = ReparseFile(prev, edit)
+		if diags.HasErrors() {
+			b.Fatalf("unexpected diagnostics: %s", diags.Error())
+		}
+	}
+}
+
+func BenchmarkParseFile(b *testing.B) {
+	src := benchmarkReparseSource(3000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, diags := ParseFile(src, "test.quo", hcl.Pos{Line: 1, Column: 1}); diags.HasErrors() {
+			b.Fatalf("unexpected diagnostics: %s", diags.Error())
+		}
+	}
+}
+
+// benchmarkReparseSource returns source code with the given number of
+// blocks, for comparing ReparseFile with ParseFile.
+func benchmarkReparseSource(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `# Level %d
+level "%d" {
+  price  = 1.5 * var.base + %d
+  amount = max(10, var.min_amount)
+  tags   = [for t in var.tags: "${t}-%d" if t != ""]
+}
+
+`, i, i, i, i)
+	}
+	return buf.Bytes()
+}
+
+// assertReparseMatches checks that the result of ReparseFile is the same as
+// that of ParseFile with the edited source, and returns that result.
+func assertReparseMatches(t *testing.T, prev *File, edit TextEdit) *File {
+	t.Helper()
+
+	// ReparseFile must leave the given file as it was, so it's still the
+	// same as the result of parsing its source code.
+	defer func() {
+		before, _ := ParseFile(prev.Bytes, prev.filename, prev.start)
+		if !reflect.DeepEqual(prev.Body, before.Body) {
+			t.Errorf("previous body changed\n%s", strings.Join(pretty.Diff(prev.Body, before.Body), "\n"))
+		}
+		if !reflect.DeepEqual(prev.Tokens, before.Tokens) {
+			t.Errorf("previous tokens changed")
+		}
+	}()
+
+	got, gotDiags := ReparseFile(prev, edit)
+	src, err := ApplyEdits(prev.Bytes, []TextEdit{edit})
+	if err != nil {
+		t.Fatalf("invalid edit: %s", err)
+	}
+	want, wantDiags := ParseFile(src, prev.filename, prev.start)
+
+	if got, want := gotDiags.HasErrors(), wantDiags.HasErrors(); got != want {
+		t.Errorf("wrong error status %t; want %t\ngot:  %s\nwant: %s", got, want, gotDiags, wantDiags)
+	}
+	if !bytes.Equal(got.Bytes, want.Bytes) {
+		t.Errorf("wrong source\ngot:  %q\nwant: %q", got.Bytes, want.Bytes)
+	}
+	if !reflect.DeepEqual(got.Tokens, want.Tokens) {
+		t.Errorf("wrong tokens\ngot:  %#v\nwant: %#v", got.Tokens, want.Tokens)
+	}
+	if !reflect.DeepEqual(got.Body, want.Body) {
+		t.Errorf("wrong body\n%s", strings.Join(pretty.Diff(got.Body, want.Body), "\n"))
+	}
+	if !reflect.DeepEqual(got.Comments, want.Comments) {
+		t.Errorf("wrong comments\ngot:  %#v\nwant: %#v", got.Comments, want.Comments)
+	}
+	return got
+}
+
+// testRangeBetweenOffsets returns the range between the given offsets in the
+// given ASCII source code.
+func testRangeBetweenOffsets(src []byte, start, end int) hcl.Range {
+	pos := func(offset int) hcl.Pos {
+		line := bytes.Count(src[:offset], []byte{'\n'}) + 1
+		col := offset - bytes.LastIndexByte(src[:offset], '\n')
+		return hcl.Pos{Line: line, Column: col, Byte: offset}
+	}
+	return hcl.Range{
+		Filename: "test.quo",
+		Start:    pos(start),
+		End:      pos(end),
+	}
+}
+
+func testItemName(item Node) string {
+	switch item := item.(type) {
+	case *Attribute:
+		return item.Name
+	case *Block:
+		return item.Type
+	default:
+		return fmt.Sprintf("%T", item)
+	}
+}
@@ -0,0 +1,77 @@
+package quosyntax
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// MarkdownReference returns a Markdown document with the given title that
+// describes the blocks and attributes of the file along with the text of
+// their doc comments, in the order they appear in the file.
+//
+// The attributes of each body are listed in a table with their values as
+// written in the source code, and each block has a section of its own
+// under the section of the body that contains it. A schema described in
+// Quo is itself a file whose blocks and attributes stand for those of the
+// configurations it describes, so its reference is produced in the same
+// way.
+func (f *File) MarkdownReference(title string) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s\n", title)
+	if f.Body != nil {
+		writeMarkdownBody(buf, f.Body, f.Bytes, f.start.Byte, 2)
+	}
+	return buf.Bytes()
+}
+
+// writeMarkdownBody writes the reference for the given body, whose source
+// code is within the given source starting at the given byte offset.
+func writeMarkdownBody(buf *bytes.Buffer, body *Body, src []byte, base int, level int) {
+	var attrs []*Attribute
+	var blocks []*Block
+	for _, item := range bodyItems(body) {
+		switch item := item.(type) {
+		case *Attribute:
+			attrs = append(attrs, item)
+		case *Block:
+			blocks = append(blocks, item)
+		}
+	}
+
+	if len(attrs) != 0 {
+		buf.WriteString("\n| Attribute | Value | Description |\n| --- | --- | --- |\n")
+		for _, attr := range attrs {
+			rng := attr.Expr.Range()
+			value := markdownTableText(string(src[rng.Start.Byte-base : rng.End.Byte-base]))
+			fmt.Fprintf(buf, "| `%s` | `%s` | %s |\n", attr.Name, value, markdownTableText(attr.Doc.Text()))
+		}
+	}
+
+	// Markdown has only six levels of heading, so any blocks nested more
+	// deeply than that share the last level.
+	heading := strings.Repeat("#", level)
+	if level > 6 {
+		heading = "######"
+	}
+	for _, block := range blocks {
+		fmt.Fprintf(buf, "\n%s %s", heading, block.Type)
+		for _, label := range block.Labels {
+			fmt.Fprintf(buf, " %q", label)
+		}
+		buf.WriteString("\n")
+		if text := block.Doc.Text(); text != "" {
+			fmt.Fprintf(buf, "\n%s\n", text)
+		}
+		if block.Body != nil {
+			writeMarkdownBody(buf, block.Body, src, base, level+1)
+		}
+	}
+}
+
+// markdownTableText returns the given text as it can be written within a
+// cell of a Markdown table, on a single line.
+func markdownTableText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
@@ -0,0 +1,77 @@
+package quosyntax
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestMarkdownReference(t *testing.T) {
+	cfg := `# Quoting strategy.
+strategy "grid" {
+  spread = var.max_spread * 0.5 // Half of the | maximum.
+  leg "buy" {
+    # Order size,
+    # in lots.
+    size = {
+      min = 1
+    }
+  }
+}
+levels = 3
+`
+	file, diags := ParseFile([]byte(cfg), "strategy.quo", hcl.Pos{Byte: 0, Line: 1, Column: 1})
+	if len(diags) != 0 {
+		t.Fatalf("Unexpected diagnostics: %s", diags)
+	}
+
+	got := string(file.MarkdownReference("Grid strategy"))
+	want := "# Grid strategy\n" +
+		"\n" +
+		"| Attribute | Value | Description |\n" +
+		"| --- | --- | --- |\n" +
+		"| `levels` | `3` |  |\n" +
+		"\n" +
+		"## strategy \"grid\"\n" +
+		"\n" +
+		"Quoting strategy.\n" +
+		"\n" +
+		"| Attribute | Value | Description |\n" +
+		"| --- | --- | --- |\n" +
+		"| `spread` | `var.max_spread * 0.5` | Half of the \\| maximum. |\n" +
+		"\n" +
+		"### leg \"buy\"\n" +
+		"\n" +
+		"| Attribute | Value | Description |\n" +
+		"| --- | --- | --- |\n" +
+		"| `size` | `{ min = 1 }` | Order size, in lots. |\n"
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownReferenceStart(t *testing.T) {
+	// The file is a fragment of a larger document, so its positions don't
+	// start at the beginning.
+	cfg := "leg \"buy\" {\n  price = mid - var.spread\n}\nlevels = 3\n"
+	file, diags := ParseFile([]byte(cfg), "strategy.quo", hcl.Pos{Byte: 120, Line: 8, Column: 1})
+	if len(diags) != 0 {
+		t.Fatalf("Unexpected diagnostics: %s", diags)
+	}
+
+	got := string(file.MarkdownReference("Legs"))
+	want := "# Legs\n" +
+		"\n" +
+		"| Attribute | Value | Description |\n" +
+		"| --- | --- | --- |\n" +
+		"| `levels` | `3` |  |\n" +
+		"\n" +
+		"## leg \"buy\"\n" +
+		"\n" +
+		"| Attribute | Value | Description |\n" +
+		"| --- | --- | --- |\n" +
+		"| `price` | `mid - var.spread` |  |\n"
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -3,57 +3,159 @@ package quosyntax
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 )
 
 type navigation struct {
 	root *Body
+
+	// src is the source code of the file, used to recognize template if
+	// directives, which are otherwise indistinguishable from conditional
+	// expressions. It may be nil, in which case if directives are not
+	// included in the context.
+	src []byte
+
+	// start is the position of the start of src, as given when parsing the
+	// file.
+	start hcl.Pos
 }
 
 // Implementation of hcled.ContextString
 func (n navigation) ContextString(offset int) string {
-	// We will walk our top-level blocks until we find one that contains
-	// the given offset, and then construct a representation of the header
-	// of the block.
-
-	var block *Block
-	for _, candidate := range n.root.Blocks {
-		if candidate.Range().ContainsOffset(offset) {
-			block = candidate
-			break
+	// We will find the nested blocks, attributes and template directives
+	// that contain the given offset, and then construct a representation
+	// of the header of each of them, from outermost to innermost.
+
+	nodes := n.contextNodes(offset)
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		switch node := node.(type) {
+		case *Block:
+			if len(node.Labels) == 0 {
+				// Easy case!
+				parts[i] = node.Type
+				continue
+			}
+
+			buf := &bytes.Buffer{}
+			buf.WriteString(node.Type)
+			for _, label := range node.Labels {
+				fmt.Fprintf(buf, " %q", label)
+			}
+			parts[i] = buf.String()
+		case *Attribute:
+			parts[i] = fmt.Sprintf("attribute %q", node.Name)
+		case *ForExpr:
+			if node.KeyVar != "" {
+				parts[i] = fmt.Sprintf("%%{ for %s, %s }", node.KeyVar, node.ValVar)
+			} else {
+				parts[i] = fmt.Sprintf("%%{ for %s }", node.ValVar)
+			}
+		case *ConditionalExpr:
+			parts[i] = "%{ if }"
 		}
 	}
+	return strings.Join(parts, " > ")
+}
 
-	if block == nil {
-		return ""
+func (n navigation) ContextDefRange(offset int) hcl.Range {
+	nodes := n.contextNodes(offset)
+	if len(nodes) == 0 {
+		return hcl.Range{}
 	}
 
-	if len(block.Labels) == 0 {
-		// Easy case!
-		return block.Type
+	switch node := nodes[len(nodes)-1].(type) {
+	case *Block:
+		return node.DefRange()
+	case *Attribute:
+		return hcl.RangeBetween(node.NameRange, node.EqualsRange)
+	case *ForExpr:
+		return node.OpenRange
+	case *ConditionalExpr:
+		return n.ifDirectiveRange(node)
+	default:
+		// should never happen, since contextNodes only returns the above
+		return hcl.Range{}
 	}
+}
 
-	buf := &bytes.Buffer{}
-	buf.WriteString(block.Type)
-	for _, label := range block.Labels {
-		fmt.Fprintf(buf, " %q", label)
+// ContextDoc returns the documentation of the innermost block or attribute
+// that contains the given byte offset, or the empty string if there is no
+// such item or it has no doc comments.
+func (n navigation) ContextDoc(offset int) string {
+	nodes := n.contextNodes(offset)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		switch node := nodes[i].(type) {
+		case *Block:
+			return node.Doc.Text()
+		case *Attribute:
+			return node.Doc.Text()
+		}
 	}
-	return buf.String()
+	return ""
 }
 
-func (n navigation) ContextDefRange(offset int) hcl.Range {
-	var block *Block
-	for _, candidate := range n.root.Blocks {
-		if candidate.Range().ContainsOffset(offset) {
-			block = candidate
-			break
+// contextNodes returns the blocks, attributes and template for and if
+// directives that contain the given offset, from outermost to innermost.
+func (n navigation) contextNodes(offset int) []Node {
+	path := n.root.NodePathAtPos(hcl.Pos{Byte: offset})
+
+	var ret []Node
+	for i, node := range path {
+		switch node := node.(type) {
+		case *Block, *Attribute:
+			ret = append(ret, node)
+		case *ForExpr:
+			// A for directive is the only way to produce a ForExpr wrapped
+			// in a TemplateJoinExpr.
+			if _, isJoin := path[i-1].(*TemplateJoinExpr); isJoin {
+				ret = append(ret, node)
+			}
+		case *ConditionalExpr:
+			if n.isIfDirective(node) {
+				ret = append(ret, node)
+			}
 		}
 	}
+	return ret
+}
 
-	if block == nil {
-		return hcl.Range{}
+// isIfDirective returns true if the given conditional expression was
+// produced by an if directive in a template, rather than by the conditional
+// operator.
+func (n navigation) isIfDirective(e *ConditionalExpr) bool {
+	start := e.SrcRange.Start.Byte
+	if start < 0 || start >= len(n.src) {
+		return false
 	}
+	return bytes.HasPrefix(n.src[start:], []byte("%{"))
+}
+
+// ifDirectiveRange returns the range of the opening if directive of the
+// given conditional expression, which must be one for which isIfDirective
+// returns true.
+func (n navigation) ifDirectiveRange(e *ConditionalExpr) hcl.Range {
+	rng := hcl.RangeBetween(e.SrcRange, e.Condition.Range())
 
-	return block.DefRange()
+	// The directive continues after its condition with an optional strip
+	// marker and then the closing brace, which we'll include if we can find
+	// it on the same line.
+	for i := rng.End.Byte; i < len(n.src); i++ {
+		switch n.src[i] {
+		case ' ', '\t', '~':
+			continue
+		case '}':
+			extra := i + 1 - rng.End.Byte
+			rng.End.Byte += extra
+			rng.End.Column += extra
+		}
+		break
+	}
+	return rng
 }
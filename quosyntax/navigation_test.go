@@ -131,3 +131,113 @@ data "another" "baz" {
 		})
 	}
 }
+
+func TestNavigationNested(t *testing.T) {
+	cfg := `strategy "mm" {
+  leg "buy" {
+    price = 1
+    note = "%{ for k, v in x }${v}%{ if v ~}a%{ endif }%{ endfor }"
+  }
+}
+`
+	file, diags := ParseFile([]byte(cfg), "", hcl.Pos{Byte: 0, Line: 1, Column: 1})
+	if len(diags) != 0 {
+		t.Fatalf("Unexpected diagnostics: %s", diags)
+	}
+	nav := file.AsHCLFile().Nav.(navigation)
+
+	testCases := []struct {
+		Offset    int
+		Want      string
+		WantRange hcl.Range
+	}{
+		{
+			4,
+			`strategy "mm"`,
+			hcl.Range{Start: hcl.Pos{Line: 1, Column: 1, Byte: 0}, End: hcl.Pos{Line: 1, Column: 16, Byte: 15}},
+		},
+		{
+			20,
+			`strategy "mm" > leg "buy"`,
+			hcl.Range{Start: hcl.Pos{Line: 2, Column: 3, Byte: 18}, End: hcl.Pos{Line: 2, Column: 14, Byte: 29}},
+		},
+		{
+			42,
+			`strategy "mm" > leg "buy" > attribute "price"`,
+			hcl.Range{Start: hcl.Pos{Line: 3, Column: 5, Byte: 34}, End: hcl.Pos{Line: 3, Column: 12, Byte: 41}},
+		},
+		{
+			44,
+			`strategy "mm" > leg "buy"`,
+			hcl.Range{Start: hcl.Pos{Line: 2, Column: 3, Byte: 18}, End: hcl.Pos{Line: 2, Column: 14, Byte: 29}},
+		},
+		{
+			76,
+			`strategy "mm" > leg "buy" > attribute "note" > %{ for k, v }`,
+			hcl.Range{Start: hcl.Pos{Line: 4, Column: 13, Byte: 56}, End: hcl.Pos{Line: 4, Column: 31, Byte: 74}},
+		},
+		{
+			88,
+			`strategy "mm" > leg "buy" > attribute "note" > %{ for k, v } > %{ if }`,
+			hcl.Range{Start: hcl.Pos{Line: 4, Column: 35, Byte: 78}, End: hcl.Pos{Line: 4, Column: 45, Byte: 88}},
+		},
+		{
+			116,
+			`strategy "mm"`,
+			hcl.Range{Start: hcl.Pos{Line: 1, Column: 1, Byte: 0}, End: hcl.Pos{Line: 1, Column: 16, Byte: 15}},
+		},
+		{
+			118,
+			``,
+			hcl.Range{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strconv.Itoa(tc.Offset), func(t *testing.T) {
+			if got := nav.ContextString(tc.Offset); got != tc.Want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, tc.Want)
+			}
+			if got := nav.ContextDefRange(tc.Offset); got != tc.WantRange {
+				t.Errorf("wrong range\ngot:  %#v\nwant: %#v", got, tc.WantRange)
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	cfg := `# leading comment
+a = 1 // trailing comment
+`
+	file, diags := ParseFile([]byte(cfg), "", hcl.Pos{Byte: 0, Line: 1, Column: 1})
+	if len(diags) != 0 {
+		t.Fatalf("Unexpected diagnostics: %s", diags)
+	}
+
+	if got, want := len(file.Tokens), 6; got != want {
+		t.Errorf("wrong number of tokens %d; want %d", got, want)
+	}
+	if got := file.Tokens[len(file.Tokens)-1].Type; got != TokenEOF {
+		t.Errorf("wrong final token type %s; want %s", got, TokenEOF)
+	}
+
+	var gotComments []string
+	for _, tok := range file.Comments {
+		gotComments = append(gotComments, string(tok.Bytes))
+	}
+	wantComments := []string{"# leading comment\n", "// trailing comment\n"}
+	if fmt.Sprintf("%q", gotComments) != fmt.Sprintf("%q", wantComments) {
+		t.Errorf("wrong comments\ngot:  %q\nwant: %q", gotComments, wantComments)
+	}
+
+	hclFile := file.AsHCLFile()
+	if hclFile.Body != file.Body {
+		t.Errorf("wrong body in hcl.File")
+	}
+	if got, want := hclFile.Nav.(navigation).ContextString(18), `attribute "a"`; got != want {
+		t.Errorf("wrong context string\ngot:  %s\nwant: %s", got, want)
+	}
+	if got, want := file.ContextDoc(18), "leading comment\ntrailing comment"; got != want {
+		t.Errorf("wrong context doc\ngot:  %q\nwant: %q", got, want)
+	}
+}
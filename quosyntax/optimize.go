@@ -0,0 +1,335 @@
+package quosyntax
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Optimize returns an expression that is equivalent to the given expression
+// but that can be evaluated more cheaply, by evaluating ahead of time the
+// parts of it that do not depend on any unknown variables.
+//
+// knownVars gives the variables whose values are already known, which are
+// inlined wherever they are referenced. Only wholly-known values are inlined;
+// any other variables are left to be resolved at evaluation time.
+//
+// Each subexpression whose result depends only on literals and known
+// variables is replaced by a LiteralValueExpr with the same source range as
+// the subexpression it replaces, so that any diagnostics produced when
+// evaluating the result still refer to the original source text. A
+// subexpression that would produce diagnostics when evaluated is never
+// replaced, so evaluating the optimized expression produces the same
+// diagnostics as evaluating the original.
+//
+// A conditional expression whose condition is known is replaced by the
+// selected result expression, as long as doing so can't change the type of
+// the result whatever the values of the variables that are not known.
+//
+// Function calls are never evaluated ahead of time, because functions are
+// not necessarily pure, but their arguments are optimized.
+//
+// The given expression is not modified. The result may share nodes with it.
+func Optimize(expr Expression, knownVars map[string]cty.Value) Expression {
+	o := &optimizer{}
+	for name, val := range knownVars {
+		if !val.IsWhollyKnown() {
+			continue
+		}
+		if o.vars == nil {
+			o.vars = make(map[string]cty.Value)
+		}
+		o.vars[name] = val
+	}
+	return o.optimize(expr)
+}
+
+// optimizer is the implementation of Optimize.
+type optimizer struct {
+	// vars are the known variables that are in scope for the expression
+	// currently being optimized.
+	vars map[string]cty.Value
+}
+
+func (o *optimizer) optimize(expr Expression) Expression {
+	switch e := expr.(type) {
+	case *LiteralValueExpr:
+		return e
+
+	case *ScopeTraversalExpr:
+		if _, known := o.vars[e.Traversal.RootName()]; !known {
+			return e
+		}
+		val, diags := traverseAbs(e.Traversal, &hcl.EvalContext{
+			Variables: o.vars,
+		})
+		return o.literal(e, val, diags)
+
+	case *RelativeTraversalExpr:
+		ne := *e
+		ne.Source = o.optimize(e.Source)
+		return o.fold(&ne, ne.Source)
+
+	case *FunctionCallExpr:
+		ne := *e
+		ne.Args = o.optimizeAll(e.Args)
+		return &ne
+
+	case *ConditionalExpr:
+		return o.optimizeConditional(e)
+
+	case *IndexExpr:
+		ne := *e
+		ne.Collection = o.optimize(e.Collection)
+		ne.Key = o.optimize(e.Key)
+		return o.fold(&ne, ne.Collection, ne.Key)
+
+	case *TupleConsExpr:
+		ne := *e
+		ne.Exprs = o.optimizeAll(e.Exprs)
+		return o.fold(&ne, ne.Exprs...)
+
+	case *ObjectConsExpr:
+		ne := *e
+		ne.Items = make([]ObjectConsItem, len(e.Items))
+		operands := make([]Expression, 0, len(e.Items)*2)
+		for i, item := range e.Items {
+			ne.Items[i] = ObjectConsItem{
+				KeyExpr:   o.optimize(item.KeyExpr),
+				ValueExpr: o.optimize(item.ValueExpr),
+			}
+			operands = append(operands, ne.Items[i].KeyExpr, ne.Items[i].ValueExpr)
+		}
+		return o.fold(&ne, operands...)
+
+	case *ObjectConsKeyExpr:
+		if _, isTraversal := e.Wrapped.(*ScopeTraversalExpr); isTraversal && !e.ForceNonLiteral {
+			// A naked identifier is a literal key rather than a reference,
+			// and a naked traversal is an error, so we must leave these
+			// as-is to preserve their meaning.
+			return e
+		}
+		ne := *e
+		ne.Wrapped = o.optimize(e.Wrapped)
+		return &ne
+
+	case *ForExpr:
+		ne := *e
+		ne.CollExpr = o.optimize(e.CollExpr)
+
+		// The iteration symbols shadow any known variables of the same
+		// names within the other clauses.
+		inner := o.shadow(e.KeyVar, e.ValVar)
+		if e.KeyExpr != nil {
+			ne.KeyExpr = inner.optimize(e.KeyExpr)
+		}
+		ne.ValExpr = inner.optimize(e.ValExpr)
+		if e.CondExpr != nil {
+			ne.CondExpr = inner.optimize(e.CondExpr)
+		}
+		return o.foldClosed(&ne)
+
+	case *SplatExpr:
+		ne := *e
+		ne.Source = o.optimize(e.Source)
+		ne.Each = o.optimize(e.Each)
+		return o.foldClosed(&ne)
+
+	case *AnonSymbolExpr:
+		// The symbol belongs to its splat expression, so must be preserved
+		// exactly as-is.
+		return e
+
+	case *BinaryOpExpr:
+		ne := *e
+		ne.LHS = o.optimize(e.LHS)
+		ne.RHS = o.optimize(e.RHS)
+		return o.fold(&ne, ne.LHS, ne.RHS)
+
+	case *UnaryOpExpr:
+		ne := *e
+		ne.Val = o.optimize(e.Val)
+		return o.fold(&ne, ne.Val)
+
+	case *TemplateExpr:
+		ne := *e
+		ne.Parts = o.optimizeAll(e.Parts)
+		return o.fold(&ne, ne.Parts...)
+
+	case *TemplateJoinExpr:
+		ne := *e
+		ne.Tuple = o.optimize(e.Tuple)
+		return o.fold(&ne, ne.Tuple)
+
+	case *TemplateWrapExpr:
+		ne := *e
+		ne.Wrapped = o.optimize(e.Wrapped)
+		return o.fold(&ne, ne.Wrapped)
+
+	default:
+		// We don't know how to optimize this expression, so we'll leave it
+		// unchanged.
+		return expr
+	}
+}
+
+func (o *optimizer) optimizeAll(exprs []Expression) []Expression {
+	if exprs == nil {
+		return nil
+	}
+	ret := make([]Expression, len(exprs))
+	for i, expr := range exprs {
+		ret[i] = o.optimize(expr)
+	}
+	return ret
+}
+
+func (o *optimizer) optimizeConditional(e *ConditionalExpr) Expression {
+	ne := *e
+	ne.Condition = o.optimize(e.Condition)
+	ne.TrueResult = o.optimize(e.TrueResult)
+	ne.FalseResult = o.optimize(e.FalseResult)
+
+	cond, isLit := ne.Condition.(*LiteralValueExpr)
+	if !isLit {
+		return &ne
+	}
+	folded := o.fold(&ne, ne.Condition, ne.TrueResult, ne.FalseResult)
+	if _, isLit := folded.(*LiteralValueExpr); isLit {
+		return folded
+	}
+
+	condVal, err := convert.Convert(cond.Val, cty.Bool)
+	if err != nil || condVal.IsNull() {
+		// Evaluation will report the invalid condition.
+		return &ne
+	}
+
+	selected, other := ne.TrueResult, ne.FalseResult
+	if condVal.False() {
+		selected, other = other, selected
+	}
+
+	// Evaluation converts the selected result to a type that both results
+	// can convert to, so we can only drop the other result if that can't
+	// change the type of the selected one.
+	if lit, isLit := other.(*LiteralValueExpr); isLit && lit.Val.RawEquals(cty.NullVal(cty.DynamicPseudoType)) {
+		return selected
+	}
+	typeCtx := o.typeContext(selected, other)
+	selectedTy, _ := InferType(selected, typeCtx)
+	otherTy, _ := InferType(other, typeCtx)
+	if selectedTy == cty.DynamicPseudoType || otherTy == cty.DynamicPseudoType {
+		return &ne
+	}
+	resultTy, _ := convert.UnifyUnsafe([]cty.Type{selectedTy, otherTy})
+	if resultTy == cty.NilType || !resultTy.Equals(selectedTy) {
+		return &ne
+	}
+	return selected
+}
+
+// fold evaluates the given expression ahead of time if all of the given
+// operands, which must be the expressions the given expression is built
+// from, are literals. If so, the result is a literal, and otherwise the
+// given expression is returned verbatim.
+func (o *optimizer) fold(expr Expression, operands ...Expression) Expression {
+	for _, operand := range operands {
+		if !isConstant(operand) {
+			return expr
+		}
+	}
+	val, diags := expr.Value(nil)
+	return o.literal(expr, val, diags)
+}
+
+// foldClosed evaluates the given expression ahead of time if it refers to no
+// variables and calls no functions. This is for expressions that introduce
+// their own local symbols, whose operands therefore can't be folded
+// separately.
+func (o *optimizer) foldClosed(expr Expression) Expression {
+	if len(Variables(expr)) != 0 {
+		return expr
+	}
+	calls := false
+	VisitAll(expr, func(node Node) hcl.Diagnostics {
+		if _, isCall := node.(*FunctionCallExpr); isCall {
+			calls = true
+		}
+		return nil
+	})
+	if calls {
+		return expr
+	}
+	val, diags := expr.Value(nil)
+	return o.literal(expr, val, diags)
+}
+
+// isConstant returns true if the given expression, which must already have
+// been optimized, is a literal.
+func isConstant(expr Expression) bool {
+	switch e := expr.(type) {
+	case *LiteralValueExpr:
+		return true
+	case *ObjectConsKeyExpr:
+		if _, isTraversal := e.Wrapped.(*ScopeTraversalExpr); isTraversal && !e.ForceNonLiteral {
+			// This is either a literal name or an ambiguous key, and in the
+			// latter case the evaluation will fail and so prevent folding.
+			return true
+		}
+		return isConstant(e.Wrapped)
+	default:
+		return false
+	}
+}
+
+// literal returns a literal expression for the given result of evaluating
+// the given expression, or the expression itself if the result is not
+// suitable for a literal.
+func (o *optimizer) literal(expr Expression, val cty.Value, diags hcl.Diagnostics) Expression {
+	if len(diags) != 0 || !val.IsWhollyKnown() {
+		return expr
+	}
+	return &LiteralValueExpr{
+		Val:      val,
+		SrcRange: expr.Range(),
+	}
+}
+
+// shadow returns an optimizer for a child scope where the given names are
+// local symbols, and thus no longer refer to known variables.
+func (o *optimizer) shadow(keyVar, valVar string) *optimizer {
+	var vars map[string]cty.Value
+	for name, val := range o.vars {
+		if name == keyVar || name == valVar {
+			continue
+		}
+		if vars == nil {
+			vars = make(map[string]cty.Value)
+		}
+		vars[name] = val
+	}
+	return &optimizer{vars: vars}
+}
+
+// typeContext returns a TypeContext describing the known variables. Any
+// other variables that the given expressions refer to are described as
+// cty.DynamicPseudoType, so that the types inferred for the expressions are
+// those that hold whatever the values of the unknown variables turn out to
+// be.
+func (o *optimizer) typeContext(exprs ...Expression) *TypeContext {
+	ctx := &TypeContext{
+		Variables: make(map[string]cty.Type, len(o.vars)),
+	}
+	for name, val := range o.vars {
+		ctx.Variables[name] = val.Type()
+	}
+	for _, expr := range exprs {
+		for _, traversal := range Variables(expr) {
+			if _, known := ctx.Variables[traversal.RootName()]; !known {
+				ctx.Variables[traversal.RootName()] = cty.DynamicPseudoType
+			}
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,195 @@
+package quosyntax
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+func TestOptimize(t *testing.T) {
+	known := map[string]cty.Value{
+		"fee":     quoty.MustParseNumberVal("0.0025"),
+		"live":    cty.True,
+		"x":       quoty.NumberIntVal(100),
+		"name":    cty.StringVal("XLM"),
+		"pending": cty.UnknownVal(quoty.Number),
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"fee":     quoty.MustParseNumberVal("0.0025"),
+			"live":    cty.True,
+			"x":       quoty.NumberIntVal(100),
+			"name":    cty.StringVal("XLM"),
+			"pending": quoty.NumberIntVal(3),
+			"price":   quoty.NumberIntVal(8),
+			"prices":  cty.ListVal([]cty.Value{quoty.NumberIntVal(1), quoty.NumberIntVal(2)}),
+		},
+		Functions: map[string]function.Function{
+			"upper": stdlib.UpperFunc,
+		},
+	}
+
+	tests := []struct {
+		input string
+		want  string // the type of the root node of the result
+	}{
+		{
+			`price * (1 + 25/10000)`,
+			"*quosyntax.BinaryOpExpr",
+		},
+		{
+			`(1 + 25/10000) * 2`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`fee * 2`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`pending * 2`,
+			"*quosyntax.BinaryOpExpr",
+		},
+		{
+			`"${name}/USD"`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`"${name}/${price}"`,
+			"*quosyntax.TemplateExpr",
+		},
+		{
+			`upper(name)`,
+			"*quosyntax.FunctionCallExpr",
+		},
+		{
+			`live ? price * (1 + fee) : 0`,
+			"*quosyntax.BinaryOpExpr",
+		},
+		{
+			`live ? pending * 2 : 0`,
+			"*quosyntax.BinaryOpExpr",
+		},
+		{
+			`!live ? 0 : price * fee`,
+			"*quosyntax.BinaryOpExpr",
+		},
+		{
+			`live ? price : null`,
+			"*quosyntax.ScopeTraversalExpr",
+		},
+		{
+			// The result type of price isn't known, so the conditional
+			// might still need to convert it.
+			`live ? price : 0`,
+			"*quosyntax.ConditionalExpr",
+		},
+		{
+			`live ? fee : 0`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`price > 1 ? fee : 0`,
+			"*quosyntax.ConditionalExpr",
+		},
+		{
+			`{ fee = fee, name = name }`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`{ (name) = fee }.XLM`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`[fee, x][1]`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`[for v in [1, 2]: v * fee]`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			// x is shadowed by the iteration symbol
+			`[for x in prices: x * 2]`,
+			"*quosyntax.ForExpr",
+		},
+		{
+			`[for x in [1, 2]: x * 2]`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`[{ a = fee }, { a = x }][*].a`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			`"%{ for v in [1, 2] }${v}%{ endfor }"`,
+			"*quosyntax.LiteralValueExpr",
+		},
+		{
+			// Must not be folded, because it produces an error
+			`price + (name + 1)`,
+			"*quosyntax.BinaryOpExpr",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+			wantVal, wantDiags := expr.Value(ctx)
+
+			got := Optimize(expr, known)
+
+			if gotType := fmt.Sprintf("%T", got); gotType != test.want {
+				t.Errorf("wrong result node type %s; want %s", gotType, test.want)
+			}
+			if _, folded := got.(*LiteralValueExpr); folded && got.Range() != expr.Range() {
+				t.Errorf("wrong result range\ngot:  %#v\nwant: %#v", got.Range(), expr.Range())
+			}
+
+			gotVal, gotDiags := got.Value(ctx)
+			if !gotVal.RawEquals(wantVal) {
+				t.Errorf("wrong result value\ngot:  %#v\nwant: %#v", gotVal, wantVal)
+			}
+			if len(gotDiags) != len(wantDiags) {
+				t.Fatalf("wrong number of diagnostics %d; want %d", len(gotDiags), len(wantDiags))
+			}
+			for i := range gotDiags {
+				if got, want := gotDiags[i].Error(), wantDiags[i].Error(); got != want {
+					t.Errorf("wrong diagnostic %d\ngot:  %s\nwant: %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizeRanges(t *testing.T) {
+	src := `price * (1 + 25/10000)`
+	expr, parseDiags := ParseExpression([]byte(src), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+	orig := expr.(*BinaryOpExpr)
+
+	got := Optimize(expr, nil).(*BinaryOpExpr)
+
+	lit, ok := got.RHS.(*LiteralValueExpr)
+	if !ok {
+		t.Fatalf("RHS is %T; want *quosyntax.LiteralValueExpr", got.RHS)
+	}
+	if got, want := lit.Val, quoty.MustParseNumberVal("1.0025"); !got.RawEquals(want) {
+		t.Errorf("wrong folded value\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if got, want := lit.SrcRange, orig.RHS.Range(); got != want {
+		t.Errorf("wrong folded range\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if _, ok := orig.RHS.(*LiteralValueExpr); ok {
+		t.Errorf("original expression was modified")
+	}
+}
@@ -0,0 +1,81 @@
+package quosyntax
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+)
+
+// evalOptions are the options for evaluating native syntax expressions that
+// an EvalContext can carry, as set by WithLimits, WithPrecision and
+// WithTracer.
+type evalOptions struct {
+	budget    *budget
+	precision *quoty.Precision
+	tracer    Tracer
+}
+
+// contextOptions holds the options carried by the EvalContexts returned by
+// withOptions, keyed by their addresses. Those contexts have no variables or
+// functions of their own, so that the options are invisible to anything that
+// enumerates them, and each entry is removed when its EvalContext is garbage
+// collected.
+//
+// contextCount is the number of entries, which lets evaluation skip looking
+// for options when there are none.
+var (
+	contextOptions sync.Map // map[uintptr]*evalOptions
+	contextCount   int64    // updated atomically
+)
+
+// withOptions returns a child of the given EvalContext that carries the
+// options of the given context, if any, as modified by the given function.
+func withOptions(evalCtx *hcl.EvalContext, modify func(opts *evalOptions)) *hcl.EvalContext {
+	opts := &evalOptions{}
+	if parent := optionsFor(evalCtx); parent != nil {
+		*opts = *parent
+	}
+	modify(opts)
+
+	ret := evalCtx.NewChild()
+	contextOptions.Store(contextKey(ret), opts)
+	atomic.AddInt64(&contextCount, 1)
+	runtime.SetFinalizer(ret, releaseOptions)
+	return ret
+}
+
+// releaseOptions is the finalizer of the EvalContexts returned by
+// withOptions, which removes their options.
+//
+// The garbage collector doesn't reuse the memory of an object until after
+// its finalizer has run, so no other EvalContext can have the same key
+// until the entry has been removed.
+func releaseOptions(ctx *hcl.EvalContext) {
+	contextOptions.Delete(contextKey(ctx))
+	atomic.AddInt64(&contextCount, -1)
+}
+
+// optionsFor returns the options carried by the given EvalContext or the
+// nearest of its ancestors that carries any, or nil if there are none.
+func optionsFor(ctx *hcl.EvalContext) *evalOptions {
+	if atomic.LoadInt64(&contextCount) == 0 {
+		return nil
+	}
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		if opts, exists := contextOptions.Load(contextKey(thisCtx)); exists {
+			return opts.(*evalOptions)
+		}
+	}
+	return nil
+}
+
+// contextKey returns the key of the given EvalContext in contextOptions. It
+// is the address rather than the pointer itself, so that the entry doesn't
+// keep the EvalContext alive.
+func contextKey(ctx *hcl.EvalContext) uintptr {
+	return reflect.ValueOf(ctx).Pointer()
+}
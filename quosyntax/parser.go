@@ -3,6 +3,7 @@ package quosyntax
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"strconv"
 	"unicode/utf8"
 
@@ -655,7 +656,7 @@ Traversal:
 
 				rng := hcl.RangeBetween(dot.Range, numTok.Range)
 				step := hcl.TraverseIndex{
-					Key:      numVal,
+					Key:      traversalKey(numVal),
 					SrcRange: rng,
 				}
 
@@ -705,7 +706,7 @@ Traversal:
 						numVal, numDiags := p.numberLitValue(numTok)
 						diags = append(diags, numDiags...)
 						trav = append(trav, hcl.TraverseIndex{
-							Key:      numVal,
+							Key:      traversalKey(numVal),
 							SrcRange: hcl.RangeBetween(dot.Range, numTok.Range),
 						})
 						lastRange = numTok.Range
@@ -850,7 +851,7 @@ Traversal:
 					litKey, _ := lit.Value(nil)
 					rng := hcl.RangeBetween(open.Range, close.Range)
 					step := hcl.TraverseIndex{
-						Key:      litKey,
+						Key:      traversalKey(litKey),
 						SrcRange: rng,
 					}
 					ret = makeRelativeTraversal(ret, step, rng)
@@ -1087,6 +1088,25 @@ func (p *parser) numberLitValue(tok Token) (cty.Value, hcl.Diagnostics) {
 	return numVal, nil
 }
 
+// traversalKey returns the key to use in a traversal step for the given
+// literal index key.
+//
+// The steps of a traversal are applied by cty, which only accepts a
+// cty.Number to index a list or tuple, so a whole quoty.Number is given as
+// the equivalent cty.Number, which represents it exactly. Any other key is
+// returned unchanged, so a fractional number is rejected when the traversal
+// is applied.
+func traversalKey(key cty.Value) cty.Value {
+	if !key.Type().Equals(quoty.Number) || !key.IsKnown() || key.IsNull() {
+		return key
+	}
+	br := key.EncapsulatedValue().(*big.Rat)
+	if !br.IsInt() {
+		return key
+	}
+	return cty.NumberVal(new(big.Float).SetInt(br.Num()))
+}
+
 // finishParsingFunctionCall parses a function call assuming that the function
 // name was already read, and so the peeker should be pointing at the opening
 // parenthesis after the name.
@@ -1437,6 +1457,7 @@ func (p *parser) finishParsingForExpr(open Token) (Expression, hcl.Diagnostics)
 
 	var diags hcl.Diagnostics
 	var keyName, valName string
+	var keyRange, valRange hcl.Range
 
 	if p.Peek().Type != TokenIdent {
 		if !p.recovery {
@@ -1455,11 +1476,12 @@ func (p *parser) finishParsingForExpr(open Token) (Expression, hcl.Diagnostics)
 		}, diags
 	}
 
-	valName = string(p.Read().Bytes)
+	valTok := p.Read()
+	valName, valRange = string(valTok.Bytes), valTok.Range
 
 	if p.Peek().Type == TokenComma {
 		// What we just read was actually the key, then.
-		keyName = valName
+		keyName, keyRange = valName, valRange
 		p.Read() // eat comma
 
 		if p.Peek().Type != TokenIdent {
@@ -1479,7 +1501,8 @@ func (p *parser) finishParsingForExpr(open Token) (Expression, hcl.Diagnostics)
 			}, diags
 		}
 
-		valName = string(p.Read().Bytes)
+		valTok = p.Read()
+		valName, valRange = string(valTok.Bytes), valTok.Range
 	}
 
 	if !inKeyword.TokenMatches(p.Peek()) {
@@ -1627,9 +1650,11 @@ func (p *parser) finishParsingForExpr(open Token) (Expression, hcl.Diagnostics)
 		CondExpr: condExpr,
 		Group:    group,
 
-		SrcRange:   hcl.RangeBetween(open.Range, close.Range),
-		OpenRange:  open.Range,
-		CloseRange: close.Range,
+		SrcRange:    hcl.RangeBetween(open.Range, close.Range),
+		OpenRange:   open.Range,
+		CloseRange:  close.Range,
+		KeyVarRange: keyRange,
+		ValVarRange: valRange,
 	}, diags
 }
 
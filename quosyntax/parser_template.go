@@ -7,6 +7,7 @@ import (
 
 	"github.com/apparentlymart/go-textseg/textseg"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -331,9 +332,11 @@ Token:
 		CollExpr: openFor.CollExpr,
 		ValExpr:  contentExpr,
 
-		SrcRange:   hcl.RangeBetween(openFor.SrcRange, endforRange),
-		OpenRange:  openFor.SrcRange,
-		CloseRange: endforRange,
+		SrcRange:    hcl.RangeBetween(openFor.SrcRange, endforRange),
+		OpenRange:   openFor.SrcRange,
+		CloseRange:  endforRange,
+		KeyVarRange: openFor.KeyVarRange,
+		ValVarRange: openFor.ValVarRange,
 	}
 
 	return &TemplateJoinExpr{
@@ -491,6 +494,7 @@ Token:
 
 			case forKeyword.TokenMatches(kw):
 				var keyName, valName string
+				var keyRange, valRange hcl.Range
 				if p.Peek().Type != TokenIdent {
 					if !p.recovery {
 						diags = append(diags, &hcl.Diagnostic{
@@ -505,11 +509,12 @@ Token:
 					continue Token
 				}
 
-				valName = string(p.Read().Bytes)
+				valTok := p.Read()
+				valName, valRange = string(valTok.Bytes), valTok.Range
 
 				if p.Peek().Type == TokenComma {
 					// What we just read was actually the key, then.
-					keyName = valName
+					keyName, keyRange = valName, valRange
 					p.Read() // eat comma
 
 					if p.Peek().Type != TokenIdent {
@@ -526,7 +531,8 @@ Token:
 						continue Token
 					}
 
-					valName = string(p.Read().Bytes)
+					valTok = p.Read()
+					valName, valRange = string(valTok.Bytes), valTok.Range
 				}
 
 				if !inKeyword.TokenMatches(p.Peek()) {
@@ -551,7 +557,9 @@ Token:
 					ValVar:   valName,
 					CollExpr: collExpr,
 
-					SrcRange: hcl.RangeBetween(next.Range, p.NextRange()),
+					SrcRange:    hcl.RangeBetween(next.Range, p.NextRange()),
+					KeyVarRange: keyRange,
+					ValVarRange: valRange,
 				})
 
 			case endforKeyword.TokenMatches(kw):
@@ -564,7 +572,7 @@ Token:
 				if !p.recovery {
 					suggestions := []string{"if", "for", "else", "endif", "endfor"}
 					given := string(kw.Bytes)
-					suggestion := nameSuggestion(given, suggestions)
+					suggestion := quoty.NameSuggestion(given, suggestions)
 					if suggestion != "" {
 						suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
 					}
@@ -756,6 +764,9 @@ type templateForToken struct {
 	ValVar   string
 	CollExpr Expression
 	SrcRange hcl.Range
+
+	KeyVarRange hcl.Range // zero if ignoring key
+	ValVarRange hcl.Range
 	isTemplateToken
 }
 
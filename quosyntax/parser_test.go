@@ -1887,6 +1887,18 @@ block "valid" {}
 								End:   hcl.Pos{Line: 1, Column: 6, Byte: 5},
 							},
 						},
+						Doc: &Doc{
+							Trailing: Tokens{
+								{
+									Type:  TokenComment,
+									Bytes: []byte("# line comment\n"),
+									Range: hcl.Range{
+										Start: hcl.Pos{Line: 1, Column: 7, Byte: 6},
+										End:   hcl.Pos{Line: 2, Column: 1, Byte: 21},
+									},
+								},
+							},
+						},
 
 						SrcRange: hcl.Range{
 							Start: hcl.Pos{Line: 1, Column: 1, Byte: 0},
@@ -1975,6 +1987,14 @@ block "valid" {}
 								Start: hcl.Pos{Line: 1, Column: 32, Byte: 31},
 								End:   hcl.Pos{Line: 1, Column: 33, Byte: 32},
 							},
+							KeyVarRange: hcl.Range{
+								Start: hcl.Pos{Line: 1, Column: 10, Byte: 9},
+								End:   hcl.Pos{Line: 1, Column: 11, Byte: 10},
+							},
+							ValVarRange: hcl.Range{
+								Start: hcl.Pos{Line: 1, Column: 13, Byte: 12},
+								End:   hcl.Pos{Line: 1, Column: 14, Byte: 13},
+							},
 						},
 
 						SrcRange: hcl.Range{
@@ -2079,6 +2099,14 @@ block "valid" {}
 								Start: hcl.Pos{Line: 1, Column: 40, Byte: 39},
 								End:   hcl.Pos{Line: 1, Column: 41, Byte: 40},
 							},
+							KeyVarRange: hcl.Range{
+								Start: hcl.Pos{Line: 1, Column: 10, Byte: 9},
+								End:   hcl.Pos{Line: 1, Column: 11, Byte: 10},
+							},
+							ValVarRange: hcl.Range{
+								Start: hcl.Pos{Line: 1, Column: 13, Byte: 12},
+								End:   hcl.Pos{Line: 1, Column: 14, Byte: 13},
+							},
 						},
 
 						SrcRange: hcl.Range{
@@ -92,7 +92,7 @@ func (p *parser) ParseTraversalAbs() (hcl.Traversal, hcl.Diagnostics) {
 				}
 
 				ret = append(ret, hcl.TraverseIndex{
-					Key:      numVal,
+					Key:      traversalKey(numVal),
 					SrcRange: hcl.RangeBetween(open.Range, close.Range),
 				})
 
@@ -0,0 +1,61 @@
+package quosyntax
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// WithPrecision returns a child of the given EvalContext that, when used to
+// evaluate native syntax expressions, applies the given precision policy to
+// the numbers produced by arithmetic operators and function calls.
+//
+// Each time the policy changes a number, evaluation produces a warning
+// diagnostic pointing at the operation or function call that produced it.
+func WithPrecision(evalCtx *hcl.EvalContext, precision quoty.Precision) *hcl.EvalContext {
+	return withOptions(evalCtx, func(opts *evalOptions) {
+		opts.precision = &precision
+	})
+}
+
+// applyPrecision applies the given precision policy, if any, to the given
+// result of evaluating the given expression, returning a warning if the
+// result was rounded.
+func applyPrecision(p *quoty.Precision, e Expression, ctx *hcl.EvalContext, val cty.Value) (cty.Value, hcl.Diagnostics) {
+	if p == nil {
+		return val, nil
+	}
+	rounded, changed := p.Round(val)
+	if !changed {
+		return val, nil
+	}
+	return rounded, hcl.Diagnostics{
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "Number rounded",
+			Detail: fmt.Sprintf(
+				"The result of this expression, %s, was rounded to %s to limit its precision.",
+				exactNumberString(val.EncapsulatedValue().(*big.Rat)),
+				exactNumberString(rounded.EncapsulatedValue().(*big.Rat)),
+			),
+			Subject:     e.Range().Ptr(),
+			Expression:  e,
+			EvalContext: ctx,
+		},
+	}
+}
+
+// exactNumberString returns a decimal representation of the given number if
+// it has one, or a fraction otherwise.
+func exactNumberString(br *big.Rat) string {
+	if br.IsInt() {
+		return br.Num().String()
+	}
+	if places, exact := quoty.ExactDecimalPlaces(br); exact {
+		return br.FloatString(places)
+	}
+	return br.String()
+}
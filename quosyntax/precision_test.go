@@ -0,0 +1,104 @@
+package quosyntax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quofn"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestWithPrecision(t *testing.T) {
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"price": quoty.NumberIntVal(2),
+		},
+		Functions: map[string]function.Function{
+			"divide": quofn.DivideFunc,
+		},
+	}
+	precision := quoty.Precision{MaxDigits: 4}
+
+	tests := []struct {
+		input    string
+		want     cty.Value
+		warnings []string // the source text of each expression that was rounded
+	}{
+		{
+			`price * 3`,
+			quoty.NumberIntVal(6),
+			nil,
+		},
+		{
+			`price / 3`,
+			quoty.MustParseNumberVal("0.6667"),
+			[]string{`price / 3`},
+		},
+		{
+			`(1 / 3) * 3`,
+			quoty.MustParseNumberVal("0.9999"),
+			[]string{`1 / 3`},
+		},
+		{
+			`divide(price, 7) + 1 / 7`,
+			quoty.MustParseNumberVal("0.4286"),
+			[]string{`divide(price, 7)`, `1 / 7`},
+		},
+		{
+			`[for d in [3, 4]: price / d]`,
+			cty.TupleVal([]cty.Value{quoty.MustParseNumberVal("0.6667"), quoty.MustParseNumberVal("0.5")}),
+			[]string{`price / d`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			check := func(got cty.Value, diags hcl.Diagnostics) {
+				t.Helper()
+				if !got.RawEquals(test.want) {
+					t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.want)
+				}
+				if len(diags) != len(test.warnings) {
+					t.Fatalf("wrong number of diagnostics %d; want %d\n%s", len(diags), len(test.warnings), diags.Error())
+				}
+				for i, diag := range diags {
+					if diag.Severity != hcl.DiagWarning || diag.Summary != "Number rounded" {
+						t.Errorf("unexpected diagnostic %d: %s", i, diag.Error())
+					}
+					rng := diag.Subject
+					if got, want := test.input[rng.Start.Byte:rng.End.Byte], test.warnings[i]; got != want {
+						t.Errorf("wrong subject for diagnostic %d %q; want %q", i, got, want)
+					}
+				}
+			}
+			check(expr.Value(WithPrecision(evalCtx, precision)))
+
+			compiled := Compile(expr, &CompileScope{
+				Variables: []string{"price"},
+				Functions: evalCtx.Functions,
+			})
+			check(compiled.ValueIn(WithPrecision(nil, precision), []cty.Value{evalCtx.Variables["price"]}))
+		})
+	}
+
+	t.Run("with limits", func(t *testing.T) {
+		// The options of nested contexts are combined.
+		expr, _ := ParseExpression([]byte(`1 / 3`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+		ctx := WithLimits(context.Background(), WithPrecision(nil, precision), Limits{MaxSteps: 10})
+		got, diags := expr.Value(ctx)
+		if len(diags) != 1 || diags[0].Summary != "Number rounded" {
+			t.Fatalf("wrong diagnostics: %s", diags.Error())
+		}
+		if want := quoty.MustParseNumberVal("0.3333"); !got.RawEquals(want) {
+			t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+		}
+	})
+}
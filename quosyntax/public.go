@@ -15,6 +15,13 @@ import (
 // should be served using the hcl.Body interface to ensure compatibility with
 // other configurationg syntaxes, such as JSON.
 func ParseConfig(src []byte, filename string, start hcl.Pos) (*hcl.File, hcl.Diagnostics) {
+	file, diags := ParseFile(src, filename, start)
+	return file.AsHCLFile(), diags
+}
+
+// ParseFile is like ParseConfig but returns a *File, which also carries the
+// tokens and comments of the file in addition to its body.
+func ParseFile(src []byte, filename string, start hcl.Pos) (*File, hcl.Diagnostics) {
 	tokens, diags := LexConfig(src, filename, start)
 	peeker := newPeeker(tokens, false)
 	parser := &parser{peeker: peeker}
@@ -26,13 +33,18 @@ func ParseConfig(src []byte, filename string, start hcl.Pos) (*hcl.File, hcl.Dia
 	// errors.
 	peeker.AssertEmptyIncludeNewlinesStack()
 
-	return &hcl.File{
+	attachDocs(body, tokens)
+
+	return &File{
 		Body:  body,
 		Bytes: src,
 
-		Nav: navigation{
-			root: body,
-		},
+		Tokens:   tokens,
+		Comments: commentTokens(tokens),
+
+		filename:  filename,
+		start:     start,
+		hasErrors: diags.HasErrors(),
 	}, diags
 }
 
@@ -0,0 +1,371 @@
+package quosyntax
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Reference is a traversal that refers to a particular variable, or to a
+// particular attribute or element of it.
+type Reference struct {
+	// Traversal is the whole traversal that contains the reference, which
+	// may continue beyond the part that was searched for.
+	Traversal hcl.Traversal
+
+	// Range is the range of the part of the traversal that matches the
+	// traversal that was searched for.
+	Range hcl.Range
+}
+
+// FindReferences returns the references within the given node to the root
+// variable, attribute or element described by the given absolute traversal,
+// such as var.max_spread, in the order they appear in the source code.
+//
+// The node may be an expression or a whole body. Traversals inside 'for'
+// expressions that declare a symbol with the same name as the root of the
+// given traversal are not included, since they refer to that symbol.
+//
+// An attribute step in the given traversal matches both attribute and index
+// syntax in the references, so var.max_spread matches var["max_spread"].
+func FindReferences(node Node, target hcl.Traversal) []Reference {
+	var ret []Reference
+	Walk(node, &referencesWalker{
+		Name: target.RootName(),
+		Callback: func(t hcl.Traversal, _ []map[string]struct{}) {
+			if rng, ok := matchTraversal(t, target); ok {
+				ret = append(ret, Reference{Traversal: t, Range: rng})
+			}
+		},
+	})
+	sortReferences(ret)
+	return ret
+}
+
+// FindSymbolReferences returns the references to the key or value symbol of
+// the given 'for' expression with the given name, in the order they appear
+// in the source code. References within nested 'for' expressions that
+// declare a symbol of the same name are not included.
+func FindSymbolReferences(e *ForExpr, name string) []Reference {
+	if name != e.KeyVar && name != e.ValVar {
+		return nil
+	}
+
+	var ret []Reference
+	walkSymbolScope(e, &referencesWalker{
+		Name: name,
+		Callback: func(t hcl.Traversal, _ []map[string]struct{}) {
+			ret = append(ret, Reference{Traversal: t, Range: t[0].SourceRange()})
+		},
+	})
+	sortReferences(ret)
+	return ret
+}
+
+// TextEdit is a replacement of the text in a range of a source file.
+type TextEdit struct {
+	Range   hcl.Range
+	NewText string
+}
+
+// ApplyEdits returns a copy of the given source code with the given edits
+// applied. The edits may be in any order, but must not overlap.
+func ApplyEdits(src []byte, edits []TextEdit) ([]byte, error) {
+	sorted := make([]TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Range.Start.Byte < sorted[j].Range.Start.Byte
+	})
+
+	ret := make([]byte, 0, len(src))
+	next := 0
+	for _, edit := range sorted {
+		start, end := edit.Range.Start.Byte, edit.Range.End.Byte
+		if start < next {
+			return nil, fmt.Errorf("edit at %s overlaps a previous edit", edit.Range)
+		}
+		if end < start || end > len(src) {
+			return nil, fmt.Errorf("edit at %s is outside of the source code", edit.Range)
+		}
+		ret = append(ret, src[next:start]...)
+		ret = append(ret, edit.NewText...)
+		next = end
+	}
+	ret = append(ret, src[next:]...)
+	return ret, nil
+}
+
+// RenameReferences returns the edits that change each of the references
+// that FindReferences would find for the first given traversal to refer to
+// the second given traversal instead. Any part of each traversal after the
+// matching part is preserved.
+//
+// Error diagnostics are returned if the new traversal can't be written in
+// native syntax, or if any of the changed references would refer to a
+// 'for' expression symbol of the same name as its new root.
+func RenameReferences(node Node, from, to hcl.Traversal) ([]TextEdit, hcl.Diagnostics) {
+	newText, diags := traversalSource(to)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	newRoot := to.RootName()
+	var edits []TextEdit
+	Walk(node, &referencesWalker{
+		Name: from.RootName(),
+		Callback: func(t hcl.Traversal, scopes []map[string]struct{}) {
+			rng, ok := matchTraversal(t, from)
+			if !ok {
+				return
+			}
+			if inScopes(newRoot, scopes) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Conflicting rename",
+					Detail:   fmt.Sprintf("This reference can't be renamed, because %q is the name of a symbol of an enclosing 'for' expression.", newRoot),
+					Subject:  rng.Ptr(),
+				})
+				return
+			}
+			edits = append(edits, TextEdit{Range: rng, NewText: newText})
+		},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	sortEdits(edits)
+	return edits, diags
+}
+
+// RenameSymbol returns the edits that rename the key or value symbol of the
+// given 'for' expression with the given name, in both its declaration and
+// the references found by FindSymbolReferences.
+//
+// Error diagnostics are returned if the new name isn't a valid identifier,
+// or if renaming would change the meaning of any reference, either because
+// the new name is already used within the 'for' expression or because some
+// references would then refer to the symbols of nested 'for' expressions.
+func RenameSymbol(e *ForExpr, name, newName string) ([]TextEdit, hcl.Diagnostics) {
+	var declRange hcl.Range
+	switch name {
+	case "":
+		return nil, nil
+	case e.KeyVar:
+		declRange = e.KeyVarRange
+	case e.ValVar:
+		declRange = e.ValVarRange
+	default:
+		return nil, nil
+	}
+
+	var diags hcl.Diagnostics
+	if !ValidIdentifier(newName) {
+		return nil, diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid symbol name",
+			Detail:   fmt.Sprintf("%q is not a valid identifier.", newName),
+			Subject:  declRange.Ptr(),
+		})
+	}
+	if newName == e.KeyVar || newName == e.ValVar {
+		return nil, diags.Append(&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Conflicting rename",
+			Detail:   fmt.Sprintf("This 'for' expression already declares a symbol named %q.", newName),
+			Subject:  declRange.Ptr(),
+		})
+	}
+
+	edits := []TextEdit{{Range: declRange, NewText: newName}}
+	walkSymbolScope(e, &referencesWalker{
+		Name: name,
+		Callback: func(t hcl.Traversal, scopes []map[string]struct{}) {
+			rng := t[0].SourceRange()
+			if inScopes(newName, scopes) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Conflicting rename",
+					Detail:   fmt.Sprintf("This reference can't be renamed, because %q is the name of a symbol of a nested 'for' expression.", newName),
+					Subject:  &rng,
+				})
+				return
+			}
+			edits = append(edits, TextEdit{Range: rng, NewText: newName})
+		},
+	})
+	walkSymbolScope(e, &referencesWalker{
+		Name: newName,
+		Callback: func(t hcl.Traversal, _ []map[string]struct{}) {
+			rng := t[0].SourceRange()
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Conflicting rename",
+				Detail:   fmt.Sprintf("The symbol can't be renamed to %q, because this reference to another variable of that name would then refer to the symbol.", newName),
+				Subject:  &rng,
+			})
+		},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	sortEdits(edits)
+	return edits, diags
+}
+
+// referencesWalker is a Walker implementation that calls its callback for
+// each root scope traversal of the variable with the given name that isn't
+// shadowed by a local scope, along with the local scopes that are active
+// there.
+//
+// This tracks local scopes in the same way as variablesWalker.
+type referencesWalker struct {
+	Name        string
+	Callback    func(hcl.Traversal, []map[string]struct{})
+	localScopes []map[string]struct{}
+}
+
+func (w *referencesWalker) Enter(n Node) hcl.Diagnostics {
+	switch tn := n.(type) {
+	case *ScopeTraversalExpr:
+		t := tn.Traversal
+		if t.RootName() != w.Name || inScopes(w.Name, w.localScopes) {
+			return nil
+		}
+		w.Callback(t, w.localScopes)
+	case ChildScope:
+		w.localScopes = append(w.localScopes, tn.LocalNames)
+	}
+	return nil
+}
+
+func (w *referencesWalker) Exit(n Node) hcl.Diagnostics {
+	switch n.(type) {
+	case ChildScope:
+		w.localScopes = w.localScopes[:len(w.localScopes)-1]
+	}
+	return nil
+}
+
+// walkSymbolScope walks the expressions of the given 'for' expression that
+// are evaluated in the scope of its symbols.
+func walkSymbolScope(e *ForExpr, w Walker) {
+	for _, expr := range []Expression{e.KeyExpr, e.ValExpr, e.CondExpr} {
+		if expr != nil {
+			Walk(expr, w)
+		}
+	}
+}
+
+func inScopes(name string, scopes []map[string]struct{}) bool {
+	for _, names := range scopes {
+		if _, exists := names[name]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTraversal returns the range of the part of the given traversal that
+// matches the given target traversal, if it begins with it.
+func matchTraversal(t, target hcl.Traversal) (hcl.Range, bool) {
+	if len(t) < len(target) || t.RootName() != target.RootName() {
+		return hcl.Range{}, false
+	}
+	for i := 1; i < len(target); i++ {
+		if !traverserMatches(t[i], target[i]) {
+			return hcl.Range{}, false
+		}
+	}
+	return hcl.RangeBetween(t[0].SourceRange(), t[len(target)-1].SourceRange()), true
+}
+
+func traverserMatches(got, want hcl.Traverser) bool {
+	gotName, gotIsName := traversalStepName(got)
+	wantName, wantIsName := traversalStepName(want)
+	if gotIsName || wantIsName {
+		return gotIsName && wantIsName && gotName == wantName
+	}
+
+	gotIndex, ok := got.(hcl.TraverseIndex)
+	if !ok {
+		return false
+	}
+	wantIndex, ok := want.(hcl.TraverseIndex)
+	if !ok {
+		return false
+	}
+	return indexKeysEqual(gotIndex.Key, wantIndex.Key)
+}
+
+// indexKeysEqual returns true if the given index keys are equal, allowing
+// for number keys being either cty.Number or quoty.Number.
+func indexKeysEqual(a, b cty.Value) bool {
+	if a.RawEquals(b) {
+		return true
+	}
+	if !isNumberKey(a) || !isNumberKey(b) {
+		return false
+	}
+	a, aErr := convert.Convert(a, quoty.Number)
+	b, bErr := convert.Convert(b, quoty.Number)
+	return aErr == nil && bErr == nil && a.RawEquals(b)
+}
+
+func isNumberKey(v cty.Value) bool {
+	ty := v.Type()
+	return v.IsKnown() && !v.IsNull() && (ty == cty.Number || ty.Equals(quoty.Number))
+}
+
+// traversalSource returns the given absolute traversal in native syntax.
+func traversalSource(t hcl.Traversal) (string, hcl.Diagnostics) {
+	if t.IsRelative() {
+		return "", hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid traversal",
+				Detail:   "The new traversal must begin with a variable name.",
+			},
+		}
+	}
+	for _, step := range t {
+		valid := true
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			valid = ValidIdentifier(step.Name)
+		case hcl.TraverseAttr:
+			valid = ValidIdentifier(step.Name)
+		case hcl.TraverseIndex:
+			key := step.Key
+			valid = key.IsKnown() && !key.IsNull() &&
+				(key.Type() == cty.String || key.Type() == cty.Number || key.Type().Equals(quoty.Number))
+		default:
+			valid = false
+		}
+		if !valid {
+			return "", hcl.Diagnostics{
+				{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid traversal",
+					Detail:   "The new traversal can't be written in native syntax.",
+				},
+			}
+		}
+	}
+	return FormatPath(t.RootName(), traversalPath(t[1:])), nil
+}
+
+func sortReferences(refs []Reference) {
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].Range.Start.Byte < refs[j].Range.Start.Byte
+	})
+}
+
+func sortEdits(edits []TextEdit) {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Range.Start.Byte < edits[j].Range.Start.Byte
+	})
+}
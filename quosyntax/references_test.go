@@ -0,0 +1,254 @@
+package quosyntax
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestFindReferences(t *testing.T) {
+	tests := []struct {
+		input  string
+		target string
+		want   []string
+	}{
+		{
+			`var.max_spread`,
+			`var.max_spread`,
+			[]string{`var.max_spread`},
+		},
+		{
+			`var.max_spread * var["max_spread"] + var.min_spread`,
+			`var.max_spread`,
+			[]string{`var.max_spread`, `var["max_spread"]`},
+		},
+		{
+			`var.max_spread.bid + var`,
+			`var`,
+			[]string{`var`, `var`},
+		},
+		{
+			`var.max_spread.bid`,
+			`var.max_spread`,
+			[]string{`var.max_spread`},
+		},
+		{
+			`orders[0].price + orders[1].price`,
+			`orders[0]`,
+			[]string{`orders[0]`},
+		},
+		{
+			`[for var in vars: var.max_spread]`,
+			`var.max_spread`,
+			nil,
+		},
+		{
+			`[for k, v in var.max_spread: "${k}${var.max_spread}"]`,
+			`var.max_spread`,
+			[]string{`var.max_spread`, `var.max_spread`},
+		},
+		{
+			`[[for k, var in x: var.max_spread if k != var], var.max_spread]`,
+			`var.max_spread`,
+			[]string{`var.max_spread`},
+		},
+		{
+			`"%{ for var in x }${var.max_spread}%{ endfor }${var.max_spread}"`,
+			`var.max_spread`,
+			[]string{`var.max_spread`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			src := []byte(test.input)
+			expr, diags := ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			target, diags := ParseTraversalAbs([]byte(test.target), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			refs := FindReferences(expr, target)
+			var got []string
+			for _, ref := range refs {
+				got = append(got, string(ref.Range.SliceBytes(src)))
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("wrong references\ngot:  %q\nwant: %q", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("wrong references\ngot:  %q\nwant: %q", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenameReferences(t *testing.T) {
+	tests := []struct {
+		input    string
+		from, to string
+		want     string
+		wantErr  bool
+	}{
+		{
+			`var.max_spread + var["max_spread"].bid + var.min_spread`,
+			`var.max_spread`,
+			`var.spread`,
+			`var.spread + var.spread.bid + var.min_spread`,
+			false,
+		},
+		{
+			`[for v in var.max_spread: v + var.max_spread]`,
+			`var.max_spread`,
+			`local.spread["max"]`,
+			`[for v in local.spread["max"]: v + local.spread["max"]]`,
+			false,
+		},
+		{
+			`[[for var in x: var.max_spread], var.max_spread]`,
+			`var.max_spread`,
+			`var.spread`,
+			`[[for var in x: var.max_spread], var.spread]`,
+			false,
+		},
+		{
+			`[for local in x: var.max_spread]`,
+			`var.max_spread`,
+			`local.spread`,
+			``,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			src := []byte(test.input)
+			expr, diags := ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			from, diags := ParseTraversalAbs([]byte(test.from), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			to, diags := ParseTraversalAbs([]byte(test.to), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			edits, diags := RenameReferences(expr, from, to)
+			if test.wantErr {
+				if !diags.HasErrors() {
+					t.Fatalf("unexpected success\nedits: %#v", edits)
+				}
+				return
+			}
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			got, err := ApplyEdits(src, edits)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRenameSymbol(t *testing.T) {
+	tests := []struct {
+		input         string
+		name, newName string
+		want          string
+		wantErr       bool
+	}{
+		{
+			`{for k, v in x: k => v.price if v != null}`,
+			`v`,
+			`order`,
+			`{for k, order in x: k => order.price if order != null}`,
+			false,
+		},
+		{
+			`{for k, v in x: k => [for v in k: v]}`,
+			`v`,
+			`order`,
+			`{for k, order in x: k => [for v in k: v]}`,
+			false,
+		},
+		{
+			`[for k, v in x: "%{ for w in v }${k}%{ endfor }"]`,
+			`k`,
+			`key`,
+			`[for key, v in x: "%{ for w in v }${key}%{ endfor }"]`,
+			false,
+		},
+		{
+			`[for k, v in x: v]`,
+			`v`,
+			`k`,
+			``,
+			true,
+		},
+		{
+			`[for v in x: v + y]`,
+			`v`,
+			`y`,
+			``,
+			true,
+		},
+		{
+			`[for v in x: [for y in v: v + y]]`,
+			`v`,
+			`y`,
+			``,
+			true,
+		},
+		{
+			`[for v in x: v]`,
+			`v`,
+			`not valid`,
+			``,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			src := []byte(test.input)
+			expr, diags := ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			forExpr, ok := expr.(*ForExpr)
+			if !ok {
+				t.Fatalf("expression is %T, not *ForExpr", expr)
+			}
+
+			edits, diags := RenameSymbol(forExpr, test.name, test.newName)
+			if test.wantErr {
+				if !diags.HasErrors() {
+					t.Fatalf("unexpected success\nedits: %#v", edits)
+				}
+				return
+			}
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			got, err := ApplyEdits(src, edits)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package quosyntax
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// SemanticTokenType is an enumeration used for the Type field on
+// SemanticToken, classifying the role of a token for syntax highlighting.
+type SemanticTokenType int
+
+const (
+	SemanticNone SemanticTokenType = iota
+
+	SemanticBlockType
+	SemanticBlockLabel
+	SemanticAttributeName
+	SemanticVariable
+	SemanticAttributeAccess
+	SemanticFunctionName
+
+	SemanticNumber
+	SemanticString
+
+	// SemanticInterpolation is used for the delimiters of template
+	// interpolation sequences and directives, but not for their content.
+	SemanticInterpolation
+
+	// SemanticKeyword is used for the for, in and if keywords of 'for'
+	// expressions, the keywords of template directives, and the literal
+	// values true, false and null.
+	SemanticKeyword
+
+	SemanticComment
+	SemanticOperator
+)
+
+// SemanticToken is a token of a file along with its role in the file.
+type SemanticToken struct {
+	Type  SemanticTokenType
+	Range hcl.Range
+}
+
+// SemanticTokens returns the classification of each of the tokens of the
+// file that has a role worth highlighting, in the order they appear in the
+// file.
+//
+// Tokens are classified using the parsed body where possible, so that for
+// example an identifier "for" is a keyword only where it begins a 'for'
+// expression, and is an attribute name or variable elsewhere. Tokens that
+// are not part of the body, such as those skipped during error recovery,
+// are classified only by their token type.
+func (f *File) SemanticTokens() []SemanticToken {
+	c := &semanticClassifier{
+		tokens: f.Tokens,
+		types:  make([]SemanticTokenType, len(f.Tokens)),
+	}
+	for i := range f.Tokens {
+		c.types[i] = defaultSemanticTokenType(f.Tokens, i)
+	}
+	if f.Body != nil {
+		Walk(f.Body, c)
+	}
+
+	var ret []SemanticToken
+	for i, tok := range f.Tokens {
+		if c.types[i] == SemanticNone {
+			continue
+		}
+		ret = append(ret, SemanticToken{
+			Type:  c.types[i],
+			Range: tok.Range,
+		})
+	}
+	return ret
+}
+
+// defaultSemanticTokenType returns the classification of the token at the
+// given index that follows from its type alone.
+func defaultSemanticTokenType(tokens Tokens, i int) SemanticTokenType {
+	switch tokens[i].Type {
+	case TokenComment:
+		return SemanticComment
+	case TokenNumberLit:
+		return SemanticNumber
+	case TokenOQuote, TokenCQuote, TokenQuotedLit, TokenStringLit, TokenOHeredoc, TokenCHeredoc:
+		return SemanticString
+	case TokenTemplateInterp, TokenTemplateControl, TokenTemplateSeqEnd:
+		return SemanticInterpolation
+	case TokenStar, TokenSlash, TokenPlus, TokenMinus, TokenPercent,
+		TokenEqual, TokenEqualOp, TokenNotEqual,
+		TokenLessThan, TokenLessThanEq, TokenGreaterThan, TokenGreaterThanEq,
+		TokenAnd, TokenOr, TokenBang,
+		TokenQuestion, TokenColon, TokenFatArrow, TokenEllipsis:
+		return SemanticOperator
+	case TokenIdent:
+		// The scanner only produces an identifier immediately after the
+		// opening of a template directive for the directive keyword.
+		if i > 0 && tokens[i-1].Type == TokenTemplateControl {
+			return SemanticKeyword
+		}
+	}
+	return SemanticNone
+}
+
+// semanticClassifier is a Walker implementation that refines the
+// classification of tokens using the nodes that contain them.
+type semanticClassifier struct {
+	tokens Tokens
+	types  []SemanticTokenType
+}
+
+func (c *semanticClassifier) Enter(n Node) hcl.Diagnostics {
+	switch tn := n.(type) {
+	case *Block:
+		c.markRange(tn.TypeRange, SemanticBlockType)
+		for _, rng := range tn.LabelRanges {
+			c.markRange(rng, SemanticBlockLabel)
+		}
+	case *Attribute:
+		c.markRange(tn.NameRange, SemanticAttributeName)
+	case *ScopeTraversalExpr:
+		c.markTraversal(tn.Traversal)
+	case *RelativeTraversalExpr:
+		c.markTraversal(tn.Traversal)
+	case *FunctionCallExpr:
+		c.markRange(tn.NameRange, SemanticFunctionName)
+	case *LiteralValueExpr:
+		// The only literal values written as identifiers are the keywords
+		// true, false and null.
+		if i := c.tokenAt(tn.SrcRange.Start.Byte); i < len(c.tokens) && c.tokens[i].Type == TokenIdent {
+			c.types[i] = SemanticKeyword
+		}
+	case *ForExpr:
+		if tn.KeyVar != "" {
+			c.markRange(tn.KeyVarRange, SemanticVariable)
+		}
+		c.markRange(tn.ValVarRange, SemanticVariable)
+		c.markKeyword(tn.OpenRange.Start.Byte, forKeyword)
+		c.markKeyword(tn.ValVarRange.End.Byte, inKeyword)
+		if tn.CondExpr != nil {
+			// The if keyword is the last token before the condition, aside
+			// from any newlines.
+			i := c.tokenAt(tn.CondExpr.StartRange().Start.Byte) - 1
+			for i > 0 && c.tokens[i].Type == TokenNewline {
+				i--
+			}
+			if i >= 0 && ifKeyword.TokenMatches(c.tokens[i]) {
+				c.types[i] = SemanticKeyword
+			}
+		}
+	}
+	return nil
+}
+
+func (c *semanticClassifier) Exit(n Node) hcl.Diagnostics {
+	switch tn := n.(type) {
+	case *ObjectConsKeyExpr:
+		// A naked identifier used as an object key is an attribute name
+		// rather than a reference, so we override the classification of
+		// the traversal we'll have visited as a child.
+		if !tn.ForceNonLiteral && tn.literalName() != "" {
+			c.markRange(tn.Range(), SemanticAttributeName)
+		}
+	}
+	return nil
+}
+
+// markTraversal classifies the tokens of the names in the given traversal.
+// Index steps are left classified by their token types.
+func (c *semanticClassifier) markTraversal(t hcl.Traversal) {
+	for _, step := range t {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			c.markRange(step.SrcRange, SemanticVariable)
+		case hcl.TraverseAttr:
+			// The range of an attribute step includes its leading dot, so
+			// we classify only the final token.
+			if i := c.tokenAt(step.SrcRange.End.Byte) - 1; i >= 0 && c.tokens[i].Type == TokenIdent {
+				c.types[i] = SemanticAttributeAccess
+			}
+		}
+	}
+}
+
+// markRange classifies each of the tokens that begin within the given range.
+func (c *semanticClassifier) markRange(rng hcl.Range, ty SemanticTokenType) {
+	for i := c.tokenAt(rng.Start.Byte); i < len(c.tokens); i++ {
+		if c.tokens[i].Range.Start.Byte >= rng.End.Byte {
+			break
+		}
+		c.types[i] = ty
+	}
+}
+
+// markKeyword classifies the first identifier token at or after the given
+// offset as a keyword, if it is the given keyword.
+func (c *semanticClassifier) markKeyword(offset int, kw Keyword) {
+	for i := c.tokenAt(offset); i < len(c.tokens); i++ {
+		switch c.tokens[i].Type {
+		case TokenIdent:
+			if kw.TokenMatches(c.tokens[i]) {
+				c.types[i] = SemanticKeyword
+			}
+			return
+		case TokenEOF:
+			return
+		}
+	}
+}
+
+// tokenAt returns the index of the first token that begins at or after the
+// given offset, or the number of tokens if there is no such token.
+func (c *semanticClassifier) tokenAt(offset int) int {
+	return sort.Search(len(c.tokens), func(i int) bool {
+		return c.tokens[i].Range.Start.Byte >= offset
+	})
+}
@@ -0,0 +1,119 @@
+package quosyntax
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	tests := map[string]struct {
+		Src  string
+		Want []string
+	}{
+		"empty": {
+			``,
+			nil,
+		},
+		"block and attribute": {
+			"# note\nleg \"buy\" {\n  price = max(var.mid, 1.5)\n}\n",
+			[]string{
+				`SemanticComment:"# note\n"`,
+				`SemanticBlockType:"leg"`,
+				`SemanticBlockLabel:"\""`,
+				`SemanticBlockLabel:"buy"`,
+				`SemanticBlockLabel:"\""`,
+				`SemanticAttributeName:"price"`,
+				`SemanticOperator:"="`,
+				`SemanticFunctionName:"max"`,
+				`SemanticVariable:"var"`,
+				`SemanticAttributeAccess:"mid"`,
+				`SemanticNumber:"1.5"`,
+			},
+		},
+		"for expression": {
+			`a = [for k, v in x: v.for if k != null]`,
+			[]string{
+				`SemanticAttributeName:"a"`,
+				`SemanticOperator:"="`,
+				`SemanticKeyword:"for"`,
+				`SemanticVariable:"k"`,
+				`SemanticVariable:"v"`,
+				`SemanticKeyword:"in"`,
+				`SemanticVariable:"x"`,
+				`SemanticOperator:":"`,
+				`SemanticVariable:"v"`,
+				`SemanticAttributeAccess:"for"`,
+				`SemanticKeyword:"if"`,
+				`SemanticVariable:"k"`,
+				`SemanticOperator:"!="`,
+				`SemanticKeyword:"null"`,
+			},
+		},
+		"contextual keywords as names": {
+			`for = { in = if }`,
+			[]string{
+				`SemanticAttributeName:"for"`,
+				`SemanticOperator:"="`,
+				`SemanticAttributeName:"in"`,
+				`SemanticOperator:"="`,
+				`SemanticVariable:"if"`,
+			},
+		},
+		"template": {
+			`a = "x${b}%{ for c in d }${c}%{ endfor }"`,
+			[]string{
+				`SemanticAttributeName:"a"`,
+				`SemanticOperator:"="`,
+				`SemanticString:"\""`,
+				`SemanticString:"x"`,
+				`SemanticInterpolation:"${"`,
+				`SemanticVariable:"b"`,
+				`SemanticInterpolation:"}"`,
+				`SemanticInterpolation:"%{"`,
+				`SemanticKeyword:"for"`,
+				`SemanticVariable:"c"`,
+				`SemanticKeyword:"in"`,
+				`SemanticVariable:"d"`,
+				`SemanticInterpolation:"}"`,
+				`SemanticInterpolation:"${"`,
+				`SemanticVariable:"c"`,
+				`SemanticInterpolation:"}"`,
+				`SemanticInterpolation:"%{"`,
+				`SemanticKeyword:"endfor"`,
+				`SemanticInterpolation:"}"`,
+				`SemanticString:"\""`,
+			},
+		},
+		"heredoc": {
+			"a = <<EOT\nhello\nEOT\n",
+			[]string{
+				`SemanticAttributeName:"a"`,
+				`SemanticOperator:"="`,
+				`SemanticString:"<<EOT\n"`,
+				`SemanticString:"hello\n"`,
+				`SemanticString:"EOT"`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			src := []byte(test.Src)
+			f, diags := ParseFile(src, "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			var got []string
+			for _, tok := range f.SemanticTokens() {
+				got = append(got, fmt.Sprintf("%s:%q", tok.Type, tok.Range.SliceBytes(src)))
+			}
+			if !reflect.DeepEqual(got, test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
 )
 
 // AsHCLBlock returns the block data expressed as a *hcl.Block.
@@ -71,7 +72,7 @@ func (b *Body) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostic
 				}
 				suggestions = append(suggestions, attrS.Name)
 			}
-			suggestion := nameSuggestion(name, suggestions)
+			suggestion := quoty.NameSuggestion(name, suggestions)
 			if suggestion != "" {
 				suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
 			} else {
@@ -100,7 +101,7 @@ func (b *Body) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostic
 			for _, blockS := range schema.Blocks {
 				suggestions = append(suggestions, blockS.Type)
 			}
-			suggestion := nameSuggestion(blockTy, suggestions)
+			suggestion := quoty.NameSuggestion(blockTy, suggestions)
 			if suggestion != "" {
 				suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
 			} else {
@@ -319,6 +320,10 @@ type Attribute struct {
 	Name string
 	Expr Expression
 
+	// Doc is the documentation given by the comments around the attribute,
+	// or nil if there are none.
+	Doc *Doc
+
 	SrcRange    hcl.Range
 	NameRange   hcl.Range
 	EqualsRange hcl.Range
@@ -375,6 +380,10 @@ type Block struct {
 	Labels []string
 	Body   *Body
 
+	// Doc is the documentation given by the comments around the block, or
+	// nil if there are none.
+	Doc *Doc
+
 	TypeRange       hcl.Range
 	LabelRanges     []hcl.Range
 	OpenBraceRange  hcl.Range
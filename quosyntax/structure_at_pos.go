@@ -116,3 +116,48 @@ func (b *Body) OutermostExprAtPos(pos hcl.Pos) hcl.Expression {
 	}
 	return attr.Expr
 }
+
+// NodePathAtPos returns the chain of nodes that contain the given position,
+// beginning with the body itself and continuing down through any blocks,
+// attributes and nested expressions to the innermost node at the position.
+//
+// The Attributes and Blocks grouping nodes are not included in the result,
+// since they have no meaningful range of their own. The result is nil if
+// the position is not within the body at all.
+func (b *Body) NodePathAtPos(pos hcl.Pos) []Node {
+	w := &nodePathWalker{Pos: pos}
+	Walk(b, w)
+	return w.Path
+}
+
+// nodePathWalker is a Walker implementation that collects the nodes on the
+// path from the root node to the innermost node containing a position.
+type nodePathWalker struct {
+	Pos  hcl.Pos
+	Path []Node
+
+	// onPath records, for each node currently being walked, whether that
+	// node is on the path.
+	onPath []bool
+}
+
+func (w *nodePathWalker) Enter(n Node) hcl.Diagnostics {
+	parentOnPath := len(w.onPath) == 0 || w.onPath[len(w.onPath)-1]
+	onPath := false
+	switch n.(type) {
+	case Attributes, Blocks:
+		onPath = parentOnPath
+	default:
+		onPath = parentOnPath && n.Range().ContainsPos(w.Pos)
+		if onPath {
+			w.Path = append(w.Path, n)
+		}
+	}
+	w.onPath = append(w.onPath, onPath)
+	return nil
+}
+
+func (w *nodePathWalker) Exit(n Node) hcl.Diagnostics {
+	w.onPath = w.onPath[:len(w.onPath)-1]
+	return nil
+}
@@ -333,3 +333,95 @@ func TestOutermostExprAtPos(t *testing.T) {
 		})
 	}
 }
+
+func TestNodePathAtPos(t *testing.T) {
+	tests := map[string]struct {
+		Src      string
+		Pos      hcl.Pos
+		WantSrcs []string
+		WantArg  int
+	}{
+		"empty": {
+			``,
+			hcl.Pos{Byte: 0},
+			nil,
+			-1,
+		},
+		"top-level attribute name": {
+			`a = b`,
+			hcl.Pos{Byte: 0},
+			[]string{`a = b`, `a = b`},
+			-1,
+		},
+		"top-level attribute expression": {
+			`a = b.c`,
+			hcl.Pos{Byte: 5},
+			[]string{`a = b.c`, `a = b.c`, `b.c`},
+			-1,
+		},
+		"function call argument": {
+			`a = max(1, b + 2)`,
+			hcl.Pos{Byte: 15},
+			[]string{`a = max(1, b + 2)`, `a = max(1, b + 2)`, `max(1, b + 2)`, `b + 2`, `2`},
+			1,
+		},
+		"function call between arguments": {
+			`a = max(1, b + 2)`,
+			hcl.Pos{Byte: 9},
+			[]string{`a = max(1, b + 2)`, `a = max(1, b + 2)`, `max(1, b + 2)`},
+			-1,
+		},
+		"nested block": {
+			"strategy \"mm\" {\n  leg \"buy\" {\n    price = [x]\n  }\n}",
+			hcl.Pos{Byte: 43},
+			[]string{
+				"strategy \"mm\" {\n  leg \"buy\" {\n    price = [x]\n  }\n}",
+				"strategy \"mm\" {\n  leg \"buy\" {\n    price = [x]\n  }\n}",
+				"{\n  leg \"buy\" {\n    price = [x]\n  }\n}",
+				"leg \"buy\" {\n    price = [x]\n  }",
+				"{\n    price = [x]\n  }",
+				`price = [x]`,
+				`[x]`,
+				`x`,
+			},
+			-1,
+		},
+		"block header": {
+			"strategy \"mm\" {\n}",
+			hcl.Pos{Byte: 10},
+			[]string{
+				"strategy \"mm\" {\n}",
+				"strategy \"mm\" {\n}",
+			},
+			-1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			inputSrc := []byte(test.Src)
+			f, diags := ParseConfig(inputSrc, "", hcl.Pos{Line: 1, Column: 1})
+			for _, diag := range diags {
+				t.Logf(diag.Error())
+			}
+
+			path := f.Body.(*Body).NodePathAtPos(test.Pos)
+			var gotSrcs []string
+			gotArg := -1
+			for _, node := range path {
+				rng := node.Range()
+				gotSrcs = append(gotSrcs, string(rng.SliceBytes(inputSrc)))
+				if call, ok := node.(*FunctionCallExpr); ok {
+					gotArg = call.ArgIndexAtPos(test.Pos)
+				}
+			}
+
+			if !reflect.DeepEqual(gotSrcs, test.WantSrcs) {
+				t.Errorf("wrong path\ngot:  %#v\nwant: %#v", gotSrcs, test.WantSrcs)
+			}
+			if gotArg != test.WantArg {
+				t.Errorf("wrong argument index\ngot:  %d\nwant: %d", gotArg, test.WantArg)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package quosyntax
+
+import (
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Tracer is implemented by observers of the evaluation of native syntax
+// expressions, which can be attached to an EvalContext using WithTracer.
+//
+// Evaluations using the same EvalContext may be concurrent, so a Tracer that
+// is used in that way must be safe for concurrent use.
+type Tracer interface {
+	// TraceExpr is called each time an expression has been evaluated, after
+	// its sub-expressions have been evaluated.
+	TraceExpr(ev *TraceEvent)
+}
+
+// TraceEvent describes the evaluation of a single expression.
+type TraceEvent struct {
+	// Expr is the expression that was evaluated, and Range is its source
+	// range.
+	Expr  Expression
+	Range hcl.Range
+
+	// Parent is the event for the expression whose evaluation caused this
+	// one, or nil if the expression was evaluated directly. The parent's
+	// evaluation is not yet complete when TraceExpr is called for this event.
+	Parent *TraceEvent
+
+	// Inputs are the events for the sub-expressions that were evaluated to
+	// produce the result, in the order they were evaluated. For example,
+	// the inputs of a function call are its arguments, and the inputs of a
+	// conditional expression are both of its results followed by its
+	// condition.
+	//
+	// A sub-expression that is evaluated more than once, such as the result
+	// expression of a "for" expression, appears once for each evaluation.
+	Inputs []*TraceEvent
+
+	// Output and Diagnostics are the result of the evaluation.
+	Output      cty.Value
+	Diagnostics hcl.Diagnostics
+}
+
+// WithTracer returns a child of the given EvalContext that, when used to
+// evaluate native syntax expressions, reports the evaluation of each
+// expression to the given tracer.
+//
+// Tracing has no effect on the results of evaluation.
+func WithTracer(evalCtx *hcl.EvalContext, tracer Tracer) *hcl.EvalContext {
+	return withOptions(evalCtx, func(opts *evalOptions) {
+		opts.tracer = tracer
+	})
+}
+
+// TraceRecorder is a Tracer that records the events for the expressions
+// that are evaluated directly, which in turn lead to the events for their
+// sub-expressions through their Inputs.
+//
+// The zero value of TraceRecorder is ready to use, and it is safe for
+// concurrent use.
+type TraceRecorder struct {
+	mu     sync.Mutex
+	events []*TraceEvent
+}
+
+var _ Tracer = (*TraceRecorder)(nil)
+
+// TraceExpr implements Tracer.
+func (r *TraceRecorder) TraceExpr(ev *TraceEvent) {
+	if ev.Parent != nil {
+		return
+	}
+	r.mu.Lock()
+	r.events = append(r.events, ev)
+	r.mu.Unlock()
+}
+
+// Events returns the events recorded so far, in the order in which their
+// evaluations completed.
+func (r *TraceRecorder) Events() []*TraceEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := make([]*TraceEvent, len(r.events))
+	copy(ret, r.events)
+	return ret
+}
+
+// trace evaluates the given expression within the receiving frame, which
+// must have a tracer, and reports the evaluation to the tracer.
+func (fr *evalFrame) trace(e valueImpl, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	ev := &TraceEvent{
+		Expr:   e,
+		Range:  e.Range(),
+		Parent: fr.event,
+	}
+
+	// The sub-expressions are evaluated in a copy of the frame, so that
+	// their events can find this one.
+	child := *fr
+	child.event = ev
+	ev.Output, ev.Diagnostics = child.evalLimited(e, ctx)
+
+	if fr.event != nil {
+		// The parent is evaluated by this goroutine, so we don't need to
+		// synchronize.
+		fr.event.Inputs = append(fr.event.Inputs, ev)
+	}
+	fr.tracer.TraceExpr(ev)
+	return ev.Output, ev.Diagnostics
+}
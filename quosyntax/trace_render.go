@@ -0,0 +1,159 @@
+package quosyntax
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// maxTraceSnippetLen is the maximum length of the source snippet shown for
+// each expression by RenderTrace.
+const maxTraceSnippetLen = 40
+
+// RenderTrace writes a description of the given event and its inputs to the
+// given writer, as a tree with one line for each expression. Each line shows
+// the source of the expression, taken from the given source code, and the
+// value it produced, along with the branch taken by conditional expressions
+// and any diagnostics the expression itself produced. For example:
+//
+//	spread > 0.5 ? price : price * 2 = 6 (condition was false)
+//	├─ price = 3 (not selected)
+//	├─ price * 2 = 6
+//	│  ├─ price = 3
+//	│  └─ 2
+//	└─ spread > 0.5 = false
+//	   ├─ spread = 0.25
+//	   └─ 0.5
+//
+// If the given source code is nil then the source ranges of the expressions
+// are shown instead.
+func RenderTrace(w io.Writer, ev *TraceEvent, src []byte) error {
+	bw := bufio.NewWriter(w)
+	renderTraceEvent(bw, ev, src, "", "", false)
+	return bw.Flush()
+}
+
+func renderTraceEvent(w *bufio.Writer, ev *TraceEvent, src []byte, prefix, childPrefix string, unused bool) {
+	w.WriteString(prefix)
+	w.WriteString(traceSnippet(ev.Range, src))
+	if _, isLiteral := ev.Expr.(*LiteralValueExpr); !isLiteral {
+		w.WriteString(" = ")
+		w.WriteString(traceValueString(ev.Output))
+	}
+	if unused {
+		w.WriteString(" (not selected)")
+	}
+
+	var unselected Expression
+	if cond, isCond := ev.Expr.(*ConditionalExpr); isCond {
+		// Both results are evaluated, so the condition's value tells us
+		// which was selected.
+		for _, input := range ev.Inputs {
+			if input.Expr != cond.Condition || !input.Output.IsKnown() || input.Output.IsNull() || !input.Output.Type().Equals(cty.Bool) {
+				continue
+			}
+			if input.Output.True() {
+				w.WriteString(" (condition was true)")
+				unselected = cond.FalseResult
+			} else {
+				w.WriteString(" (condition was false)")
+				unselected = cond.TrueResult
+			}
+		}
+	}
+
+	// We show only the diagnostics that didn't come from the inputs, so
+	// that each appears beside the expression responsible for it.
+	for _, diag := range ev.Diagnostics {
+		if traceInputsHaveDiag(ev, diag) {
+			continue
+		}
+		severity := "error"
+		if diag.Severity == hcl.DiagWarning {
+			severity = "warning"
+		}
+		fmt.Fprintf(w, " [%s: %s]", severity, diag.Summary)
+	}
+	w.WriteByte('\n')
+
+	for i, input := range ev.Inputs {
+		unused := unselected != nil && input.Expr == unselected
+		if i == len(ev.Inputs)-1 {
+			renderTraceEvent(w, input, src, childPrefix+"└─ ", childPrefix+"   ", unused)
+		} else {
+			renderTraceEvent(w, input, src, childPrefix+"├─ ", childPrefix+"│  ", unused)
+		}
+	}
+}
+
+// traceInputsHaveDiag returns true if any of the inputs of the given event
+// produced the given diagnostic.
+func traceInputsHaveDiag(ev *TraceEvent, diag *hcl.Diagnostic) bool {
+	for _, input := range ev.Inputs {
+		for _, inputDiag := range input.Diagnostics {
+			if inputDiag == diag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// traceSnippet returns the source code in the given range on a single line,
+// shortened if necessary, or the range itself if the source isn't available.
+func traceSnippet(rng hcl.Range, src []byte) string {
+	if src == nil || rng.End.Byte > len(src) || rng.Start.Byte > rng.End.Byte {
+		return rng.String()
+	}
+	snippet := strings.Join(strings.Fields(string(src[rng.Start.Byte:rng.End.Byte])), " ")
+	if runes := []rune(snippet); len(runes) > maxTraceSnippetLen {
+		snippet = string(runes[:maxTraceSnippetLen-3]) + "..."
+	}
+	return snippet
+}
+
+// traceValueString returns a concise representation of the given value, for
+// RenderTrace.
+func traceValueString(v cty.Value) string {
+	switch {
+	case !v.IsKnown():
+		return "(unknown)"
+	case v.IsNull():
+		return "null"
+	}
+
+	ty := v.Type()
+	switch {
+	case ty.Equals(quoty.Number):
+		return exactNumberString(v.EncapsulatedValue().(*big.Rat))
+	case ty == cty.String:
+		return strconv.Quote(v.AsString())
+	case ty == cty.Bool:
+		return strconv.FormatBool(v.True())
+	case ty == cty.Number:
+		return v.AsBigFloat().Text('f', -1)
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		var elems []string
+		for it := v.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			elems = append(elems, traceValueString(elem))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case ty.IsMapType() || ty.IsObjectType():
+		var attrs []string
+		for it := v.ElementIterator(); it.Next(); {
+			k, elem := it.Element()
+			attrs = append(attrs, k.AsString()+" = "+traceValueString(elem))
+		}
+		return "{" + strings.Join(attrs, ", ") + "}"
+	default:
+		return v.GoString()
+	}
+}
@@ -0,0 +1,178 @@
+package quosyntax
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quofn"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestRenderTrace(t *testing.T) {
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"price":  quoty.NumberIntVal(3),
+			"spread": quoty.MustParseNumberVal("0.25"),
+			"pairs":  cty.ListVal([]cty.Value{cty.StringVal("XLM"), cty.StringVal("USD")}),
+		},
+		Functions: map[string]function.Function{
+			"divide": quofn.DivideFunc,
+		},
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			`spread > 0.5 ? price : price * 2`,
+			`
+spread > 0.5 ? price : price * 2 = 6 (condition was false)
+├─ price = 3 (not selected)
+├─ price * 2 = 6
+│  ├─ price = 3
+│  └─ 2
+└─ spread > 0.5 = false
+   ├─ spread = 0.25
+   └─ 0.5
+`,
+		},
+		{
+			`divide(price, 4 - 4 * spread)`,
+			`
+divide(price, 4 - 4 * spread) = 1
+├─ price = 3
+└─ 4 - 4 * spread = 3
+   ├─ 4
+   └─ 4 * spread = 1
+      ├─ 4
+      └─ spread = 0.25
+`,
+		},
+		{
+			`{for p in pairs: p => "${p}/${missing}"}`,
+			`
+{for p in pairs: p => "${p}/${missing}"} = {USD = (unknown), XLM = (unknown)}
+├─ pairs = ["XLM", "USD"]
+├─ p = "XLM"
+├─ "${p}/${missing}" = (unknown)
+│  ├─ p = "XLM"
+│  ├─ /
+│  └─ missing = (unknown) [error: Unknown variable]
+├─ p = "USD"
+└─ "${p}/${missing}" = (unknown)
+   ├─ p = "USD"
+   ├─ /
+   └─ missing = (unknown) [error: Unknown variable]
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			src := []byte(test.input)
+			expr, parseDiags := ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			var rec TraceRecorder
+			got, diags := expr.Value(WithTracer(evalCtx, &rec))
+			want, wantDiags := expr.Value(evalCtx)
+			if !got.RawEquals(want) || len(diags) != len(wantDiags) {
+				t.Fatalf("tracing changed the result\ngot:  %#v %s\nwant: %#v %s", got, diags.Error(), want, wantDiags.Error())
+			}
+
+			events := rec.Events()
+			if len(events) != 1 {
+				t.Fatalf("wrong number of events %d; want 1", len(events))
+			}
+			var buf strings.Builder
+			if err := RenderTrace(&buf, events[0], src); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := buf.String(), test.want[1:]; got != want {
+				t.Errorf("wrong trace\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestWithTracerConcurrent(t *testing.T) {
+	expr, parseDiags := ParseExpression([]byte(`[for x in xs: x * 2]`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"xs": cty.ListVal([]cty.Value{quoty.NumberIntVal(1), quoty.NumberIntVal(2)}),
+		},
+	}
+
+	var rec TraceRecorder
+	tracedCtx := WithTracer(ctx, &rec)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expr.Value(tracedCtx)
+		}()
+	}
+	wg.Wait()
+
+	events := rec.Events()
+	if len(events) != 8 {
+		t.Fatalf("wrong number of events %d; want 8", len(events))
+	}
+	for _, ev := range events {
+		// The collection, and then the result for each element
+		if got, want := len(ev.Inputs), 3; got != want {
+			t.Errorf("wrong number of inputs %d; want %d", got, want)
+		}
+	}
+}
+
+func TestWithTracerLimits(t *testing.T) {
+	// The expression that exceeds a limit is traced with the diagnostic.
+	expr, parseDiags := ParseExpression([]byte(`[for x in xs: x * 2]`), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if len(parseDiags) != 0 {
+		t.Fatalf("unexpected parse diagnostics")
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"xs": cty.ListVal([]cty.Value{quoty.NumberIntVal(1), quoty.NumberIntVal(2)}),
+		},
+	}
+
+	var rec TraceRecorder
+	tracedCtx := WithTracer(WithLimits(context.Background(), ctx, Limits{MaxSteps: 3}), &rec)
+	_, diags := expr.Value(tracedCtx)
+	if len(diags) != 1 || diags[0].Summary != "Evaluation step limit exceeded" {
+		t.Fatalf("wrong diagnostics: %s", diags.Error())
+	}
+
+	events := rec.Events()
+	if len(events) != 1 {
+		t.Fatalf("wrong number of events %d; want 1", len(events))
+	}
+	var buf strings.Builder
+	if err := RenderTrace(&buf, events[0], []byte(`[for x in xs: x * 2]`)); err != nil {
+		t.Fatal(err)
+	}
+	want := `[for x in xs: x * 2] = (unknown)
+├─ xs = [1, 2]
+└─ x * 2 = (unknown)
+   ├─ x = (unknown) [error: Evaluation step limit exceeded]
+   └─ 2
+`
+	if got := buf.String(); got != want {
+		t.Errorf("wrong trace\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
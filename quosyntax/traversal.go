@@ -0,0 +1,139 @@
+package quosyntax
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// traverseAbs is like hcl.Traversal.TraverseAbs, except that if the root
+// variable or an attribute doesn't exist then the diagnostic suggests the
+// closest name that does.
+func traverseAbs(traversal hcl.Traversal, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	split := traversal.SimpleSplit()
+	root := split.Abs[0].(hcl.TraverseRoot)
+
+	hasVariables := false
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		if thisCtx.Variables == nil {
+			continue
+		}
+		hasVariables = true
+		if val, exists := thisCtx.Variables[root.Name]; exists {
+			return traverseRel(split.Rel, val)
+		}
+	}
+
+	if !hasVariables {
+		return cty.DynamicVal, hcl.Diagnostics{
+			{
+				Severity: hcl.DiagError,
+				Summary:  "Variables not allowed",
+				Detail:   "Variables may not be used here.",
+				Subject:  &root.SrcRange,
+			},
+		}
+	}
+
+	suggestion := quoty.NameSuggestion(root.Name, variableNames(ctx))
+	if suggestion != "" {
+		suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
+	}
+	return cty.DynamicVal, hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unknown variable",
+			Detail:   fmt.Sprintf("There is no variable named %q.%s", root.Name, suggestion),
+			Subject:  &root.SrcRange,
+		},
+	}
+}
+
+// traverseRel is like hcl.Traversal.TraverseRel, except that if an attribute
+// doesn't exist then the diagnostic suggests the closest name that does.
+func traverseRel(traversal hcl.Traversal, val cty.Value) (cty.Value, hcl.Diagnostics) {
+	for _, step := range traversal {
+		next, diags := step.TraversalStep(val)
+		if diags.HasErrors() {
+			if name, ok := traversalStepName(step); ok {
+				addAttributeSuggestion(diags, name, val.Type())
+			}
+			return cty.DynamicVal, diags
+		}
+		val = next
+	}
+	return val, nil
+}
+
+// traversalStepName returns the attribute name that the given traversal step
+// refers to, if it is an attribute access or an index with a string key.
+func traversalStepName(step hcl.Traverser) (string, bool) {
+	switch step := step.(type) {
+	case hcl.TraverseAttr:
+		return step.Name, true
+	case hcl.TraverseIndex:
+		key := step.Key
+		if key.Type() == cty.String && key.IsKnown() && !key.IsNull() {
+			return key.AsString(), true
+		}
+	}
+	return "", false
+}
+
+// addAttributeSuggestion adds a suggestion of the closest attribute of the
+// given type to the given attribute name to the diagnostics about it, if the
+// type is an object type that lacks the attribute.
+//
+// Like setDiagEvalContext, this modifies the diagnostics in-place, so must
+// only be used on diagnostics that no caller has seen yet.
+func addAttributeSuggestion(diags hcl.Diagnostics, name string, ty cty.Type) {
+	if !ty.IsObjectType() || ty.HasAttribute(name) {
+		return
+	}
+	suggestion := quoty.NameSuggestion(name, quoty.AttributeNames(ty))
+	if suggestion == "" {
+		return
+	}
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError {
+			diag.Detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+		}
+	}
+}
+
+// variableNames returns the names of the variables in the given EvalContext
+// and its parents, in lexical order.
+func variableNames(ctx *hcl.EvalContext) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		for name := range thisCtx.Variables {
+			if !seen[name] {
+				seen[name] = true
+				ret = append(ret, name)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// functionNames returns the names of the functions in the given EvalContext
+// and its parents, in lexical order.
+func functionNames(ctx *hcl.EvalContext) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		for name := range thisCtx.Functions {
+			if !seen[name] {
+				seen[name] = true
+				ret = append(ret, name)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,96 @@
+package quosyntax
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestTraversalSuggestions(t *testing.T) {
+	asset := cty.ObjectVal(map[string]cty.Value{
+		"code":   cty.StringVal("USD"),
+		"issuer": cty.StringVal("GABC"),
+	})
+	parent := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"price": quoty.NumberIntVal(2),
+		},
+	}
+	ctx := parent.NewChild()
+	ctx.Variables = map[string]cty.Value{
+		"asset":  asset,
+		"assets": cty.TupleVal([]cty.Value{asset}),
+	}
+
+	tests := []struct {
+		input  string
+		detail string
+	}{
+		{
+			`prcie`,
+			`There is no variable named "prcie". Did you mean "price"?`,
+		},
+		{
+			`aset`,
+			`There is no variable named "aset". Did you mean "asset"?`,
+		},
+		{
+			`liability`,
+			`There is no variable named "liability".`,
+		},
+		{
+			`asset.isuer`,
+			`This object does not have an attribute named "isuer". Did you mean "issuer"?`,
+		},
+		{
+			`asset["isuer"]`,
+			`The given key does not identify an element in this collection value. Did you mean "issuer"?`,
+		},
+		{
+			`assets[0].cod`,
+			`This object does not have an attribute named "cod". Did you mean "code"?`,
+		},
+		{
+			`(asset).isuer`,
+			`This object does not have an attribute named "isuer". Did you mean "issuer"?`,
+		},
+		{
+			`[asset][0]["isuer"]`,
+			`The given key does not identify an element in this collection value. Did you mean "issuer"?`,
+		},
+		{
+			`asset.amount`,
+			`This object does not have an attribute named "amount".`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics: %s", parseDiags.Error())
+			}
+
+			check := func(diags hcl.Diagnostics) {
+				t.Helper()
+				if len(diags) != 1 {
+					t.Fatalf("wrong number of diagnostics %d; want 1\n%s", len(diags), diags.Error())
+				}
+				if got := diags[0].Detail; got != test.detail {
+					t.Errorf("wrong detail\ngot:  %s\nwant: %s", got, test.detail)
+				}
+			}
+
+			_, diags := expr.Value(ctx)
+			check(diags)
+
+			compiled := Compile(expr, &CompileScope{
+				Variables: []string{"asset", "assets", "price"},
+			})
+			_, diags = compiled.Value([]cty.Value{asset, ctx.Variables["assets"], parent.Variables["price"]})
+			check(diags)
+		})
+	}
+}
@@ -0,0 +1,807 @@
+package quosyntax
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// TypeContext is the static analog of hcl.EvalContext, used with InferType.
+//
+// Rather than variable values it provides only the types of variables, and
+// the functions are used only for their signatures: InferType never calls
+// a function's implementation.
+type TypeContext struct {
+	Variables map[string]cty.Type
+	Functions map[string]function.Function
+
+	parent *TypeContext
+}
+
+// NewChild returns a new TypeContext that is a child of the receiver.
+func (ctx *TypeContext) NewChild() *TypeContext {
+	return &TypeContext{parent: ctx}
+}
+
+// Parent returns the parent of the receiver, or nil if the receiver has
+// no parent.
+func (ctx *TypeContext) Parent() *TypeContext {
+	return ctx.parent
+}
+
+// InferType statically determines the type of the result of the given
+// expression, given only the types of the variables and the signatures of the
+// functions in the given context.
+//
+// The result is the same type that Value would return for the expression if
+// each variable were set to an unknown value of its given type, and any
+// errors that can be detected from types alone are reported using the same
+// diagnostic messages that Value would produce. Where the type of a result
+// depends on values that are not known until evaluation, such as the number
+// of elements produced by a "for" expression, cty.DynamicPseudoType is
+// returned.
+//
+// The returned diagnostics do not have EvalContext set, since there is no
+// EvalContext during static analysis.
+func InferType(expr Expression, ctx *TypeContext) (cty.Type, hcl.Diagnostics) {
+	c := &typeChecker{
+		symbols:      make(map[*AnonSymbolExpr]cty.Value),
+		evalContexts: make(map[*TypeContext]*hcl.EvalContext),
+	}
+	val, diags := c.check(expr, ctx)
+	return val.Type(), diags
+}
+
+// typeChecker is the implementation of InferType.
+//
+// Internally the checker produces values rather than types, so that it can
+// reuse the same operations as evaluation. The values are unknown except
+// where they come directly from literals in the expression, which allows
+// static keys and indices to be checked exactly.
+type typeChecker struct {
+	// symbols tracks the item values of the splat expressions that are
+	// currently being checked.
+	symbols map[*AnonSymbolExpr]cty.Value
+
+	// evalContexts caches the result of evalContext for each TypeContext
+	// seen during a single InferType call, so that the variables of a
+	// context are converted to values only once.
+	evalContexts map[*TypeContext]*hcl.EvalContext
+}
+
+// evalContext returns an hcl.EvalContext with the same structure as the
+// given TypeContext, where each variable is an unknown value of its given
+// type.
+func (c *typeChecker) evalContext(ctx *TypeContext) *hcl.EvalContext {
+	if ctx == nil {
+		return nil
+	}
+	if ret, ok := c.evalContexts[ctx]; ok {
+		return ret
+	}
+	parent := c.evalContext(ctx.parent)
+	var ret *hcl.EvalContext
+	if parent != nil {
+		ret = parent.NewChild()
+	} else {
+		ret = &hcl.EvalContext{}
+	}
+	ret.Functions = ctx.Functions
+	if ctx.Variables != nil {
+		ret.Variables = make(map[string]cty.Value, len(ctx.Variables))
+		for name, ty := range ctx.Variables {
+			ret.Variables[name] = cty.UnknownVal(ty)
+		}
+	}
+	c.evalContexts[ctx] = ret
+	return ret
+}
+
+func (c *typeChecker) check(expr Expression, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	switch e := expr.(type) {
+	case *LiteralValueExpr:
+		return e.Val, nil
+	case *ScopeTraversalExpr:
+		val, diags := traverseAbs(e.Traversal, c.evalContext(ctx))
+		setDiagEvalContext(diags, e, nil)
+		return val, diags
+	case *RelativeTraversalExpr:
+		src, diags := c.check(e.Source, ctx)
+		ret, travDiags := traverseRel(e.Traversal, src)
+		setDiagEvalContext(travDiags, e, nil)
+		diags = append(diags, travDiags...)
+		return ret, diags
+	case *FunctionCallExpr:
+		return c.checkFunctionCall(e, ctx)
+	case *ConditionalExpr:
+		return c.checkConditional(e, ctx)
+	case *IndexExpr:
+		return c.checkIndex(e, ctx)
+	case *TupleConsExpr:
+		var diags hcl.Diagnostics
+		vals := make([]cty.Value, len(e.Exprs))
+		for i, expr := range e.Exprs {
+			val, valDiags := c.check(expr, ctx)
+			vals[i] = val
+			diags = append(diags, valDiags...)
+		}
+		return cty.TupleVal(vals), diags
+	case *ObjectConsExpr:
+		return c.checkObjectCons(e, ctx)
+	case *ObjectConsKeyExpr:
+		return c.checkObjectConsKey(e, ctx)
+	case *ForExpr:
+		return c.checkFor(e, ctx)
+	case *SplatExpr:
+		return c.checkSplat(e, ctx)
+	case *AnonSymbolExpr:
+		val, bound := c.symbols[e]
+		if !bound {
+			return cty.DynamicVal, nil
+		}
+		return val, nil
+	case *BinaryOpExpr:
+		return c.checkBinaryOp(e, ctx)
+	case *UnaryOpExpr:
+		return c.checkUnaryOp(e, ctx)
+	case *TemplateExpr:
+		return c.checkTemplate(e, ctx)
+	case *TemplateJoinExpr:
+		_, diags := c.check(e.Tuple, ctx)
+		return cty.UnknownVal(cty.String), diags
+	case *TemplateWrapExpr:
+		return c.check(e.Wrapped, ctx)
+	default:
+		// We don't know how to analyze this expression, so we'll just let
+		// it be checked during evaluation instead.
+		return cty.DynamicVal, nil
+	}
+}
+
+func (c *typeChecker) checkFunctionCall(e *FunctionCallExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	var f function.Function
+	exists := false
+	hasNonNilMap := false
+	var avail []string
+	for thisCtx := ctx; thisCtx != nil; thisCtx = thisCtx.Parent() {
+		if thisCtx.Functions == nil {
+			continue
+		}
+		hasNonNilMap = true
+		f, exists = thisCtx.Functions[e.Name]
+		if exists {
+			break
+		}
+		for name := range thisCtx.Functions {
+			avail = append(avail, name)
+		}
+	}
+
+	if !exists {
+		// We still check the arguments, so that we can report as many
+		// problems as possible in one pass.
+		for _, argExpr := range e.Args {
+			_, argDiags := c.check(argExpr, ctx)
+			diags = append(diags, argDiags...)
+		}
+
+		if !hasNonNilMap {
+			return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Function calls not allowed",
+				Detail:     "Functions may not be called here.",
+				Subject:    e.Range().Ptr(),
+				Expression: e,
+			})
+		}
+
+		sort.Strings(avail)
+		suggestion := quoty.NameSuggestion(e.Name, avail)
+		if suggestion != "" {
+			suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
+		}
+
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Call to unknown function",
+			Detail:     fmt.Sprintf("There is no function named %q.%s", e.Name, suggestion),
+			Subject:    &e.NameRange,
+			Context:    e.Range().Ptr(),
+			Expression: e,
+		})
+	}
+
+	params := f.Params()
+	varParam := f.VarParam()
+
+	args := make([]Expression, 0, len(e.Args))
+	argVals := make([]cty.Value, 0, len(e.Args))
+	for i, argExpr := range e.Args {
+		val, argDiags := c.check(argExpr, ctx)
+		diags = append(diags, argDiags...)
+		if !e.ExpandFinal || i < len(e.Args)-1 {
+			args = append(args, argExpr)
+			argVals = append(argVals, val)
+			continue
+		}
+
+		if argDiags.HasErrors() {
+			return cty.DynamicVal, diags
+		}
+
+		ty := val.Type()
+		switch {
+		case val.IsNull() && (ty.IsTupleType() || ty.IsListType() || ty.IsSetType()):
+			return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Invalid expanding argument value",
+				Detail:     "The expanding argument (indicated by ...) must not be null.",
+				Subject:    argExpr.Range().Ptr(),
+				Context:    e.Range().Ptr(),
+				Expression: argExpr,
+			})
+		case ty.IsTupleType():
+			// A tuple type tells us the number of arguments it expands
+			// to, so we can continue checking.
+			for _, ety := range ty.TupleElementTypes() {
+				args = append(args, argExpr)
+				argVals = append(argVals, cty.UnknownVal(ety))
+			}
+		case ty == cty.DynamicPseudoType, ty.IsListType(), ty.IsSetType():
+			// The number of arguments isn't known until evaluation.
+			return cty.DynamicVal, diags
+		default:
+			return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Invalid expanding argument value",
+				Detail:     "The expanding argument (indicated by ...) must be of a tuple, list, or set type.",
+				Subject:    argExpr.Range().Ptr(),
+				Context:    e.Range().Ptr(),
+				Expression: argExpr,
+			})
+		}
+	}
+
+	if len(args) < len(params) {
+		missing := params[len(args)]
+		qual := ""
+		if varParam != nil {
+			qual = " at least"
+		}
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Not enough function arguments",
+			Detail: fmt.Sprintf(
+				"Function %q expects%s %d argument(s). Missing value for %q.",
+				e.Name, qual, len(params), missing.Name,
+			),
+			Subject:    &e.CloseParenRange,
+			Context:    e.Range().Ptr(),
+			Expression: e,
+		})
+	}
+
+	if varParam == nil && len(args) > len(params) {
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Too many function arguments",
+			Detail: fmt.Sprintf(
+				"Function %q expects only %d argument(s).",
+				e.Name, len(params),
+			),
+			Subject:    args[len(params)].StartRange().Ptr(),
+			Context:    e.Range().Ptr(),
+			Expression: e,
+		})
+	}
+
+	for i, argExpr := range args {
+		var param *function.Parameter
+		if i < len(params) {
+			param = &params[i]
+		} else {
+			param = varParam
+		}
+
+		val, err := convert.Convert(argVals[i], param.Type)
+		if err != nil {
+			diags = append(diags, argumentDiagnostic(e, argExpr, param, argVals[i].Type(), err, nil))
+		}
+		argVals[i] = val
+	}
+
+	if diags.HasErrors() {
+		return cty.DynamicVal, diags
+	}
+
+	retTy, err := f.ReturnTypeForValues(argVals)
+	if err != nil {
+		var argErr function.ArgError
+		if errors.As(err, &argErr) {
+			i := argErr.Index
+			var param *function.Parameter
+			if i < len(params) {
+				param = &params[i]
+			} else {
+				param = varParam
+			}
+			argExpr := args[i]
+
+			// The diagnostic shows the path to the deep value where the
+			// error was detected, if the function returned a cty.PathError
+			// using quofn.NewArgError.
+			diags = append(diags, argumentDiagnostic(e, argExpr, param, cty.NilType, err, nil))
+		} else {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Error in function call",
+				Detail: fmt.Sprintf(
+					"Call to function %q failed: %s.",
+					e.Name, err,
+				),
+				Subject:    e.StartRange().Ptr(),
+				Context:    e.Range().Ptr(),
+				Expression: e,
+			})
+		}
+
+		return cty.DynamicVal, diags
+	}
+
+	return cty.UnknownVal(retTy), diags
+}
+
+func (c *typeChecker) checkConditional(e *ConditionalExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	condResult, diags := c.check(e.Condition, ctx)
+	trueResult, trueDiags := c.check(e.TrueResult, ctx)
+	falseResult, falseDiags := c.check(e.FalseResult, ctx)
+	diags = append(diags, trueDiags...)
+	diags = append(diags, falseDiags...)
+
+	resultType := cty.DynamicPseudoType
+	switch {
+	case trueResult.RawEquals(cty.NullVal(cty.DynamicPseudoType)):
+		resultType = falseResult.Type()
+	case falseResult.RawEquals(cty.NullVal(cty.DynamicPseudoType)):
+		resultType = trueResult.Type()
+	case trueResult.Type() == cty.DynamicPseudoType, falseResult.Type() == cty.DynamicPseudoType:
+		// the final resultType type is still unknown
+	default:
+		resultType, _ = convert.UnifyUnsafe([]cty.Type{trueResult.Type(), falseResult.Type()})
+	}
+
+	if resultType == cty.NilType {
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Inconsistent conditional result types",
+			Detail:     inconsistentResultsDetail(trueResult.Type(), falseResult.Type()),
+			Subject:    hcl.RangeBetween(e.TrueResult.Range(), e.FalseResult.Range()).Ptr(),
+			Context:    &e.SrcRange,
+			Expression: e,
+		})
+	}
+
+	if condResult.IsNull() {
+		return cty.UnknownVal(resultType), append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Null condition",
+			Detail:     "The condition value is null. Conditions must either be true or false.",
+			Subject:    e.Condition.Range().Ptr(),
+			Context:    &e.SrcRange,
+			Expression: e.Condition,
+		})
+	}
+	if _, err := convert.Convert(condResult, cty.Bool); err != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Incorrect condition type",
+			Detail:     "The condition expression must be of type bool.",
+			Subject:    e.Condition.Range().Ptr(),
+			Context:    &e.SrcRange,
+			Expression: e.Condition,
+		})
+	}
+
+	return cty.UnknownVal(resultType), diags
+}
+
+func (c *typeChecker) checkIndex(e *IndexExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	coll, diags := c.check(e.Collection, ctx)
+	key, keyDiags := c.check(e.Key, ctx)
+	diags = append(diags, keyDiags...)
+
+	val, indexDiags := quoty.Index(coll, key, &e.SrcRange)
+	setDiagEvalContext(indexDiags, e, nil)
+	diags = append(diags, indexDiags...)
+	return val, diags
+}
+
+func (c *typeChecker) checkObjectCons(e *ObjectConsExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+
+	// As with evaluation, we can only produce an object type if we know
+	// all of the keys.
+	known := true
+
+	vals := make(map[string]cty.Value, len(e.Items))
+	for _, item := range e.Items {
+		key, keyDiags := c.check(item.KeyExpr, ctx)
+		diags = append(diags, keyDiags...)
+
+		val, valDiags := c.check(item.ValueExpr, ctx)
+		diags = append(diags, valDiags...)
+
+		if keyDiags.HasErrors() {
+			known = false
+			continue
+		}
+
+		if key.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Null value as key",
+				Detail:     "Can't use a null value as a key.",
+				Subject:    item.ValueExpr.Range().Ptr(),
+				Expression: item.KeyExpr,
+			})
+			known = false
+			continue
+		}
+
+		key, err := quoty.ToString(key)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Incorrect key type",
+				Detail:     fmt.Sprintf("Can't use this value as a key: %s.", err.Error()),
+				Subject:    item.KeyExpr.Range().Ptr(),
+				Expression: item.KeyExpr,
+			})
+			known = false
+			continue
+		}
+
+		if !key.IsKnown() {
+			known = false
+			continue
+		}
+
+		vals[key.AsString()] = val
+	}
+
+	if !known {
+		return cty.DynamicVal, diags
+	}
+
+	return cty.ObjectVal(vals), diags
+}
+
+func (c *typeChecker) checkObjectConsKey(e *ObjectConsKeyExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	if !e.ForceNonLiteral {
+		if travExpr, isTraversal := e.Wrapped.(*ScopeTraversalExpr); isTraversal && len(travExpr.Traversal) > 1 {
+			return cty.DynamicVal, hcl.Diagnostics{
+				{
+					Severity: hcl.DiagError,
+					Summary:  "Ambiguous attribute key",
+					Detail:   "If this expression is intended to be a reference, wrap it in parentheses. If it's instead intended as a literal name containing periods, wrap it in quotes to create a string literal.",
+					Subject:  e.Range().Ptr(),
+				},
+			}
+		}
+
+		if ln := e.literalName(); ln != "" {
+			return cty.StringVal(ln), nil
+		}
+	}
+	return c.check(e.Wrapped, ctx)
+}
+
+func (c *typeChecker) checkFor(e *ForExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	collVal, diags := c.check(e.CollExpr, ctx)
+
+	if collVal.IsNull() {
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Iteration over null value",
+			Detail:     "A null value cannot be used as the collection in a 'for' expression.",
+			Subject:    e.CollExpr.Range().Ptr(),
+			Context:    &e.SrcRange,
+			Expression: e.CollExpr,
+		})
+	}
+
+	// The number of elements in the result generally depends on the
+	// values in the collection, so we can't predict the result type.
+	// We do still check the other clauses against the types of the
+	// iteration symbols, since that's where most mistakes will be.
+	keyTy, valTy := cty.DynamicPseudoType, cty.DynamicPseudoType
+	collTy := collVal.Type()
+	switch {
+	case collTy == cty.DynamicPseudoType:
+		// We'll check the clauses with dynamic symbols.
+	case !collVal.CanIterateElements():
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Iteration over non-iterable value",
+			Detail: fmt.Sprintf(
+				"A value of type %s cannot be used as the collection in a 'for' expression.",
+				collTy.FriendlyName(),
+			),
+			Subject:    e.CollExpr.Range().Ptr(),
+			Context:    &e.SrcRange,
+			Expression: e.CollExpr,
+		})
+	default:
+		keyTy, valTy = iterationTypes(collTy)
+	}
+
+	childCtx := ctx.NewChild()
+	childCtx.Variables = map[string]cty.Type{}
+	if e.KeyVar != "" {
+		childCtx.Variables[e.KeyVar] = keyTy
+	}
+	childCtx.Variables[e.ValVar] = valTy
+
+	if e.CondExpr != nil {
+		result, condDiags := c.check(e.CondExpr, childCtx)
+		diags = append(diags, condDiags...)
+		if result.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Condition is null",
+				Detail:     "The value of the 'if' clause must not be null.",
+				Subject:    e.CondExpr.Range().Ptr(),
+				Context:    &e.SrcRange,
+				Expression: e.CondExpr,
+			})
+		} else if _, err := convert.Convert(result, cty.Bool); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Invalid 'for' condition",
+				Detail:     fmt.Sprintf("The 'if' clause value is invalid: %s.", err.Error()),
+				Subject:    e.CondExpr.Range().Ptr(),
+				Context:    &e.SrcRange,
+				Expression: e.CondExpr,
+			})
+		}
+	}
+
+	if e.KeyExpr != nil {
+		keyRaw, keyDiags := c.check(e.KeyExpr, childCtx)
+		diags = append(diags, keyDiags...)
+		if keyRaw.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Invalid object key",
+				Detail:     "Key expression in 'for' expression must not produce a null value.",
+				Subject:    e.KeyExpr.Range().Ptr(),
+				Context:    &e.SrcRange,
+				Expression: e.KeyExpr,
+			})
+		} else if _, err := quoty.ToString(keyRaw); err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Invalid object key",
+				Detail:     fmt.Sprintf("The key expression produced an invalid result: %s.", err.Error()),
+				Subject:    e.KeyExpr.Range().Ptr(),
+				Context:    &e.SrcRange,
+				Expression: e.KeyExpr,
+			})
+		}
+	}
+
+	_, valDiags := c.check(e.ValExpr, childCtx)
+	diags = append(diags, valDiags...)
+
+	return cty.DynamicVal, diags
+}
+
+func (c *typeChecker) checkSplat(e *SplatExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	sourceVal, diags := c.check(e.Source, ctx)
+	if diags.HasErrors() {
+		_, eachDiags := c.check(e.Each, ctx)
+		diags = append(diags, eachDiags...)
+		return cty.DynamicVal, diags
+	}
+
+	sourceTy := sourceVal.Type()
+	if sourceTy == cty.DynamicPseudoType {
+		return cty.DynamicVal, diags
+	}
+
+	autoUpgrade := !(sourceTy.IsTupleType() || sourceTy.IsListType() || sourceTy.IsSetType())
+
+	if sourceVal.IsNull() {
+		if autoUpgrade {
+			return cty.EmptyTupleVal, diags
+		}
+		return cty.DynamicVal, append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Splat of null value",
+			Detail:     "Splat expressions (with the * symbol) cannot be applied to null sequences.",
+			Subject:    e.Source.Range().Ptr(),
+			Context:    hcl.RangeBetween(e.Source.Range(), e.MarkerRange).Ptr(),
+			Expression: e.Source,
+		})
+	}
+
+	if autoUpgrade {
+		sourceTy = cty.Tuple([]cty.Type{sourceTy})
+	}
+
+	defer delete(c.symbols, e.Item)
+	switch {
+	case sourceTy.IsListType() || sourceTy.IsSetType():
+		c.symbols[e.Item] = cty.UnknownVal(sourceTy.ElementType())
+		val, itemDiags := c.check(e.Each, ctx)
+		diags = append(diags, itemDiags...)
+		return cty.UnknownVal(cty.List(val.Type())), diags
+	default:
+		etys := sourceTy.TupleElementTypes()
+		resultTys := make([]cty.Type, 0, len(etys))
+		for _, ety := range etys {
+			c.symbols[e.Item] = cty.UnknownVal(ety)
+			val, itemDiags := c.check(e.Each, ctx)
+			diags = append(diags, itemDiags...)
+			resultTys = append(resultTys, val.Type())
+		}
+		return cty.UnknownVal(cty.Tuple(resultTys)), diags
+	}
+}
+
+func (c *typeChecker) checkBinaryOp(e *BinaryOpExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	impl := e.Op.Impl // assumed to be a function taking exactly two arguments
+	params := impl.Params()
+	lhsParam := params[0]
+	rhsParam := params[1]
+
+	givenLHSVal, diags := c.check(e.LHS, ctx)
+	givenRHSVal, rhsDiags := c.check(e.RHS, ctx)
+	diags = append(diags, rhsDiags...)
+
+	lhsVal, err := convert.Convert(givenLHSVal, lhsParam.Type)
+	if err != nil {
+		diags = append(diags, operandDiagnostic("left", e.LHS, e.SrcRange, givenLHSVal.Type(), lhsParam.Type, err, nil))
+	}
+	rhsVal, err := convert.Convert(givenRHSVal, rhsParam.Type)
+	if err != nil {
+		diags = append(diags, operandDiagnostic("right", e.RHS, e.SrcRange, givenRHSVal.Type(), rhsParam.Type, err, nil))
+	}
+
+	if diags.HasErrors() {
+		return cty.UnknownVal(e.Op.Type), diags
+	}
+
+	return c.operationResult(e, e.Op, []cty.Value{lhsVal, rhsVal}, diags)
+}
+
+func (c *typeChecker) checkUnaryOp(e *UnaryOpExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	impl := e.Op.Impl // assumed to be a function taking exactly one argument
+	param := impl.Params()[0]
+
+	givenVal, diags := c.check(e.Val, ctx)
+
+	val, err := convert.Convert(givenVal, param.Type)
+	if err != nil {
+		diags = append(diags, operandDiagnostic("unary", e.Val, e.SrcRange, givenVal.Type(), param.Type, err, nil))
+	}
+
+	if diags.HasErrors() {
+		return cty.UnknownVal(e.Op.Type), diags
+	}
+
+	return c.operationResult(e, e.Op, []cty.Value{val}, diags)
+}
+
+// operationResult determines the result type of the given operation applied
+// to the given already-converted arguments.
+func (c *typeChecker) operationResult(e Expression, op *Operation, args []cty.Value, diags hcl.Diagnostics) (cty.Value, hcl.Diagnostics) {
+	retTy, err := op.Impl.ReturnTypeForValues(args)
+	if err != nil {
+		return cty.UnknownVal(op.Type), append(diags, &hcl.Diagnostic{
+			Severity:   hcl.DiagError,
+			Summary:    "Operation failed",
+			Detail:     fmt.Sprintf("Error during operation: %s.", err),
+			Subject:    e.Range().Ptr(),
+			Expression: e,
+		})
+	}
+	return cty.UnknownVal(retTy), diags
+}
+
+func (c *typeChecker) checkTemplate(e *TemplateExpr, ctx *TypeContext) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	known := true
+	var str string
+
+	for _, part := range e.Parts {
+		partVal, partDiags := c.check(part, ctx)
+		diags = append(diags, partDiags...)
+
+		if partVal.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity:   hcl.DiagError,
+				Summary:    "Invalid template interpolation value",
+				Detail:     "The expression result is null. Cannot include a null value in a string template.",
+				Subject:    part.Range().Ptr(),
+				Context:    &e.SrcRange,
+				Expression: part,
+			})
+			continue
+		}
+
+		// Unlike evaluation, we check the conversion even for unknown
+		// values, because the conversion rules depend only on the type.
+		strVal, err := quoty.ToString(partVal)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid template interpolation value",
+				Detail: fmt.Sprintf(
+					"Cannot include the given value in a string template: %s.",
+					err.Error(),
+				),
+				Subject:    part.Range().Ptr(),
+				Context:    &e.SrcRange,
+				Expression: part,
+			})
+			continue
+		}
+
+		if !strVal.IsKnown() {
+			known = false
+			continue
+		}
+		str += strVal.AsString()
+	}
+
+	if !known {
+		return cty.UnknownVal(cty.String), diags
+	}
+	return cty.StringVal(str), diags
+}
+
+// iterationTypes returns the types of the key and value symbols of a 'for'
+// expression that iterates over a collection of the given type, which are
+// cty.DynamicPseudoType if they can't be predicted.
+func iterationTypes(collTy cty.Type) (keyTy, valTy cty.Type) {
+	switch {
+	case collTy.IsListType():
+		return quoty.Number, collTy.ElementType()
+	case collTy.IsSetType():
+		return collTy.ElementType(), collTy.ElementType()
+	case collTy.IsMapType():
+		return cty.String, collTy.ElementType()
+	case collTy.IsTupleType():
+		return quoty.Number, unifyElementTypes(collTy.TupleElementTypes())
+	case collTy.IsObjectType():
+		atys := make([]cty.Type, 0, len(collTy.AttributeTypes()))
+		for _, aty := range collTy.AttributeTypes() {
+			atys = append(atys, aty)
+		}
+		return cty.String, unifyElementTypes(atys)
+	default:
+		return cty.DynamicPseudoType, cty.DynamicPseudoType
+	}
+}
+
+// unifyElementTypes returns the type that all of the given types can
+// convert to, or cty.DynamicPseudoType if there is no such type.
+func unifyElementTypes(tys []cty.Type) cty.Type {
+	if len(tys) == 0 {
+		return cty.DynamicPseudoType
+	}
+	ty, _ := convert.UnifyUnsafe(tys)
+	if ty == cty.NilType {
+		return cty.DynamicPseudoType
+	}
+	return ty
+}
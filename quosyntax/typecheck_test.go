@@ -0,0 +1,386 @@
+package quosyntax
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+func TestInferType(t *testing.T) {
+	assetTy := cty.Object(map[string]cty.Type{
+		"code":   cty.String,
+		"issuer": cty.String,
+	})
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{
+			"price":    quoty.Number,
+			"name":     cty.String,
+			"enabled":  cty.Bool,
+			"asset":    assetTy,
+			"assets":   cty.List(assetTy),
+			"prices":   cty.Map(quoty.Number),
+			"pair":     cty.Tuple([]cty.Type{assetTy, assetTy}),
+			"anything": cty.DynamicPseudoType,
+		},
+		Functions: map[string]function.Function{
+			"upper": stdlib.UpperFunc,
+			"max":   stdlib.MaxFunc,
+		},
+	}
+
+	tests := []struct {
+		input     string
+		ctx       *TypeContext
+		want      cty.Type
+		diagCount int
+	}{
+		{
+			`1`,
+			nil,
+			quoty.Number,
+			0,
+		},
+		{
+			`price * 2 + 1`,
+			ctx,
+			quoty.Number,
+			0,
+		},
+		{
+			`price + "USD"`,
+			ctx,
+			quoty.Number,
+			1, // Invalid operand
+		},
+		{
+			`-asset`,
+			ctx,
+			cty.Number,
+			1, // Invalid operand
+		},
+		{
+			`price > 2 && enabled`,
+			ctx,
+			cty.Bool,
+			0,
+		},
+		{
+			`asset.code`,
+			ctx,
+			cty.String,
+			0,
+		},
+		{
+			`asset.cod`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Unsupported attribute
+		},
+		{
+			`nope`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Unknown variable
+		},
+		{
+			`anything.foo[0].bar`,
+			ctx,
+			cty.DynamicPseudoType,
+			0,
+		},
+		{
+			`assets[0].issuer`,
+			ctx,
+			cty.String,
+			0,
+		},
+		{
+			`prices["XLM"]`,
+			ctx,
+			quoty.Number,
+			0,
+		},
+		{
+			`pair[1].code`,
+			ctx,
+			cty.String,
+			0,
+		},
+		{
+			`pair[2]`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Invalid index
+		},
+		{
+			`name[0]`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Invalid index
+		},
+		{
+			`assets[*].code`,
+			ctx,
+			cty.List(cty.String),
+			0,
+		},
+		{
+			`assets[*].cod`,
+			ctx,
+			cty.List(cty.DynamicPseudoType),
+			1, // Unsupported attribute
+		},
+		{
+			`asset.*.issuer`,
+			ctx,
+			cty.Tuple([]cty.Type{cty.String}),
+			0,
+		},
+		{
+			`upper(name)`,
+			ctx,
+			cty.String,
+			0,
+		},
+		{
+			`upper(asset)`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Invalid function argument
+		},
+		{
+			`upper(name, name)`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Too many function arguments
+		},
+		{
+			`upper()`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Not enough function arguments
+		},
+		{
+			`uper(name)`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Call to unknown function
+		},
+		{
+			`uper(asset.cod)`,
+			ctx,
+			cty.DynamicPseudoType,
+			2, // Unsupported attribute and Call to unknown function
+		},
+		{
+			`upper("a")`,
+			&TypeContext{},
+			cty.DynamicPseudoType,
+			1, // Function calls not allowed
+		},
+		{
+			`upper([name]...)`,
+			ctx,
+			cty.String,
+			0,
+		},
+		{
+			`upper(name...)`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Invalid expanding argument value
+		},
+		{
+			`enabled ? price : 0`,
+			ctx,
+			quoty.Number,
+			0,
+		},
+		{
+			`enabled ? asset : null`,
+			ctx,
+			assetTy,
+			0,
+		},
+		{
+			`enabled ? asset : price`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Inconsistent conditional result types
+		},
+		{
+			`asset ? 1 : 2`,
+			ctx,
+			quoty.Number,
+			1, // Incorrect condition type
+		},
+		{
+			`{ code = name, amount = price }`,
+			ctx,
+			cty.Object(map[string]cty.Type{
+				"code":   cty.String,
+				"amount": quoty.Number,
+			}),
+			0,
+		},
+		{
+			`{ code = name }.amount`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Unsupported attribute
+		},
+		{
+			`{ (name) = price }`,
+			ctx,
+			cty.DynamicPseudoType,
+			0,
+		},
+		{
+			`[name, price][1]`,
+			ctx,
+			quoty.Number,
+			0,
+		},
+		{
+			`"${asset.code}/${price}"`,
+			ctx,
+			cty.String,
+			0,
+		},
+		{
+			`"x${asset}"`,
+			ctx,
+			cty.String,
+			1, // Invalid template interpolation value
+		},
+		{
+			`[for a in assets: a.code]`,
+			ctx,
+			cty.DynamicPseudoType,
+			0,
+		},
+		{
+			`[for a in assets: a.cod]`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Unsupported attribute
+		},
+		{
+			`{for k, v in prices: k => v * 2 if v > 0}`,
+			ctx,
+			cty.DynamicPseudoType,
+			0,
+		},
+		{
+			`{for k, v in prices: v => k if k}`,
+			ctx,
+			cty.DynamicPseudoType,
+			0, // strings can convert to bool and numbers to strings
+		},
+		{
+			`[for a in assets: a if a]`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Invalid 'for' condition
+		},
+		{
+			`[for c in price: c]`,
+			ctx,
+			cty.DynamicPseudoType,
+			1, // Iteration over non-iterable value
+		},
+		{
+			`"%{ for a in assets }${a.code}%{ endfor }"`,
+			ctx,
+			cty.String,
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(test.input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			got, diags := InferType(expr, test.ctx)
+
+			if len(diags) != test.diagCount {
+				t.Errorf("wrong number of diagnostics %d; want %d", len(diags), test.diagCount)
+				for _, diag := range diags {
+					t.Logf(" - %s", diag.Error())
+				}
+			}
+
+			if !got.Equals(test.want) {
+				t.Errorf(
+					"wrong result\nexpr: %s\ngot:  %#v\nwant: %#v",
+					test.input, got, test.want,
+				)
+			}
+		})
+	}
+}
+
+func TestInferTypeMatchesValue(t *testing.T) {
+	// For expressions whose errors don't depend on values, the diagnostics
+	// from InferType should be the same as from evaluating the expression
+	// with unknown values of the same types.
+	ctx := &TypeContext{
+		Variables: map[string]cty.Type{
+			"price": quoty.Number,
+			"asset": cty.Object(map[string]cty.Type{
+				"code": cty.String,
+			}),
+		},
+		Functions: map[string]function.Function{
+			"upper": stdlib.UpperFunc,
+		},
+	}
+
+	tests := []string{
+		`price + "USD"`,
+		`asset.cod`,
+		`prices`,
+		`upper(asset)`,
+		`uper(asset.code)`,
+		`upper()`,
+		`asset.code ? asset : price`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			expr, parseDiags := ParseExpression([]byte(input), "", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+			if len(parseDiags) != 0 {
+				t.Fatalf("unexpected parse diagnostics")
+			}
+
+			_, typeDiags := InferType(expr, ctx)
+			_, valDiags := expr.Value((&typeChecker{evalContexts: map[*TypeContext]*hcl.EvalContext{}}).evalContext(ctx))
+
+			if len(typeDiags) == 0 {
+				t.Fatalf("no diagnostics from InferType")
+			}
+			if len(typeDiags) != len(valDiags) {
+				t.Fatalf("got %d diagnostics from InferType but %d from Value", len(typeDiags), len(valDiags))
+			}
+			for i := range typeDiags {
+				got, want := typeDiags[i], valDiags[i]
+				if got.Summary != want.Summary || got.Detail != want.Detail {
+					t.Errorf(
+						"wrong diagnostic %d\ngot:  %s: %s\nwant: %s: %s",
+						i, got.Summary, got.Detail, want.Summary, want.Detail,
+					)
+				}
+				if *got.Subject != *want.Subject {
+					t.Errorf("wrong subject for diagnostic %d\ngot:  %s\nwant: %s", i, got.Subject, want.Subject)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package quoty
 import (
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
@@ -62,7 +63,12 @@ func Index(collection, key cty.Value, srcRange *hcl.Range) (cty.Value, hcl.Diagn
 			panic("don't know what key type we want")
 		}
 
-		key, keyErr := convert.Convert(key, wantType)
+		var keyErr error
+		if wantType == cty.String {
+			key, keyErr = ToString(key)
+		} else {
+			key, keyErr = convert.Convert(key, wantType)
+		}
 		if keyErr != nil {
 			return cty.DynamicVal, hcl.Diagnostics{
 				{
@@ -121,7 +127,7 @@ func Index(collection, key cty.Value, srcRange *hcl.Range) (cty.Value, hcl.Diagn
 		return collection.Index(key), nil
 
 	case ty.IsObjectType():
-		key, keyErr := convert.Convert(key, cty.String)
+		key, keyErr := ToString(key)
 		if keyErr != nil {
 			return cty.DynamicVal, hcl.Diagnostics{
 				{
@@ -145,11 +151,15 @@ func Index(collection, key cty.Value, srcRange *hcl.Range) (cty.Value, hcl.Diagn
 		attrName := key.AsString()
 
 		if !ty.HasAttribute(attrName) {
+			suggestion := NameSuggestion(attrName, AttributeNames(ty))
+			if suggestion != "" {
+				suggestion = fmt.Sprintf(" Did you mean %q?", suggestion)
+			}
 			return cty.DynamicVal, hcl.Diagnostics{
 				{
 					Severity: hcl.DiagError,
 					Summary:  "Invalid index",
-					Detail:   "The given key does not identify an element in this collection value.",
+					Detail:   "The given key does not identify an element in this collection value." + suggestion,
 					Subject:  srcRange,
 				},
 			}
@@ -168,3 +178,15 @@ func Index(collection, key cty.Value, srcRange *hcl.Range) (cty.Value, hcl.Diagn
 		}
 	}
 }
+
+// AttributeNames returns the names of the attributes of the given object
+// type, in lexical order.
+func AttributeNames(ty cty.Type) []string {
+	atys := ty.AttributeTypes()
+	ret := make([]string, 0, len(atys))
+	for name := range atys {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
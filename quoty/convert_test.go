@@ -2,6 +2,7 @@ package quoty
 
 import (
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/zclconf/go-cty/cty"
@@ -89,6 +90,32 @@ func TestConvert(t *testing.T) {
 			`number required`,
 		},
 
+		// To StellarAssetAmountType
+		{
+			MustParseNumberVal("2.5123456"),
+			StellarAssetAmountType,
+			StellarAssetAmountVal(StellarAssetAmount(25123456)),
+			``,
+		},
+		{
+			cty.StringVal("-1"),
+			StellarAssetAmountType,
+			StellarAssetAmountVal(StellarAssetAmount(-10000000)),
+			``,
+		},
+		{
+			MustParseNumberVal("0.00000001"),
+			StellarAssetAmountType,
+			cty.NilVal,
+			`an amount may have at most seven decimal places`,
+		},
+		{
+			MustParseNumberVal("10000000000000"),
+			StellarAssetAmountType,
+			cty.NilVal,
+			`amount is out of range`,
+		},
+
 		// Normal cty conversions should still be working
 		{
 			cty.StringVal("hi"),
@@ -126,3 +153,74 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestToString(t *testing.T) {
+	tests := []struct {
+		in      cty.Value
+		want    cty.Value
+		wantErr string
+	}{
+		{
+			MustParseNumberVal("1.50"),
+			cty.StringVal("1.5"),
+			``,
+		},
+		{
+			MustParseNumberVal("-0.0000001"),
+			cty.StringVal("-0.0000001"),
+			``,
+		},
+		{
+			MustParseNumberVal("100"),
+			cty.StringVal("100"),
+			``,
+		},
+		{
+			NumberVal(big.NewRat(1, 3)),
+			cty.StringVal("0.33333333333333333333"),
+			``,
+		},
+		{
+			cty.NullVal(Number),
+			cty.NullVal(cty.String),
+			``,
+		},
+		{
+			cty.UnknownVal(Number),
+			cty.UnknownVal(cty.String),
+			``,
+		},
+		{
+			cty.True,
+			cty.StringVal("true"),
+			``,
+		},
+		{
+			cty.ListValEmpty(cty.String),
+			cty.NilVal,
+			`string required`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%#v", test.in), func(t *testing.T) {
+			got, err := ToString(test.in)
+
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !test.want.RawEquals(got) {
+					t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.want)
+				}
+			} else {
+				if err == nil {
+					t.Fatalf("wrong error:\ngot:  <no error>\nwant: %s", test.wantErr)
+				}
+				if got, want := err.Error(), test.wantErr; got != want {
+					t.Fatalf("wrong error:\ngot:  %s\nwant: %s", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package quoty
+
+import (
+	"unicode/utf8"
+
+	"github.com/agext/levenshtein"
+)
+
+// NameSuggestion tries to find a name from the given slice of suggested names
+// that is close to the given name and returns it if found. If no suggestion
+// is close enough, returns the empty string.
+//
+// This is exported so that the Quo syntaxes and applications can suggest
+// names for typos in a consistent way, as in "Did you mean ...?" details of
+// diagnostics.
+//
+// The closest suggestion is returned, by Levenshtein distance except that a
+// transposition of two adjacent characters counts as a single edit. Earlier
+// suggestions take precedence if the given string is equally similar to two
+// or more suggestions, so callers that gather suggestions from a map should
+// sort them first for the result to be consistent.
+//
+// Suggestions whose lengths alone make them further away than the closest
+// one found so far are skipped without calculating their distance, so this
+// is suitable for large numbers of suggestions.
+func NameSuggestion(given string, suggestions []string) string {
+	const threshold = 3 // determined experimentally
+
+	givenLen := utf8.RuneCountInString(given)
+	best, bestDist := "", threshold
+	for _, suggestion := range suggestions {
+		lenDiff := utf8.RuneCountInString(suggestion) - givenLen
+		if lenDiff < 0 {
+			lenDiff = -lenDiff
+		}
+		if lenDiff >= bestDist {
+			continue
+		}
+		dist := levenshtein.Distance(given, suggestion, nil)
+		if dist == 2 && isTransposition(given, suggestion) {
+			// Swapping two adjacent characters is a common typo, so we'll
+			// count it as a single edit rather than two substitutions.
+			dist = 1
+		}
+		if dist < bestDist {
+			best, bestDist = suggestion, dist
+			if dist == 0 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// isTransposition returns true if the given strings differ only by the order
+// of two adjacent characters.
+func isTransposition(a, b string) bool {
+	ar, br := []rune(a), []rune(b)
+	if len(ar) != len(br) {
+		return false
+	}
+	for i := 0; i < len(ar)-1; i++ {
+		if ar[i] != br[i] {
+			return ar[i] == br[i+1] && ar[i+1] == br[i] && string(ar[i+2:]) == string(br[i+2:])
+		}
+	}
+	return false
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
 // Number is the specialized number type used for numeric expressions in
@@ -58,7 +60,7 @@ var numberOps = &cty.CapsuleOps{
 				if acc != big.Exact {
 					// Rat is defined to return non-exact only if the input is
 					// an infinity.
-					return cty.NilVal, errors.New("infinity is not allowed")
+					return cty.NilVal, path.NewErrorf("infinity is not allowed")
 				}
 				return br, nil
 			}
@@ -66,7 +68,7 @@ var numberOps = &cty.CapsuleOps{
 			return func(in cty.Value, path cty.Path) (interface{}, error) {
 				v, err := ParseNumberVal(in.AsString())
 				if err != nil {
-					return cty.NilVal, errors.New("a number is required")
+					return cty.NilVal, path.NewErrorf("a number is required")
 				}
 				return v.EncapsulatedValue(), nil
 			}
@@ -135,6 +137,84 @@ func MustParseNumberVal(s string) cty.Value {
 	return v
 }
 
+// ExactDecimalPlaces returns the number of digits required after the decimal
+// point to represent the given number exactly in decimal notation. The second
+// return value is false if the number has no finite decimal representation,
+// such as one third.
+func ExactDecimalPlaces(br *big.Rat) (int, bool) {
+	// A reduced fraction has a finite decimal representation only if the
+	// prime factors of its denominator are all 2 or 5, and then the number
+	// of places required is the greater of the two exponents.
+	d := new(big.Int).Set(br.Denom())
+	var twos, fives int
+	var q, r big.Int
+	for {
+		q.QuoRem(d, big.NewInt(2), &r)
+		if r.Sign() != 0 {
+			break
+		}
+		d.Set(&q)
+		twos++
+	}
+	for {
+		q.QuoRem(d, big.NewInt(5), &r)
+		if r.Sign() != 0 {
+			break
+		}
+		d.Set(&q)
+		fives++
+	}
+	if !d.IsInt64() || d.Int64() != 1 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// maxInexactDecimalPlaces is the number of decimal places used when
+// converting to string a number that has no finite decimal representation.
+const maxInexactDecimalPlaces = 20
+
+// numberString returns the decimal string representation of the given
+// number. The result is exact if the number has a finite decimal
+// representation, and rounded to maxInexactDecimalPlaces places otherwise.
+func numberString(br *big.Rat) string {
+	if br.IsInt() {
+		return br.Num().String()
+	}
+	if places, exact := ExactDecimalPlaces(br); exact {
+		return br.FloatString(places)
+	}
+	s := br.FloatString(maxInexactDecimalPlaces)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// ToString is a helper function that converts the given value to a string in
+// the same way as the Quo expression language does for the interpolations of
+// string templates and for object keys.
+//
+// A Number is written in decimal notation, exactly if it has a finite
+// decimal representation and rounded to 20 decimal places otherwise. Any
+// other value is converted using convert.Convert. Number has no implicit
+// conversion to string, so that numbers are converted only where the
+// language explicitly calls for it.
+func ToString(val cty.Value) (cty.Value, error) {
+	if !val.Type().Equals(Number) {
+		return convert.Convert(val, cty.String)
+	}
+	switch {
+	case val.IsNull():
+		return cty.NullVal(cty.String), nil
+	case !val.IsKnown():
+		return cty.UnknownVal(cty.String), nil
+	default:
+		return cty.StringVal(numberString(val.EncapsulatedValue().(*big.Rat))), nil
+	}
+}
+
 func ratFromStellarAssetVal(v StellarAssetAmount) *big.Rat {
 	var br big.Rat
 	br.SetFrac64(int64(v), 10000000)
@@ -0,0 +1,137 @@
+package quoty
+
+import (
+	"math/big"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Precision is a policy for limiting the precision of numbers, to prevent
+// the numerators and denominators of the results of repeated arithmetic
+// from growing without limit.
+//
+// The zero value of Precision does not limit precision at all.
+type Precision struct {
+	// MaxDenominator, if greater than zero, causes numbers whose
+	// denominator is greater than MaxDenominator to be rounded to a multiple
+	// of one over MaxDenominator. For example, a MaxDenominator of 10000000
+	// limits numbers to the precision of Stellar asset amounts.
+	MaxDenominator int64
+
+	// MaxDigits, if greater than zero, causes numbers to be rounded to at
+	// most MaxDigits significant decimal digits.
+	//
+	// If both MaxDigits and MaxDenominator are set then the number of
+	// significant digits is limited first, and then the denominator.
+	MaxDigits int
+
+	// Rounding selects the direction in which numbers are rounded. The zero
+	// value, big.ToNearestEven, rounds to the nearest representable number
+	// and rounds ties to the even one.
+	Rounding big.RoundingMode
+}
+
+// Round applies the precision policy to the given number value, returning
+// the rounded value and true if rounding changed the value, or the given
+// value and false otherwise.
+//
+// Values that are not known, non-null numbers are returned unchanged.
+func (p Precision) Round(v cty.Value) (cty.Value, bool) {
+	if !v.Type().Equals(Number) || !v.IsKnown() || v.IsNull() {
+		return v, false
+	}
+	br := v.EncapsulatedValue().(*big.Rat)
+	ret, changed := p.RoundRat(br)
+	if !changed {
+		return v, false
+	}
+	return NumberVal(ret), true
+}
+
+// RoundRat is like Round, but for the big.Rat that is the internal
+// representation of a number value. The given rational is not modified.
+func (p Precision) RoundRat(br *big.Rat) (*big.Rat, bool) {
+	ret := br
+	if p.MaxDigits > 0 && ret.Sign() != 0 {
+		// The scale that gives MaxDigits digits is ten to the power of
+		// MaxDigits less the position of the number's most significant digit.
+		ret = roundToScale(ret, pow10Rat(p.MaxDigits-1-decimalExponent(ret)), p.Rounding)
+	}
+	if p.MaxDenominator > 0 && ret.Denom().Cmp(big.NewInt(p.MaxDenominator)) > 0 {
+		ret = roundToScale(ret, new(big.Rat).SetInt64(p.MaxDenominator), p.Rounding)
+	}
+	return ret, ret.Cmp(br) != 0
+}
+
+// roundToScale returns the given number rounded to a multiple of one over
+// the given scale, using the given rounding mode.
+func roundToScale(br *big.Rat, scale *big.Rat, mode big.RoundingMode) *big.Rat {
+	scaled := new(big.Rat).Mul(br, scale)
+	if scaled.IsInt() {
+		return br
+	}
+
+	var q, r big.Int
+	q.QuoRem(scaled.Num(), scaled.Denom(), &r) // q is truncated toward zero
+	sign := scaled.Sign()
+
+	var away bool
+	switch mode {
+	case big.ToZero:
+		away = false
+	case big.AwayFromZero:
+		away = true
+	case big.ToNegativeInf:
+		away = sign < 0
+	case big.ToPositiveInf:
+		away = sign > 0
+	default:
+		// For the nearest modes we compare twice the remainder with the
+		// denominator to find which of the candidates is nearer.
+		r2 := new(big.Int).Lsh(new(big.Int).Abs(&r), 1)
+		switch cmp := r2.Cmp(scaled.Denom()); {
+		case cmp > 0:
+			away = true
+		case cmp < 0:
+			away = false
+		case mode == big.ToNearestAway:
+			away = true
+		default:
+			away = q.Bit(0) == 1
+		}
+	}
+	if away {
+		q.Add(&q, big.NewInt(int64(sign)))
+	}
+
+	ret := new(big.Rat).SetInt(&q)
+	return ret.Quo(ret, scale)
+}
+
+// decimalExponent returns the exponent of the most significant decimal digit
+// of the given non-zero number, such that it is at least ten to the power of
+// the result but less than ten to the power of the result plus one.
+func decimalExponent(br *big.Rat) int {
+	abs := new(big.Rat).Abs(br)
+	// The difference in the number of digits of the numerator and
+	// denominator is either the exponent or one more than it.
+	exp := len(abs.Num().String()) - len(abs.Denom().String())
+	if abs.Cmp(pow10Rat(exp)) < 0 {
+		exp--
+	}
+	return exp
+}
+
+// pow10Rat returns ten to the power of the given exponent, which may be
+// negative.
+func pow10Rat(exp int) *big.Rat {
+	n := exp
+	if n < 0 {
+		n = -n
+	}
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
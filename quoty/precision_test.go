@@ -0,0 +1,82 @@
+package quoty
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestPrecisionRound(t *testing.T) {
+	tests := []struct {
+		precision Precision
+		in        string
+		want      string
+	}{
+		// No limits
+		{Precision{}, "1/3", "1/3"},
+
+		// MaxDenominator
+		{Precision{MaxDenominator: 100}, "1/3", "1/3"},
+		{Precision{MaxDenominator: 100}, "1/4", "1/4"},
+		{Precision{MaxDenominator: 100}, "1/300", "0"},
+		{Precision{MaxDenominator: 100}, "1/150", "1/100"},
+		{Precision{MaxDenominator: 100}, "-1/150", "-1/100"},
+		{Precision{MaxDenominator: 100}, "1001/1000", "1"},
+		{Precision{MaxDenominator: 10000000}, "1/15000000", "1/10000000"},
+
+		// MaxDigits
+		{Precision{MaxDigits: 3}, "1/3", "333/1000"},
+		{Precision{MaxDigits: 3}, "200/3", "667/10"},
+		{Precision{MaxDigits: 3}, "-200/3", "-667/10"},
+		{Precision{MaxDigits: 3}, "123456", "123000"},
+		{Precision{MaxDigits: 3}, "1/3000", "333/1000000"},
+		{Precision{MaxDigits: 3}, "1/100", "1/100"},
+		{Precision{MaxDigits: 3}, "999/100", "999/100"},
+		{Precision{MaxDigits: 2}, "999/100", "10"},
+		{Precision{MaxDigits: 3}, "0", "0"},
+
+		// Both, with the digits limited first
+		{Precision{MaxDigits: 4, MaxDenominator: 100}, "1/3", "33/100"},
+
+		// Rounding modes
+		{Precision{MaxDenominator: 1}, "5/2", "2"},
+		{Precision{MaxDenominator: 1}, "7/2", "4"},
+		{Precision{MaxDenominator: 1}, "-5/2", "-2"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToNearestAway}, "5/2", "3"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToNearestAway}, "-5/2", "-3"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToZero}, "-9/4", "-2"},
+		{Precision{MaxDenominator: 1, Rounding: big.AwayFromZero}, "9/4", "3"},
+		{Precision{MaxDenominator: 1, Rounding: big.AwayFromZero}, "-9/4", "-3"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToNegativeInf}, "9/4", "2"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToNegativeInf}, "-9/4", "-3"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToPositiveInf}, "9/4", "3"},
+		{Precision{MaxDenominator: 1, Rounding: big.ToPositiveInf}, "-9/4", "-2"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%+v %s", test.precision, test.in), func(t *testing.T) {
+			in, _ := new(big.Rat).SetString(test.in)
+			want, _ := new(big.Rat).SetString(test.want)
+
+			got, changed := test.precision.Round(NumberVal(in))
+			if !got.RawEquals(NumberVal(want)) {
+				t.Errorf("wrong result %#v; want %#v", got, NumberVal(want))
+			}
+			if wantChanged := in.Cmp(want) != 0; changed != wantChanged {
+				t.Errorf("wrong changed %#v; want %#v", changed, wantChanged)
+			}
+		})
+	}
+
+	t.Run("not a number", func(t *testing.T) {
+		p := Precision{MaxDigits: 1}
+		for _, v := range []cty.Value{cty.StringVal("1.5"), cty.UnknownVal(Number), cty.NullVal(Number)} {
+			got, changed := p.Round(v)
+			if changed || !got.RawEquals(v) {
+				t.Errorf("%#v was changed to %#v", v, got)
+			}
+		}
+	})
+}
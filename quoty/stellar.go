@@ -1,6 +1,9 @@
 package quoty
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
 	"reflect"
 
 	"github.com/zclconf/go-cty/cty"
@@ -12,7 +15,64 @@ import (
 // converted to StellarAssetAmountType.
 var StellarAssetAmountType cty.Type
 
-var stellarAmountOps = &cty.CapsuleOps{}
+var stellarAmountOps = &cty.CapsuleOps{
+	GoString: func(v interface{}) string {
+		amt := v.(*StellarAssetAmount)
+		return fmt.Sprintf("quoty.StellarAssetAmountVal(%d)", int64(*amt))
+	},
+	TypeGoString: func(ty reflect.Type) string {
+		return "quoty.StellarAssetAmountType"
+	},
+	RawEquals: func(a, b interface{}) bool {
+		return *(a.(*StellarAssetAmount)) == *(b.(*StellarAssetAmount))
+	},
+	ConversionTo: func(srcTy cty.Type) func(cty.Value, cty.Path) (interface{}, error) {
+		switch {
+		case srcTy.Equals(Number):
+			return func(in cty.Value, path cty.Path) (interface{}, error) {
+				br := in.EncapsulatedValue().(*big.Rat)
+				amt, err := stellarAssetAmountFromRat(br)
+				if err != nil {
+					return nil, path.NewError(err)
+				}
+				return amt, nil
+			}
+		case srcTy.Equals(cty.String):
+			return func(in cty.Value, path cty.Path) (interface{}, error) {
+				v, err := ParseNumberVal(in.AsString())
+				if err != nil {
+					return nil, path.NewErrorf("an amount is required")
+				}
+				amt, err := stellarAssetAmountFromRat(v.EncapsulatedValue().(*big.Rat))
+				if err != nil {
+					return nil, path.NewError(err)
+				}
+				return amt, nil
+			}
+		default:
+			return nil
+		}
+	},
+}
+
+var stellarAmountScale = big.NewInt(10000000)
+
+// stellarAssetAmountFromRat returns the StellarAssetAmount that exactly
+// represents the given rational number, or an error if the number has more
+// than seven decimal places or is outside of the range of an int64.
+func stellarAssetAmountFromRat(br *big.Rat) (*StellarAssetAmount, error) {
+	var scaled big.Rat
+	scaled.Mul(br, new(big.Rat).SetInt(stellarAmountScale))
+	if !scaled.IsInt() {
+		return nil, errors.New("an amount may have at most seven decimal places")
+	}
+	num := scaled.Num()
+	if !num.IsInt64() {
+		return nil, errors.New("amount is out of range")
+	}
+	amt := StellarAssetAmount(num.Int64())
+	return &amt, nil
+}
 
 // StellarAssetAmountVal wraps a StallarAssetAmount in a cty.Value of type
 // StellarAssetAmountType.
@@ -60,6 +120,36 @@ var StellarNativeAssetVal = cty.ObjectVal(map[string]cty.Value{
 	"issuer": cty.NullVal(cty.String),
 })
 
+// StellarAsset is the Go representation of a value of StellarAssetType.
+//
+// The native asset XLM is represented with an empty Issuer, which
+// corresponds to a null issuer in the cty representation.
+type StellarAsset struct {
+	Code   string
+	Issuer string
+}
+
+// IsNative returns true if the receiver represents XLM, the native asset of
+// Stellar.
+func (a StellarAsset) IsNative() bool {
+	return a.Issuer == "" && a.Code == "XLM"
+}
+
+// StellarAssetVal returns a cty value of type StellarAssetType representing
+// the given asset.
+func StellarAssetVal(a StellarAsset) cty.Value {
+	if a.Issuer == "" {
+		return cty.ObjectVal(map[string]cty.Value{
+			"code":   cty.StringVal(a.Code),
+			"issuer": cty.NullVal(cty.String),
+		})
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"code":   cty.StringVal(a.Code),
+		"issuer": cty.StringVal(a.Issuer),
+	})
+}
+
 func init() {
 	StellarAssetAmountType = cty.CapsuleWithOps(
 		"Stellar asset amount",
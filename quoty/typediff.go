@@ -0,0 +1,256 @@
+package quoty
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// TypeDifference returns a sentence describing the first difference between
+// the two given types that prevents a value of one from being converted to
+// the other, referring to the types by the given names. For example:
+//
+//	quoty.TypeDifference(a, b, "the true result", "the false result")
+//
+// might return:
+//
+//	Attribute "price" of each element of attribute "orders" is a number in
+//	the true result, but an object in the false result.
+//
+// The result is an empty string if no such difference can be found, such as
+// when the types are equal or when one of them is cty.DynamicPseudoType.
+func TypeDifference(a, b cty.Type, aName, bName string) string {
+	d := typeDifference(a, b, nil)
+	if d == nil {
+		return ""
+	}
+
+	var s string
+	loc := describePath(d.path)
+	switch {
+	case d.attr != "":
+		hasName, lacksName := aName, bName
+		if d.attrInB {
+			hasName, lacksName = bName, aName
+		}
+		if loc == "" {
+			s = fmt.Sprintf("%s has attribute %q, but %s does not.", hasName, d.attr, lacksName)
+		} else {
+			s = fmt.Sprintf("%s has attribute %q in %s, but %s does not.", hasName, d.attr, loc, lacksName)
+		}
+	case d.aLen != d.bLen:
+		if loc == "" {
+			s = fmt.Sprintf("%s has %s, but %s has %d.", aName, elementCount(d.aLen), bName, d.bLen)
+		} else {
+			s = fmt.Sprintf("%s has %s in %s, but %d in %s.", loc, elementCount(d.aLen), aName, d.bLen, bName)
+		}
+	default:
+		if loc == "" {
+			s = fmt.Sprintf("%s is %s, but %s is %s.", aName, TypeDescription(d.a), bName, TypeDescription(d.b))
+		} else {
+			s = fmt.Sprintf("%s is %s in %s, but %s in %s.", loc, TypeDescription(d.a), aName, TypeDescription(d.b), bName)
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// TypeDescription returns a short description of the given type, with an
+// indefinite article, such as "a list" or "an object". The Quo number types
+// are distinguished from cty.Number, which is described as a floating-point
+// number.
+func TypeDescription(ty cty.Type) string {
+	switch {
+	case ty == cty.DynamicPseudoType:
+		return "any type"
+	case ty.Equals(Number):
+		return "a number"
+	case ty.Equals(StellarAssetAmountType):
+		return "a Stellar asset amount"
+	case ty == cty.Number:
+		return "a floating-point number"
+	case ty == cty.String:
+		return "a string"
+	case ty == cty.Bool:
+		return "a bool"
+	case ty.IsListType():
+		return "a list"
+	case ty.IsSetType():
+		return "a set"
+	case ty.IsMapType():
+		return "a map"
+	case ty.IsTupleType():
+		return "a tuple"
+	case ty.IsObjectType():
+		return "an object"
+	default:
+		name := ty.FriendlyName()
+		if strings.IndexAny(name[:1], "aeiou") == 0 {
+			return "an " + name
+		}
+		return "a " + name
+	}
+}
+
+// typeDiff describes the difference between two types found by
+// typeDifference. If attr is set then an object has an attribute that the
+// other lacks, and otherwise the types at the path are different kinds or
+// tuple types with different numbers of elements.
+type typeDiff struct {
+	path cty.Path
+
+	attr    string
+	attrInB bool
+
+	a, b       cty.Type
+	aLen, bLen int
+}
+
+// typeDifference returns the first difference between the given types at
+// the given path, or nil if there is no difference that prevents conversion.
+func typeDifference(a, b cty.Type, path cty.Path) *typeDiff {
+	if a.Equals(b) || a == cty.DynamicPseudoType || b == cty.DynamicPseudoType {
+		return nil
+	}
+
+	switch {
+	case a.IsObjectType() && b.IsObjectType():
+		aAttrs, bAttrs := a.AttributeTypes(), b.AttributeTypes()
+		for _, name := range attributeNames(aAttrs, bAttrs) {
+			aty, inA := aAttrs[name]
+			bty, inB := bAttrs[name]
+			if !inA || !inB {
+				return &typeDiff{path: path, attr: name, attrInB: inB}
+			}
+			if d := typeDifference(aty, bty, appendPath(path, cty.GetAttrStep{Name: name})); d != nil {
+				return d
+			}
+		}
+		return nil
+
+	case a.IsTupleType() && b.IsTupleType():
+		aElems, bElems := a.TupleElementTypes(), b.TupleElementTypes()
+		if len(aElems) != len(bElems) {
+			return &typeDiff{path: path, a: a, b: b, aLen: len(aElems), bLen: len(bElems)}
+		}
+		for i := range aElems {
+			if d := typeDifference(aElems[i], bElems[i], appendPath(path, cty.IndexStep{Key: cty.NumberIntVal(int64(i))})); d != nil {
+				return d
+			}
+		}
+		return nil
+
+	case a.IsCollectionType() && b.IsCollectionType() && sameCollectionKind(a, b):
+		return typeDifference(a.ElementType(), b.ElementType(), appendPath(path, elementsStep))
+
+	// A tuple can be converted to a list or set, and an object to a map, if
+	// all of their elements can be converted to the element type.
+	case a.IsTupleType() && (b.IsListType() || b.IsSetType()):
+		return tupleCollectionDifference(a.TupleElementTypes(), b.ElementType(), path, false)
+	case b.IsTupleType() && (a.IsListType() || a.IsSetType()):
+		return tupleCollectionDifference(b.TupleElementTypes(), a.ElementType(), path, true)
+	case a.IsObjectType() && b.IsMapType():
+		return objectMapDifference(a.AttributeTypes(), b.ElementType(), path, false)
+	case b.IsObjectType() && a.IsMapType():
+		return objectMapDifference(b.AttributeTypes(), a.ElementType(), path, true)
+	}
+
+	if convert.GetConversionUnsafe(a, b) != nil || convert.GetConversionUnsafe(b, a) != nil {
+		// Primitive values can often be converted to one another, such as a
+		// string containing a number, so we'll leave it to the conversion to
+		// report a problem with the particular value.
+		return nil
+	}
+	return &typeDiff{path: path, a: a, b: b}
+}
+
+func tupleCollectionDifference(elems []cty.Type, ety cty.Type, path cty.Path, swap bool) *typeDiff {
+	for i, elem := range elems {
+		a, b := elem, ety
+		if swap {
+			a, b = b, a
+		}
+		if d := typeDifference(a, b, appendPath(path, cty.IndexStep{Key: cty.NumberIntVal(int64(i))})); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+func objectMapDifference(attrs map[string]cty.Type, ety cty.Type, path cty.Path, swap bool) *typeDiff {
+	for _, name := range attributeNames(attrs, nil) {
+		a, b := attrs[name], ety
+		if swap {
+			a, b = b, a
+		}
+		if d := typeDifference(a, b, appendPath(path, cty.GetAttrStep{Name: name})); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+func sameCollectionKind(a, b cty.Type) bool {
+	return (a.IsListType() && b.IsListType()) || (a.IsSetType() && b.IsSetType()) || (a.IsMapType() && b.IsMapType())
+}
+
+// elementsStep is the path step used for the elements of a collection, which
+// are all of the same type.
+var elementsStep = cty.IndexStep{Key: cty.UnknownVal(cty.DynamicPseudoType)}
+
+// appendPath returns a new path with the given step added to the given path,
+// without modifying the given path.
+func appendPath(path cty.Path, step cty.PathStep) cty.Path {
+	ret := make(cty.Path, len(path), len(path)+1)
+	copy(ret, path)
+	return append(ret, step)
+}
+
+// attributeNames returns the names of the attributes in either of the given
+// maps, in lexical order.
+func attributeNames(a, b map[string]cty.Type) []string {
+	var ret []string
+	for name := range a {
+		ret = append(ret, name)
+	}
+	for name := range b {
+		if _, exists := a[name]; !exists {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// describePath describes the given path within a type, such as
+// `attribute "price" of each element of attribute "orders"`, or returns
+// an empty string for the empty path.
+func describePath(path cty.Path) string {
+	parts := make([]string, 0, len(path))
+	for i := len(path) - 1; i >= 0; i-- {
+		switch step := path[i].(type) {
+		case cty.GetAttrStep:
+			parts = append(parts, fmt.Sprintf("attribute %q", step.Name))
+		case cty.IndexStep:
+			if step.Key.IsKnown() && step.Key.Type() == cty.Number {
+				parts = append(parts, fmt.Sprintf("element %s", step.Key.AsBigFloat().Text('f', -1)))
+			} else {
+				parts = append(parts, "each element")
+			}
+		}
+	}
+	return strings.Join(parts, " of ")
+}
+
+func elementCount(n int) string {
+	if n == 1 {
+		return "1 element"
+	}
+	return fmt.Sprintf("%d elements", n)
+}
@@ -0,0 +1,85 @@
+package quoty
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestTypeDifference(t *testing.T) {
+	order := func(price cty.Type) cty.Type {
+		return cty.Object(map[string]cty.Type{
+			"side":  cty.String,
+			"price": price,
+		})
+	}
+
+	tests := []struct {
+		a, b cty.Type
+		want string
+	}{
+		{cty.String, cty.String, ""},
+		{cty.String, cty.DynamicPseudoType, ""},
+		{cty.String, cty.Number, ""},
+		{Number, cty.Number, ""},
+		{cty.String, cty.List(cty.String), "A is a string, but B is a list."},
+		{Number, cty.Bool, "A is a number, but B is a bool."},
+		{StellarAssetAmountType, cty.Object(nil), "A is a Stellar asset amount, but B is an object."},
+		{cty.Number, cty.Tuple(nil), "A is a floating-point number, but B is a tuple."},
+		{
+			cty.Object(map[string]cty.Type{"side": cty.String}),
+			order(Number),
+			`B has attribute "price", but A does not.`,
+		},
+		{
+			order(Number),
+			cty.Object(map[string]cty.Type{"side": cty.String}),
+			`A has attribute "price", but B does not.`,
+		},
+		{
+			order(Number),
+			order(cty.Object(nil)),
+			`Attribute "price" is a number in A, but an object in B.`,
+		},
+		{
+			cty.Object(map[string]cty.Type{"orders": cty.List(order(Number))}),
+			cty.Object(map[string]cty.Type{"orders": cty.List(order(cty.List(cty.String)))}),
+			`Attribute "price" of each element of attribute "orders" is a number in A, but a list in B.`,
+		},
+		{
+			cty.Object(map[string]cty.Type{"orders": cty.List(order(Number))}),
+			cty.Object(map[string]cty.Type{"orders": cty.List(cty.Object(map[string]cty.Type{"side": cty.String}))}),
+			`A has attribute "price" in each element of attribute "orders", but B does not.`,
+		},
+		{
+			cty.Tuple([]cty.Type{cty.String}),
+			cty.Tuple([]cty.Type{cty.String, cty.String}),
+			"A has 1 element, but B has 2.",
+		},
+		{
+			cty.Object(map[string]cty.Type{"pair": cty.Tuple([]cty.Type{cty.String, Number, cty.Bool})}),
+			cty.Object(map[string]cty.Type{"pair": cty.Tuple([]cty.Type{cty.String, Number})}),
+			`Attribute "pair" has 3 elements in A, but 2 in B.`,
+		},
+		{
+			cty.Tuple([]cty.Type{Number, cty.Object(nil)}),
+			cty.List(Number),
+			"Element 1 is an object in A, but a number in B.",
+		},
+		{
+			cty.Map(cty.String),
+			cty.Object(map[string]cty.Type{"a": cty.String, "b": cty.Set(cty.String)}),
+			`Attribute "b" is a string in A, but a set in B.`,
+		},
+		{cty.Map(cty.String), cty.Map(cty.List(cty.String)), "Each element is a string in A, but a list in B."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a.GoString()+" "+test.b.GoString(), func(t *testing.T) {
+			got := TypeDifference(test.a, test.b, "A", "B")
+			if got != test.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// Package quotypeexpr parses type constraints written as Quo expressions,
+// such as:
+//
+//	list(object({ asset = asset, amount = amount, price = number }))
+//
+// It is like the typeexpr extension of HCL, but it understands the Quo types:
+// the keyword number is quoty.Number rather than cty.Number, amount is
+// quoty.StellarAssetAmountType, asset is quoty.StellarAssetType and price is
+// quoty.Number, which can represent any Stellar price. The other keywords
+// are string, bool and, in a type constraint, any. Types are built with
+// list, set, map, object and tuple, as in HCL.
+//
+// An attribute of an object type constraint may be declared as
+// optional(T) or optional(T, default), in which case Constraint.Convert
+// inserts the default value, or a null value of type T, when the attribute
+// is missing.
+//
+// TypeString and Constraint.String render types back in the same syntax, for
+// use in diagnostics.
+package quotypeexpr
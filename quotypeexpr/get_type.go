@@ -0,0 +1,255 @@
+package quotypeexpr
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const invalidTypeSummary = "Invalid type specification"
+
+// keywordTypes are the types named by keywords, other than "any".
+var keywordTypes = map[string]cty.Type{
+	"string": cty.String,
+	"bool":   cty.Bool,
+	"number": quoty.Number,
+	"price":  quoty.Number,
+	"amount": quoty.StellarAssetAmountType,
+	"asset":  quoty.StellarAssetType,
+}
+
+// getType returns the constraint described by the given expression. If
+// constraint is false then the keyword "any" and optional attributes are not
+// allowed.
+func getType(expr hcl.Expression, constraint bool) (*Constraint, hcl.Diagnostics) {
+	// First we'll try for one of our keywords.
+	kw := hcl.ExprAsKeyword(expr)
+	if ty, exists := keywordTypes[kw]; exists {
+		return &Constraint{Type: ty}, nil
+	}
+	switch kw {
+	case "any":
+		if !constraint {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  invalidTypeSummary,
+				Detail:   "The keyword \"any\" cannot be used in this type specification: an exact type is required.",
+				Subject:  expr.Range().Ptr(),
+			}}
+		}
+		return &Constraint{Type: cty.DynamicPseudoType}, nil
+	case "list", "set", "map", "object", "tuple":
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   fmt.Sprintf("The %s type constructor requires one argument specifying the element type.", kw),
+			Subject:  expr.Range().Ptr(),
+		}}
+	case "":
+		// okay! we'll fall through and try processing as a call, then.
+	default:
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   fmt.Sprintf("The keyword %q is not a valid type specification.", kw),
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+
+	// If we get down here then our expression isn't just a keyword, so we'll
+	// try to process it as a call instead.
+	call, diags := hcl.ExprCall(expr)
+	if diags.HasErrors() {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   "A type specification is either a primitive type keyword (bool, number, string, amount, asset, price) or a complex type constructor call, like list(string).",
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+
+	switch call.Name {
+	case "list", "set", "map":
+		if len(call.Arguments) != 1 {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  invalidTypeSummary,
+				Detail:   fmt.Sprintf("The %s type constructor requires one argument specifying the element type.", call.Name),
+				Subject:  &call.ArgsRange,
+				Context:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+			}}
+		}
+		elem, diags := getType(call.Arguments[0], constraint)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		ret := &Constraint{Element: elem}
+		switch call.Name {
+		case "list":
+			ret.Type = cty.List(elem.Type)
+		case "set":
+			ret.Type = cty.Set(elem.Type)
+		default:
+			ret.Type = cty.Map(elem.Type)
+		}
+		return ret, diags
+	case "object":
+		if len(call.Arguments) != 1 {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  invalidTypeSummary,
+				Detail:   "The object type constructor requires one argument specifying the attribute types as a map.",
+				Subject:  &call.ArgsRange,
+				Context:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+			}}
+		}
+		attrDefs, diags := hcl.ExprMap(call.Arguments[0])
+		if diags.HasErrors() {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  invalidTypeSummary,
+				Detail:   "Object type constructor requires a map whose keys are attribute names and whose values are the corresponding attribute types.",
+				Subject:  call.Arguments[0].Range().Ptr(),
+				Context:  expr.Range().Ptr(),
+			}}
+		}
+
+		ret := &Constraint{Attributes: make(map[string]*Constraint)}
+		atys := make(map[string]cty.Type)
+		for _, attrDef := range attrDefs {
+			attrName := hcl.ExprAsKeyword(attrDef.Key)
+			if attrName == "" {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  invalidTypeSummary,
+					Detail:   "Object constructor map keys must be attribute names.",
+					Subject:  attrDef.Key.Range().Ptr(),
+					Context:  expr.Range().Ptr(),
+				})
+				continue
+			}
+			attr, def, isOptional, attrDiags := getAttributeType(attrDef.Value, constraint)
+			diags = append(diags, attrDiags...)
+			if attrDiags.HasErrors() {
+				continue
+			}
+			ret.Attributes[attrName] = attr
+			atys[attrName] = attr.Type
+			if isOptional {
+				if ret.Optional == nil {
+					ret.Optional = make(map[string]cty.Value)
+				}
+				ret.Optional[attrName] = def
+			}
+		}
+		ret.Type = cty.Object(atys)
+		return ret, diags
+	case "tuple":
+		if len(call.Arguments) != 1 {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  invalidTypeSummary,
+				Detail:   "The tuple type constructor requires one argument specifying the element types as a list.",
+				Subject:  &call.ArgsRange,
+				Context:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+			}}
+		}
+		elemDefs, diags := hcl.ExprList(call.Arguments[0])
+		if diags.HasErrors() {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  invalidTypeSummary,
+				Detail:   "Tuple type constructor requires a list of element types.",
+				Subject:  call.Arguments[0].Range().Ptr(),
+				Context:  expr.Range().Ptr(),
+			}}
+		}
+		ret := &Constraint{}
+		etys := make([]cty.Type, 0, len(elemDefs))
+		for _, defExpr := range elemDefs {
+			elem, elemDiags := getType(defExpr, constraint)
+			diags = append(diags, elemDiags...)
+			if elemDiags.HasErrors() {
+				elem = &Constraint{Type: cty.DynamicPseudoType}
+			}
+			ret.Elements = append(ret.Elements, elem)
+			etys = append(etys, elem.Type)
+		}
+		ret.Type = cty.Tuple(etys)
+		return ret, diags
+	case "optional":
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   "The optional modifier can only be used for the type of an attribute in an object type constraint.",
+			Subject:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+		}}
+	default:
+		// Can't access call.Arguments in this path because we've not validated
+		// that it contains exactly one expression here.
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   fmt.Sprintf("Keyword %q is not a valid type constructor.", call.Name),
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+}
+
+// getAttributeType returns the constraint described by the given expression
+// for the type of an attribute of an object type, which may use the optional
+// modifier. If it does, the result includes the default value, which is null
+// if none is given.
+func getAttributeType(expr hcl.Expression, constraint bool) (*Constraint, cty.Value, bool, hcl.Diagnostics) {
+	call, diags := hcl.ExprCall(expr)
+	if diags.HasErrors() || call.Name != "optional" {
+		ret, diags := getType(expr, constraint)
+		return ret, cty.NilVal, false, diags
+	}
+
+	if !constraint {
+		return nil, cty.NilVal, false, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   "The optional modifier cannot be used in this type specification: an exact type is required.",
+			Subject:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+		}}
+	}
+	if len(call.Arguments) < 1 || len(call.Arguments) > 2 {
+		return nil, cty.NilVal, false, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  invalidTypeSummary,
+			Detail:   "The optional modifier requires the attribute type as its first argument and, optionally, a default value as its second.",
+			Subject:  &call.ArgsRange,
+			Context:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+		}}
+	}
+
+	ret, diags := getType(call.Arguments[0], constraint)
+	if diags.HasErrors() {
+		return nil, cty.NilVal, false, diags
+	}
+	if len(call.Arguments) == 1 {
+		return ret, cty.NullVal(ret.Type), true, diags
+	}
+
+	defExpr := call.Arguments[1]
+	def, defDiags := defExpr.Value(nil)
+	diags = append(diags, defDiags...)
+	if defDiags.HasErrors() {
+		return nil, cty.NilVal, false, diags
+	}
+	def, err := ret.Convert(def)
+	if err != nil {
+		return nil, cty.NilVal, false, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid default value",
+			Detail:   fmt.Sprintf("The default value is not compatible with the attribute type: %s.", err),
+			Subject:  defExpr.Range().Ptr(),
+			Context:  hcl.RangeBetween(call.NameRange, call.ArgsRange).Ptr(),
+		})
+	}
+	return ret, def, true, diags
+}
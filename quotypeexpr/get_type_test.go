@@ -0,0 +1,146 @@
+package quotypeexpr
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestTypeConstraint(t *testing.T) {
+	tests := []struct {
+		Source  string
+		Want    cty.Type
+		WantErr string
+	}{
+		{
+			`number`,
+			quoty.Number,
+			``,
+		},
+		{
+			`price`,
+			quoty.Number,
+			``,
+		},
+		{
+			`amount`,
+			quoty.StellarAssetAmountType,
+			``,
+		},
+		{
+			`asset`,
+			quoty.StellarAssetType,
+			``,
+		},
+		{
+			`any`,
+			cty.DynamicPseudoType,
+			``,
+		},
+		{
+			`list(object({ asset = asset, amount = amount, price = number }))`,
+			cty.List(cty.Object(map[string]cty.Type{
+				"asset":  quoty.StellarAssetType,
+				"amount": quoty.StellarAssetAmountType,
+				"price":  quoty.Number,
+			})),
+			``,
+		},
+		{
+			`map(set(string))`,
+			cty.Map(cty.Set(cty.String)),
+			``,
+		},
+		{
+			`tuple([bool, any])`,
+			cty.Tuple([]cty.Type{cty.Bool, cty.DynamicPseudoType}),
+			``,
+		},
+		{
+			`object({ side = string, size = optional(number, 1) })`,
+			cty.Object(map[string]cty.Type{
+				"side": cty.String,
+				"size": quoty.Number,
+			}),
+			``,
+		},
+		{
+			`float`,
+			cty.DynamicPseudoType,
+			`The keyword "float" is not a valid type specification.`,
+		},
+		{
+			`list`,
+			cty.DynamicPseudoType,
+			`The list type constructor requires one argument specifying the element type.`,
+		},
+		{
+			`list(number, string)`,
+			cty.DynamicPseudoType,
+			`The list type constructor requires one argument specifying the element type.`,
+		},
+		{
+			`optional(number)`,
+			cty.DynamicPseudoType,
+			`The optional modifier can only be used for the type of an attribute in an object type constraint.`,
+		},
+		{
+			`list(optional(number))`,
+			cty.DynamicPseudoType,
+			`The optional modifier can only be used for the type of an attribute in an object type constraint.`,
+		},
+		{
+			`object({ size = optional(number, "many") })`,
+			cty.DynamicPseudoType,
+			`The default value is not compatible with the attribute type: a value of type number is required.`,
+		},
+		{
+			`"number"`,
+			cty.DynamicPseudoType,
+			`A type specification is either a primitive type keyword (bool, number, string, amount, asset, price) or a complex type constructor call, like list(string).`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Source, func(t *testing.T) {
+			expr, diags := quosyntax.ParseExpression([]byte(test.Source), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("failed to parse: %s", diags.Error())
+			}
+
+			got, diags := TypeConstraint(expr)
+			if test.WantErr != "" {
+				if !diags.HasErrors() {
+					t.Fatalf("unexpected success\ngot: %#v", got.Type)
+				}
+				if diags[0].Detail != test.WantErr {
+					t.Errorf("wrong error\ngot:  %s\nwant: %s", diags[0].Detail, test.WantErr)
+				}
+				return
+			}
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			if !got.Type.Equals(test.Want) {
+				t.Errorf("wrong type\ngot:  %#v\nwant: %#v", got.Type, test.Want)
+			}
+		})
+	}
+}
+
+func TestType(t *testing.T) {
+	for _, src := range []string{`any`, `object({ a = optional(number) })`} {
+		t.Run(src, func(t *testing.T) {
+			expr, diags := quosyntax.ParseExpression([]byte(src), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("failed to parse: %s", diags.Error())
+			}
+			if _, diags := Type(expr); !diags.HasErrors() {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
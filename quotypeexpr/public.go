@@ -0,0 +1,339 @@
+package quotypeexpr
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Constraint is a type constraint along with the optional attributes of the
+// object types within it.
+type Constraint struct {
+	// Type is the type that values are converted to. It contains
+	// cty.DynamicPseudoType wherever the keyword "any" was used.
+	Type cty.Type
+
+	// Optional are the default values of the optional attributes of an
+	// object type, by attribute name. The default value of an optional
+	// attribute declared without one is a null value of its type.
+	Optional map[string]cty.Value
+
+	// Attributes are the constraints for the attributes of an object type,
+	// Elements for the elements of a tuple type, and Element for the
+	// elements of a list, set or map type.
+	Attributes map[string]*Constraint
+	Elements   []*Constraint
+	Element    *Constraint
+}
+
+// Type attempts to process the given expression as a type expression and, if
+// successful, returns the resulting type. If unsuccessful, error diagnostics
+// are returned.
+func Type(expr hcl.Expression) (cty.Type, hcl.Diagnostics) {
+	c, diags := getType(expr, false)
+	if diags.HasErrors() {
+		return cty.DynamicPseudoType, diags
+	}
+	return c.Type, diags
+}
+
+// TypeConstraint attempts to parse the given expression as a type constraint
+// and, if successful, returns the resulting constraint. If unsuccessful,
+// error diagnostics are returned.
+//
+// A type constraint has the same structure as a type, but it additionally
+// allows the keyword "any" to represent cty.DynamicPseudoType and the
+// optional modifier for the attributes of object types.
+func TypeConstraint(expr hcl.Expression) (*Constraint, hcl.Diagnostics) {
+	return getType(expr, true)
+}
+
+// Convert returns the given value converted to the type of the constraint,
+// after inserting the default values of any optional attributes that are
+// missing from the objects within it.
+//
+// The error describes the type of the constraint using TypeString, so that
+// it matches how the constraint was written, and the path of the part of
+// the value that is wrong, if any, such as [0].price.
+func (c *Constraint) Convert(val cty.Value) (cty.Value, error) {
+	val = c.withDefaults(val)
+	ret, err := convert.Convert(val, c.Type)
+	if err != nil {
+		// An error located within the value says what is wrong there, but
+		// the convert package's message for the value as a whole only
+		// repeats the type using a less precise name, so we report a
+		// missing attribute ourselves instead.
+		var pathErr cty.PathError
+		if errors.As(err, &pathErr) && len(pathErr.Path) > 0 {
+			return cty.UnknownVal(c.Type), fmt.Errorf("a value of type %s is required; at %s: %s", TypeString(c.Type), quosyntax.FormatPath("", pathErr.Path), err)
+		}
+		if name := missingAttribute(val, c.Type); name != "" {
+			return cty.UnknownVal(c.Type), fmt.Errorf("a value of type %s is required, but attribute %q is required", TypeString(c.Type), name)
+		}
+		return cty.UnknownVal(c.Type), fmt.Errorf("a value of type %s is required", TypeString(c.Type))
+	}
+	return ret, nil
+}
+
+// missingAttribute returns the first, by name, of the attributes of the
+// given object type that the given object value doesn't have, or the empty
+// string if it has them all or either isn't an object.
+func missingAttribute(val cty.Value, ty cty.Type) string {
+	if !ty.IsObjectType() || !val.Type().IsObjectType() {
+		return ""
+	}
+	var missing []string
+	for name := range ty.AttributeTypes() {
+		if !val.Type().HasAttribute(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	sort.Strings(missing)
+	return missing[0]
+}
+
+// withDefaults returns the given value with the default values of any missing
+// optional attributes inserted, leaving the rest of the conversion to the
+// convert package.
+func (c *Constraint) withDefaults(val cty.Value) cty.Value {
+	if !c.hasOptional() || !val.IsKnown() || val.IsNull() {
+		return val
+	}
+	ty := val.Type()
+
+	switch {
+	case c.Type.IsObjectType() && (ty.IsObjectType() || ty.IsMapType()):
+		attrs := val.AsValueMap()
+		if attrs == nil {
+			attrs = make(map[string]cty.Value)
+		}
+		for name, def := range c.Optional {
+			if _, exists := attrs[name]; !exists {
+				attrs[name] = def
+			}
+		}
+		for name, attr := range c.Attributes {
+			if v, exists := attrs[name]; exists {
+				attrs[name] = attr.withDefaults(v)
+			}
+		}
+		return cty.ObjectVal(attrs)
+	case c.Type.IsTupleType() && ty.IsTupleType():
+		elems := val.AsValueSlice()
+		for i := range elems {
+			if i < len(c.Elements) {
+				elems[i] = c.Elements[i].withDefaults(elems[i])
+			}
+		}
+		return cty.TupleVal(elems)
+	case c.Element != nil && c.Type.IsMapType() && (ty.IsObjectType() || ty.IsMapType()):
+		elems := val.AsValueMap()
+		for key, elem := range elems {
+			elems[key] = c.Element.withDefaults(elem)
+		}
+		// Elements given defaults might no longer have the same type, so we
+		// return an object for the convert package to unify.
+		return cty.ObjectVal(elems)
+	case c.Element != nil && (ty.IsListType() || ty.IsSetType() || ty.IsTupleType()):
+		elems := val.AsValueSlice()
+		for i, elem := range elems {
+			elems[i] = c.Element.withDefaults(elem)
+		}
+		if len(elems) == 0 {
+			return val
+		}
+		return cty.TupleVal(elems)
+	default:
+		return val
+	}
+}
+
+// hasOptional returns true if the constraint has any optional attributes.
+func (c *Constraint) hasOptional() bool {
+	if c == nil {
+		return false
+	}
+	if len(c.Optional) != 0 || c.Element.hasOptional() {
+		return true
+	}
+	for _, attr := range c.Attributes {
+		if attr.hasOptional() {
+			return true
+		}
+	}
+	for _, elem := range c.Elements {
+		if elem.hasOptional() {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint as it would be written, including any
+// optional attributes and their default values.
+func (c *Constraint) String() string {
+	switch {
+	case c.Type.IsObjectType() && c.Attributes != nil:
+		names := make([]string, 0, len(c.Attributes))
+		for name := range c.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts := make([]string, len(names))
+		for i, name := range names {
+			attr := c.Attributes[name].String()
+			if def, optional := c.Optional[name]; optional {
+				if def.IsNull() {
+					attr = fmt.Sprintf("optional(%s)", attr)
+				} else {
+					attr = fmt.Sprintf("optional(%s, %s)", attr, valueString(def))
+				}
+			}
+			parts[i] = fmt.Sprintf("%s = %s", attributeName(name), attr)
+		}
+		return objectString(parts)
+	case c.Type.IsTupleType() && c.Elements != nil:
+		parts := make([]string, len(c.Elements))
+		for i, elem := range c.Elements {
+			parts[i] = elem.String()
+		}
+		return fmt.Sprintf("tuple([%s])", strings.Join(parts, ", "))
+	case c.Type.IsListType() && c.Element != nil:
+		return fmt.Sprintf("list(%s)", c.Element)
+	case c.Type.IsSetType() && c.Element != nil:
+		return fmt.Sprintf("set(%s)", c.Element)
+	case c.Type.IsMapType() && c.Element != nil:
+		return fmt.Sprintf("map(%s)", c.Element)
+	default:
+		return TypeString(c.Type)
+	}
+}
+
+// TypeString returns a string rendering of the given type as it would be
+// written in a type expression, such as list(object({ amount = amount })).
+//
+// Types that have no such syntax, such as cty.Number and capsule types other
+// than those of the quoty package, are rendered with their friendly names.
+func TypeString(ty cty.Type) string {
+	switch {
+	case ty == cty.DynamicPseudoType:
+		return "any"
+	case ty == cty.String:
+		return "string"
+	case ty == cty.Bool:
+		return "bool"
+	case ty.Equals(quoty.Number):
+		return "number"
+	case ty.Equals(quoty.StellarAssetAmountType):
+		return "amount"
+	case ty.Equals(quoty.StellarAssetType):
+		return "asset"
+	case ty.IsListType():
+		return fmt.Sprintf("list(%s)", TypeString(ty.ElementType()))
+	case ty.IsSetType():
+		return fmt.Sprintf("set(%s)", TypeString(ty.ElementType()))
+	case ty.IsMapType():
+		return fmt.Sprintf("map(%s)", TypeString(ty.ElementType()))
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		names := make([]string, 0, len(atys))
+		for name := range atys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%s = %s", attributeName(name), TypeString(atys[name]))
+		}
+		return objectString(parts)
+	case ty.IsTupleType():
+		etys := ty.TupleElementTypes()
+		parts := make([]string, len(etys))
+		for i, ety := range etys {
+			parts[i] = TypeString(ety)
+		}
+		return fmt.Sprintf("tuple([%s])", strings.Join(parts, ", "))
+	default:
+		return ty.FriendlyName()
+	}
+}
+
+// objectString returns an object type constructor call with the given
+// attribute definitions.
+func objectString(parts []string) string {
+	if len(parts) == 0 {
+		return "object({})"
+	}
+	return fmt.Sprintf("object({ %s })", strings.Join(parts, ", "))
+}
+
+// attributeName returns the given attribute name as it would be written as
+// an object key.
+func attributeName(name string) string {
+	if quosyntax.ValidIdentifier(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// valueString returns the given value as it would be written as a constant
+// expression, for the default values of optional attributes.
+func valueString(val cty.Value) string {
+	ty := val.Type()
+	switch {
+	case !val.IsKnown():
+		return "(unknown)"
+	case val.IsNull():
+		return "null"
+	case ty == cty.String:
+		return fmt.Sprintf("%q", val.AsString())
+	case ty == cty.Bool:
+		if val.True() {
+			return "true"
+		}
+		return "false"
+	case ty == cty.Number || ty.Equals(quoty.Number) || ty.Equals(quoty.StellarAssetAmountType):
+		// Amounts and cty numbers are all exactly representable as a
+		// quoty.Number, which has the decimal notation of a literal.
+		num, err := convert.Convert(val, quoty.Number)
+		if err != nil {
+			return ty.FriendlyName()
+		}
+		str, _ := quoty.ToString(num)
+		return str.AsString()
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		var parts []string
+		for it := val.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			parts = append(parts, valueString(elem))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(parts, ", "))
+	case ty.IsMapType() || ty.IsObjectType():
+		elems := val.AsValueMap()
+		names := make([]string, 0, len(elems))
+		for name := range elems {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = fmt.Sprintf("%s = %s", attributeName(name), valueString(elems[name]))
+		}
+		if len(parts) == 0 {
+			return "{}"
+		}
+		return fmt.Sprintf("{ %s }", strings.Join(parts, ", "))
+	default:
+		return ty.FriendlyName()
+	}
+}
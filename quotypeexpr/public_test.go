@@ -0,0 +1,179 @@
+package quotypeexpr
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/quomproject/quolang/quosyntax"
+	"github.com/quomproject/quolang/quoty"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestConstraintConvert(t *testing.T) {
+	order := func(side string, size cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"side": cty.StringVal(side),
+			"size": size,
+		})
+	}
+
+	tests := []struct {
+		Type    string
+		Value   string
+		Want    cty.Value
+		WantErr string
+	}{
+		{
+			`number`,
+			`"1.25"`,
+			quoty.MustParseNumberVal("1.25"),
+			``,
+		},
+		{
+			`object({ side = string, size = optional(number, 1) })`,
+			`{ side = "buy" }`,
+			order("buy", quoty.NumberIntVal(1)),
+			``,
+		},
+		{
+			`object({ side = string, size = optional(number) })`,
+			`{ side = "buy" }`,
+			order("buy", cty.NullVal(quoty.Number)),
+			``,
+		},
+		{
+			`list(object({ side = string, size = optional(number, 1) }))`,
+			`[{ side = "buy", size = 2 }, { side = "sell" }]`,
+			cty.ListVal([]cty.Value{
+				order("buy", quoty.NumberIntVal(2)),
+				order("sell", quoty.NumberIntVal(1)),
+			}),
+			``,
+		},
+		{
+			`map(object({ side = string, size = optional(number, 1) }))`,
+			`{ a = { side = "buy" } }`,
+			cty.MapVal(map[string]cty.Value{
+				"a": order("buy", quoty.NumberIntVal(1)),
+			}),
+			``,
+		},
+		{
+			`object({ legs = tuple([object({ size = optional(number, 1) })]) })`,
+			`{ legs = [{}] }`,
+			cty.ObjectVal(map[string]cty.Value{
+				"legs": cty.TupleVal([]cty.Value{
+					cty.ObjectVal(map[string]cty.Value{"size": quoty.NumberIntVal(1)}),
+				}),
+			}),
+			``,
+		},
+		{
+			`object({ side = string, size = optional(number, 1) })`,
+			`{ size = 2 }`,
+			cty.NilVal,
+			`a value of type object({ side = string, size = number }) is required, but attribute "side" is required`,
+		},
+		{
+			`list(object({ side = string, post_only = optional(bool) }))`,
+			`[{ side = "buy", post_only = "maybe" }]`,
+			cty.NilVal,
+			`a value of type list(object({ post_only = bool, side = string })) is required; at [0].post_only: a bool is required`,
+		},
+		{
+			`list(amount)`,
+			`true`,
+			cty.NilVal,
+			`a value of type list(amount) is required`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Type+" "+test.Value, func(t *testing.T) {
+			tyExpr, diags := quosyntax.ParseExpression([]byte(test.Type), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("failed to parse type: %s", diags.Error())
+			}
+			c, diags := TypeConstraint(tyExpr)
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			valExpr, diags := quosyntax.ParseExpression([]byte(test.Value), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("failed to parse value: %s", diags.Error())
+			}
+			val, diags := valExpr.Value(nil)
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+
+			got, err := c.Convert(val)
+			if test.WantErr != "" {
+				if err == nil {
+					t.Fatalf("unexpected success\ngot: %#v", got)
+				}
+				if err.Error() != test.WantErr {
+					t.Errorf("wrong error\ngot:  %s\nwant: %s", err.Error(), test.WantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !got.RawEquals(test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestConstraintString(t *testing.T) {
+	tests := []string{
+		`number`,
+		`list(object({ amount = amount, asset = asset, price = number }))`,
+		`map(set(string))`,
+		`tuple([bool, any])`,
+		`object({})`,
+		`object({ side = string, size = optional(number, 1.5), tags = optional(map(string), { "a b" = "c" }) })`,
+		`object({ legs = list(object({ note = optional(string) })) })`,
+	}
+
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			expr, diags := quosyntax.ParseExpression([]byte(src), "", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("failed to parse: %s", diags.Error())
+			}
+			c, diags := TypeConstraint(expr)
+			if diags.HasErrors() {
+				t.Fatalf("unexpected diagnostics: %s", diags.Error())
+			}
+			if got := c.String(); got != src {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, src)
+			}
+		})
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	tests := []struct {
+		Type cty.Type
+		Want string
+	}{
+		{quoty.Number, `number`},
+		{quoty.StellarAssetAmountType, `amount`},
+		{quoty.StellarAssetType, `asset`},
+		{cty.List(cty.Map(cty.DynamicPseudoType)), `list(map(any))`},
+		{cty.Object(map[string]cty.Type{"b": cty.Bool, "a b": quoty.Number}), `object({ "a b" = number, b = bool })`},
+		{cty.EmptyTuple, `tuple([])`},
+		{cty.Number, `number`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Want, func(t *testing.T) {
+			if got := TypeString(test.Type); got != test.Want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.Want)
+			}
+		})
+	}
+}